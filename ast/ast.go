@@ -8,116 +8,359 @@ import (
 
 type Node interface {
 	String() string
+	Pos() Position
 }
 
+// Position is the row and column (both starting at 1) where a node begins in the source.
+type Position struct {
+	Row, Col int
+}
+
+func (p Position) Pos() Position { return p }
+
+func (p Position) String() string { return fmt.Sprintf("%d:%d", p.Row, p.Col) }
+
 type Program struct {
+	Position
 	Statements []Node
+	Comments   []*Comment //All comments of the source, in order
 }
 
-func (p *Program) String() string {
-	var sb strings.Builder
-
-	for _, v := range p.Statements {
-		sb.WriteString(v.String())
-	}
+func (p *Program) String() string { return joinNodes(p.Statements, "\n") }
 
-	return sb.String()
+// Comment is a line comment, its text includes the leading //.
+type Comment struct {
+	Position
+	Text string
 }
 
+func (c *Comment) String() string { return c.Text }
+
 type DeclarativeStatement struct {
+	Position
 	Name  Identifier
 	Value Node //Expression
 }
 
 func (d *DeclarativeStatement) String() string {
-	return fmt.Sprintf("%s : %s", d.Name.String(), d.Value.String())
+	return fmt.Sprintf("let %s = %s", declaration(&d.Name), d.Value.String())
 }
 
 type ReturnStatement struct {
+	Position
 	Value Node
 }
 
-func (r *ReturnStatement) String() string { return r.Value.String() }
+func (r *ReturnStatement) String() string { return "return " + r.Value.String() }
 
 type ExpressionStatement struct {
+	Position
 	Expression Node
 }
 
 func (e *ExpressionStatement) String() string { return e.Expression.String() }
 
 type PrefixExpression struct {
+	Position
 	Prefix     string
 	Expression Node
 }
 
-func (p *PrefixExpression) String() string { return "TODO PrefixExpression" }
+func (p *PrefixExpression) String() string { return p.Prefix + operand(p.Expression) }
 
 type ConditionalExpression struct {
+	Position
 	Condition Node
 	True      *CodeBlock
 	False     *CodeBlock
 }
 
-func (c *ConditionalExpression) String() string { return "TODO ConditionalExpression" }
+func (c *ConditionalExpression) String() string {
+	result := fmt.Sprintf("if (%s) %s", c.Condition.String(), c.True.String())
+	if c.False != nil {
+		result += " else " + c.False.String()
+	}
+	return result
+}
 
 type CallExpression struct {
+	Position
 	Arguments []Node
 	Function  Node
 }
 
-func (c *CallExpression) String() string { return "TODO CallExpression" }
+func (c *CallExpression) String() string {
+	return fmt.Sprintf("%s(%s)", operand(c.Function), joinNodes(c.Arguments, ", "))
+}
 
 type Identifier struct {
+	Position
 	Value string
+	Type  *TypeAnnotation //Optional, only on declarations
 }
 
 func (i *Identifier) String() string { return i.Value }
 
+type TypeAnnotation struct {
+	Position
+	Name string
+}
+
+func (t *TypeAnnotation) String() string { return t.Name }
+
 type FunctionLiteral struct {
+	Position
 	Parameters []*Identifier
+	ReturnType *TypeAnnotation
 	Body       *CodeBlock
 }
 
-func (f *FunctionLiteral) String() string { return "TODO FunctionLiteral" }
+func (f *FunctionLiteral) String() string { return "func" + f.signature() }
+
+// signature renders the parameters, return type and body shared with FunctionStatement.
+func (f *FunctionLiteral) signature() string {
+	params := make([]string, len(f.Parameters))
+	for i, p := range f.Parameters {
+		params[i] = declaration(p)
+	}
+
+	result := "(" + strings.Join(params, ", ") + ")"
+	if f.ReturnType != nil {
+		result += ": " + f.ReturnType.String()
+	}
+	return result + " " + f.Body.String()
+}
 
 type CodeBlock struct {
+	Position
 	Statements []Node
+	Rbrace     Position //Closing brace
 }
 
 func (c *CodeBlock) String() string {
-	var sb strings.Builder
-
-	for _, v := range c.Statements {
-		sb.WriteString(v.String())
+	if len(c.Statements) == 0 {
+		return "{}"
 	}
-
-	return sb.String()
+	return "{ " + joinNodes(c.Statements, "; ") + " }"
 }
 
 type IntLiteral struct {
+	Position
 	Value int64
 }
 
-func (il *IntLiteral) String() string { return strconv.FormatInt(il.Value, 0) }
+func (il *IntLiteral) String() string { return strconv.FormatInt(il.Value, 10) }
+
+type FloatLiteral struct {
+	Position
+	Value float64
+}
+
+// String always includes a decimal point so the literal reads back as a float.
+func (fl *FloatLiteral) String() string {
+	result := strconv.FormatFloat(fl.Value, 'f', -1, 64)
+	if !strings.Contains(result, ".") {
+		result += ".0"
+	}
+	return result
+}
 
 type BoolLiteral struct {
+	Position
 	Value bool
 }
 
 func (b *BoolLiteral) String() string { return strconv.FormatBool(b.Value) }
 
 type StringLiteral struct {
+	Position
 	Value string
 }
 
-func (s *StringLiteral) String() string { return s.Value }
+func (s *StringLiteral) String() string { return `"` + s.Value + `"` }
 
 type InfixExpression struct {
+	Position
 	Left     Node
 	Operator string
 	Right    Node
 }
 
 func (i *InfixExpression) String() string {
-	return fmt.Sprintf("%s %s %s", i.Left.String(), i.Operator, i.Right.String())
+	return fmt.Sprintf("%s %s %s", operand(i.Left), i.Operator, operand(i.Right))
+}
+
+type FunctionStatement struct {
+	Position
+	Name     Identifier
+	Function *FunctionLiteral
+}
+
+func (f *FunctionStatement) String() string {
+	return "func " + f.Name.String() + f.Function.signature()
+}
+
+type StructStatement struct {
+	Position
+	Name    Identifier
+	Traits  []*Identifier
+	Fields  []*Identifier
+	Methods []*FunctionStatement
+	Rbrace  Position
+}
+
+func (s *StructStatement) String() string {
+	result := "struct " + s.Name.String()
+	if len(s.Traits) > 0 {
+		traits := make([]string, len(s.Traits))
+		for i, t := range s.Traits {
+			traits[i] = t.String()
+		}
+		result += " impl " + strings.Join(traits, ", ")
+	}
+
+	var members []string
+	for _, f := range s.Fields {
+		members = append(members, declaration(f))
+	}
+	for _, m := range s.Methods {
+		members = append(members, m.String())
+	}
+	if len(members) == 0 {
+		return result + " {}"
+	}
+	return result + " { " + strings.Join(members, "; ") + " }"
+}
+
+type TraitMethod struct {
+	Position
+	Name       Identifier
+	Parameters []*Identifier
+}
+
+func (t *TraitMethod) String() string {
+	params := make([]string, len(t.Parameters))
+	for i, p := range t.Parameters {
+		params[i] = declaration(p)
+	}
+	return fmt.Sprintf("%s(%s)", t.Name.String(), strings.Join(params, ", "))
+}
+
+type TraitStatement struct {
+	Position
+	Name    Identifier
+	Methods []*TraitMethod
+	Rbrace  Position
+}
+
+func (t *TraitStatement) String() string {
+	if len(t.Methods) == 0 {
+		return "trait " + t.Name.String() + " {}"
+	}
+
+	methods := make([]string, len(t.Methods))
+	for i, m := range t.Methods {
+		methods[i] = m.String()
+	}
+	return "trait " + t.Name.String() + " { " + strings.Join(methods, "; ") + " }"
+}
+
+type ImportStatement struct {
+	Position
+	Path  *StringLiteral
+	Alias *Identifier //Optional, defaults to the file name without extension
+}
+
+func (i *ImportStatement) String() string {
+	if i.Alias != nil {
+		return fmt.Sprintf("import %s as %s", i.Path.String(), i.Alias.String())
+	}
+	return "import " + i.Path.String()
+}
+
+type ExportStatement struct {
+	Position
+	Statement Node //Declaration being exported
+}
+
+func (e *ExportStatement) String() string { return "export " + e.Statement.String() }
+
+type MemberExpression struct {
+	Position
+	Object Node
+	Member *Identifier
+}
+
+func (m *MemberExpression) String() string {
+	return fmt.Sprintf("%s.%s", operand(m.Object), m.Member.String())
+}
+
+type AssignmentExpression struct {
+	Position
+	Target Node //Identifier or MemberExpression
+	Value  Node
+}
+
+func (a *AssignmentExpression) String() string {
+	return fmt.Sprintf("%s = %s", a.Target.String(), a.Value.String())
+}
+
+type ArrayLiteral struct {
+	Position
+	Elements []Node
+}
+
+func (a *ArrayLiteral) String() string { return "[" + joinNodes(a.Elements, ", ") + "]" }
+
+type MapPair struct {
+	Key   Node
+	Value Node
+}
+
+type MapLiteral struct {
+	Position
+	Pairs []*MapPair
+}
+
+func (m *MapLiteral) String() string {
+	pairs := make([]string, len(m.Pairs))
+	for i, p := range m.Pairs {
+		pairs[i] = p.Key.String() + ": " + p.Value.String()
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+type IndexExpression struct {
+	Position
+	Left  Node
+	Index Node
+}
+
+func (i *IndexExpression) String() string {
+	return fmt.Sprintf("%s[%s]", operand(i.Left), i.Index.String())
+}
+
+func joinNodes(nodes []Node, sep string) string {
+	result := make([]string, len(nodes))
+	for i, n := range nodes {
+		result[i] = n.String()
+	}
+	return strings.Join(result, sep)
+}
+
+// declaration renders an identifier with its type annotation.
+func declaration(i *Identifier) string {
+	if i.Type != nil {
+		return i.Value + ": " + i.Type.String()
+	}
+	return i.Value
+}
+
+// operand parenthesizes operators so the result parses back to the same tree.
+func operand(n Node) string {
+	switch n.(type) {
+	case *InfixExpression, *PrefixExpression, *AssignmentExpression:
+		return "(" + n.String() + ")"
+	}
+	return n.String()
 }
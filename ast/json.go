@@ -0,0 +1,257 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode"
+)
+
+// The JSON form of a node is an object with its "kind", such as "InfixExpression",
+// its "position" and its fields named in lower camel case, in declaration order:
+//
+//	{"kind": "Identifier", "position": {"row": 1, "col": 5}, "value": "x", "type": null}
+//
+// Positions are objects with a row and a col, missing nodes are null.
+
+var (
+	nodeType     = reflect.TypeOf((*Node)(nil)).Elem()
+	positionType = reflect.TypeOf(Position{})
+)
+
+// nodeKinds maps the kind of every node to its type.
+var nodeKinds = map[string]reflect.Type{}
+
+// optionalFields are the node fields the parser may leave nil, every other
+// node field must be decoded.
+var optionalFields = map[string]bool{
+	"Identifier.Type":             true,
+	"FunctionLiteral.ReturnType":  true,
+	"ConditionalExpression.False": true,
+	"ImportStatement.Alias":       true,
+}
+
+func init() {
+	for _, n := range []Node{
+		&Program{}, &Comment{}, &DeclarativeStatement{}, &ReturnStatement{}, &ExpressionStatement{},
+		&PrefixExpression{}, &ConditionalExpression{}, &CallExpression{}, &Identifier{},
+		&TypeAnnotation{}, &FunctionLiteral{}, &CodeBlock{}, &IntLiteral{}, &FloatLiteral{},
+		&BoolLiteral{}, &StringLiteral{}, &InfixExpression{}, &FunctionStatement{},
+		&StructStatement{}, &TraitMethod{}, &TraitStatement{}, &ImportStatement{},
+		&ExportStatement{}, &MemberExpression{}, &AssignmentExpression{}, &ArrayLiteral{},
+		&MapLiteral{}, &IndexExpression{},
+	} {
+		t := reflect.TypeOf(n).Elem()
+		nodeKinds[t.Name()] = t
+	}
+}
+
+// ToJSON encodes the tree as indented JSON.
+func ToJSON(node Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, reflect.ValueOf(&node).Elem()); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeValue(buf, v.Elem())
+	case reflect.Slice:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case reflect.Struct:
+		return encodeStruct(buf, v)
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
+
+func encodeStruct(buf *bytes.Buffer, v reflect.Value) error {
+	t := v.Type()
+	if t == positionType {
+		p := v.Interface().(Position)
+		fmt.Fprintf(buf, `{"row":%d,"col":%d}`, p.Row, p.Col)
+		return nil
+	}
+
+	buf.WriteByte('{')
+	if reflect.PointerTo(t).Implements(nodeType) {
+		fmt.Fprintf(buf, `"kind":%q,`, t.Name())
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, "%q:", jsonName(t.Field(i).Name))
+		if err := encodeValue(buf, v.Field(i)); err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), t.Field(i).Name, err)
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// FromJSON decodes a tree encoded by ToJSON. Fields missing from the JSON keep
+// their zero value, except nodes, which must be there unless the parser may
+// leave them out.
+func FromJSON(data []byte) (Node, error) {
+	return decodeNode(data)
+}
+
+func decodeNode(data json.RawMessage) (Node, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, nil
+	}
+
+	var kind string
+	if err := json.Unmarshal(fields["kind"], &kind); err != nil || kind == "" {
+		return nil, fmt.Errorf("node without kind")
+	}
+	t, ok := nodeKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown node kind %q", kind)
+	}
+
+	v := reflect.New(t)
+	if err := decodeStruct(fields, v.Elem()); err != nil {
+		return nil, fmt.Errorf("%s: %w", kind, err)
+	}
+	return v.Interface().(Node), nil
+}
+
+func decodeStruct(fields map[string]json.RawMessage, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i).Name)
+		data, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if err := decodeValue(data, v.Field(i)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if isNil(v.Field(i)) && !optionalFields[t.Name()+"."+t.Field(i).Name] {
+			return fmt.Errorf("missing %s", jsonName(t.Field(i).Name))
+		}
+	}
+	return nil
+}
+
+// isNil reports whether v is a node, or a map pair, that is missing.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+func decodeValue(data json.RawMessage, v reflect.Value) error {
+	t := v.Type()
+	switch {
+	case t == positionType:
+		var p struct{ Row, Col int }
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(Position{Row: p.Row, Col: p.Col}))
+		return nil
+	case t.Kind() == reflect.Slice:
+		var elements []json.RawMessage
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(t, len(elements), len(elements))
+		for i, e := range elements {
+			if err := decodeValue(e, slice.Index(i)); err != nil {
+				return fmt.Errorf("%d: %w", i, err)
+			}
+			if isNil(slice.Index(i)) {
+				return fmt.Errorf("%d: missing node", i)
+			}
+		}
+		v.Set(slice)
+		return nil
+	case t.Kind() == reflect.Pointer && !t.Implements(nodeType):
+		//MapPair, the only struct that is not a node
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		if fields == nil {
+			return nil
+		}
+		elem := reflect.New(t.Elem())
+		if err := decodeStruct(fields, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case t == nodeType || t.Kind() == reflect.Pointer || reflect.PointerTo(t).Implements(nodeType):
+		n, err := decodeNode(data)
+		if err != nil || n == nil {
+			return err
+		}
+		return setNode(v, n)
+	}
+
+	return json.Unmarshal(data, v.Addr().Interface())
+}
+
+// setNode stores n in a field of type Node, a pointer to a node or a node value.
+func setNode(v reflect.Value, n Node) error {
+	nv := reflect.ValueOf(n)
+	switch {
+	case nv.Type().AssignableTo(v.Type()):
+		v.Set(nv)
+	case nv.Elem().Type() == v.Type():
+		v.Set(nv.Elem())
+	default:
+		expected := v.Type()
+		if expected.Kind() == reflect.Pointer {
+			expected = expected.Elem()
+		}
+		return fmt.Errorf("expected %s, got %s", expected.Name(), nv.Elem().Type().Name())
+	}
+	return nil
+}
+
+func jsonName(field string) string {
+	r := []rune(field)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
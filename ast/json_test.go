@@ -0,0 +1,116 @@
+package ast_test
+
+import (
+	"Simply/ast"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	program := parse(t, everything)
+
+	data, err := ast.ToJSON(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := ast.FromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(node, program) {
+		t.Errorf("decoded tree differs from the original:\n%s\n%s", node, program)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	program := parse(t, "-x")
+
+	data, err := ast.ToJSON(program.Statements[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "kind": "ExpressionStatement",
+  "position": {
+    "row": 1,
+    "col": 1
+  },
+  "expression": {
+    "kind": "PrefixExpression",
+    "position": {
+      "row": 1,
+      "col": 1
+    },
+    "prefix": "-",
+    "expression": {
+      "kind": "Identifier",
+      "position": {
+        "row": 1,
+        "col": 2
+      },
+      "value": "x",
+      "type": null
+    }
+  }
+}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind": "InfixExpression", "left": {"kind": "IntLiteral", "value": 1}, "operator": "+", "right": {"kind": "FloatLiteral", "value": 2}}`, "1 + 2.0"},
+		{`{"kind": "MapLiteral", "pairs": [{"key": {"kind": "StringLiteral", "value": "k"}, "value": {"kind": "BoolLiteral", "value": true}}]}`, `{"k": true}`},
+		{`{"kind": "DeclarativeStatement", "name": {"kind": "Identifier", "value": "a"}, "value": {"kind": "ArrayLiteral"}}`, "let a = []"},
+		{`null`, "<nil>"},
+	}
+
+	for _, tt := range tests {
+		node, err := ast.FromJSON([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+
+		got := "<nil>"
+		if node != nil {
+			got = node.String()
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[]`, "cannot unmarshal array"},
+		{`{"value": 1}`, "node without kind"},
+		{`{"kind": "Loop"}`, `unknown node kind "Loop"`},
+		{`{"kind": "IntLiteral", "value": "1"}`, "IntLiteral: value: json: cannot unmarshal string"},
+		{`{"kind": "ConditionalExpression", "true": {"kind": "IntLiteral"}}`, "ConditionalExpression: true: expected CodeBlock, got IntLiteral"},
+		{`{"kind": "Program", "statements": [{"kind": "ExpressionStatement", "position": 1}]}`, "Program: statements: 0: ExpressionStatement: position: json: cannot unmarshal number"},
+		{`{"kind": "Program", "statements": [{"kind": "DeclarativeStatement"}]}`, "Program: statements: 0: DeclarativeStatement: missing value"},
+		{`{"kind": "ExpressionStatement", "expression": null}`, "ExpressionStatement: missing expression"},
+		{`{"kind": "Program", "statements": [null]}`, "Program: statements: 0: missing node"},
+		{`{"kind": "MapLiteral", "pairs": [{"key": {"kind": "IntLiteral"}}]}`, "MapLiteral: pairs: 0: missing value"},
+		{`{"kind": "FunctionLiteral", "parameters": [null], "body": {"kind": "CodeBlock"}}`, "FunctionLiteral: parameters: 0: missing node"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.FromJSON([]byte(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
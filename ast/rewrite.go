@@ -0,0 +1,141 @@
+package ast
+
+import "fmt"
+
+// Rewrite traverses the tree like Walk and replaces every node with the result
+// of f. Children are rewritten before their parent, so f sees a node whose
+// children were already replaced. Returning the node unchanged keeps it, and
+// returning nil removes it from a list of statements, arguments or elements.
+//
+// A field with a concrete type, such as the body of a function, can only be
+// replaced with a node of that type. Rewrite panics otherwise.
+func Rewrite(node Node, f func(Node) Node) Node {
+	if node == nil {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = rewriteList(n.Statements, f)
+		comments := n.Comments[:0]
+		for _, c := range n.Comments {
+			if r := f(c); r != nil {
+				comments = append(comments, assertNode(c, r))
+			}
+		}
+		n.Comments = comments
+	case *DeclarativeStatement:
+		n.Name = *rewriteAs(&n.Name, f)
+		n.Value = Rewrite(n.Value, f)
+	case *ReturnStatement:
+		n.Value = Rewrite(n.Value, f)
+	case *ExpressionStatement:
+		n.Expression = Rewrite(n.Expression, f)
+	case *PrefixExpression:
+		n.Expression = Rewrite(n.Expression, f)
+	case *ConditionalExpression:
+		n.Condition = Rewrite(n.Condition, f)
+		n.True = rewriteAs(n.True, f)
+		if n.False != nil {
+			n.False = rewriteAs(n.False, f)
+		}
+	case *CallExpression:
+		n.Function = Rewrite(n.Function, f)
+		n.Arguments = rewriteList(n.Arguments, f)
+	case *Identifier:
+		if n.Type != nil {
+			n.Type = rewriteAs(n.Type, f)
+		}
+	case *FunctionLiteral:
+		n.Parameters = rewriteIdentifiers(n.Parameters, f)
+		if n.ReturnType != nil {
+			n.ReturnType = rewriteAs(n.ReturnType, f)
+		}
+		n.Body = rewriteAs(n.Body, f)
+	case *CodeBlock:
+		n.Statements = rewriteList(n.Statements, f)
+	case *InfixExpression:
+		n.Left = Rewrite(n.Left, f)
+		n.Right = Rewrite(n.Right, f)
+	case *FunctionStatement:
+		n.Name = *rewriteAs(&n.Name, f)
+		n.Function = rewriteAs(n.Function, f)
+	case *StructStatement:
+		n.Name = *rewriteAs(&n.Name, f)
+		n.Traits = rewriteIdentifiers(n.Traits, f)
+		n.Fields = rewriteIdentifiers(n.Fields, f)
+		for i, m := range n.Methods {
+			n.Methods[i] = rewriteAs(m, f)
+		}
+	case *TraitMethod:
+		n.Name = *rewriteAs(&n.Name, f)
+		n.Parameters = rewriteIdentifiers(n.Parameters, f)
+	case *TraitStatement:
+		n.Name = *rewriteAs(&n.Name, f)
+		for i, m := range n.Methods {
+			n.Methods[i] = rewriteAs(m, f)
+		}
+	case *ImportStatement:
+		n.Path = rewriteAs(n.Path, f)
+		if n.Alias != nil {
+			n.Alias = rewriteAs(n.Alias, f)
+		}
+	case *ExportStatement:
+		n.Statement = Rewrite(n.Statement, f)
+	case *MemberExpression:
+		n.Object = Rewrite(n.Object, f)
+		n.Member = rewriteAs(n.Member, f)
+	case *AssignmentExpression:
+		n.Target = Rewrite(n.Target, f)
+		n.Value = Rewrite(n.Value, f)
+	case *ArrayLiteral:
+		n.Elements = rewriteList(n.Elements, f)
+	case *MapLiteral:
+		for _, p := range n.Pairs {
+			p.Key = Rewrite(p.Key, f)
+			p.Value = Rewrite(p.Value, f)
+		}
+	case *IndexExpression:
+		n.Left = Rewrite(n.Left, f)
+		n.Index = Rewrite(n.Index, f)
+	case *Comment, *TypeAnnotation, *IntLiteral, *FloatLiteral, *BoolLiteral, *StringLiteral:
+		//No children
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+func rewriteList(nodes []Node, f func(Node) Node) []Node {
+	result := nodes[:0]
+	for _, n := range nodes {
+		if n = Rewrite(n, f); n != nil {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func rewriteIdentifiers(identifiers []*Identifier, f func(Node) Node) []*Identifier {
+	result := identifiers[:0]
+	for _, i := range identifiers {
+		if n := Rewrite(i, f); n != nil {
+			result = append(result, assertNode(i, n))
+		}
+	}
+	return result
+}
+
+// rewriteAs rewrites a node held in a field of concrete type T.
+func rewriteAs[T Node](node T, f func(Node) Node) T {
+	return assertNode[T](node, Rewrite(node, f))
+}
+
+func assertNode[T Node](old T, n Node) T {
+	t, ok := n.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace %T with %T", old, n))
+	}
+	return t
+}
@@ -0,0 +1,137 @@
+package ast
+
+import "fmt"
+
+// Visitor's Visit method is called for each node found by Walk. If the returned
+// visitor w is not nil, Walk visits the children of the node with w and then
+// calls w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree in depth-first order, children in source order. The
+// comments of a program are visited after its statements. Missing nodes, as left
+// by a parser that reported errors, are skipped.
+func Walk(v Visitor, node Node) {
+	if node == nil {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkList(v, n.Statements)
+		for _, c := range n.Comments {
+			Walk(v, c)
+		}
+	case *DeclarativeStatement:
+		Walk(v, &n.Name)
+		Walk(v, n.Value)
+	case *ReturnStatement:
+		Walk(v, n.Value)
+	case *ExpressionStatement:
+		Walk(v, n.Expression)
+	case *PrefixExpression:
+		Walk(v, n.Expression)
+	case *ConditionalExpression:
+		Walk(v, n.Condition)
+		Walk(v, n.True)
+		if n.False != nil {
+			Walk(v, n.False)
+		}
+	case *CallExpression:
+		Walk(v, n.Function)
+		walkList(v, n.Arguments)
+	case *Identifier:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+	case *FunctionLiteral:
+		walkIdentifiers(v, n.Parameters)
+		if n.ReturnType != nil {
+			Walk(v, n.ReturnType)
+		}
+		Walk(v, n.Body)
+	case *CodeBlock:
+		walkList(v, n.Statements)
+	case *InfixExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *FunctionStatement:
+		Walk(v, &n.Name)
+		Walk(v, n.Function)
+	case *StructStatement:
+		Walk(v, &n.Name)
+		walkIdentifiers(v, n.Traits)
+		walkIdentifiers(v, n.Fields)
+		for _, m := range n.Methods {
+			Walk(v, m)
+		}
+	case *TraitMethod:
+		Walk(v, &n.Name)
+		walkIdentifiers(v, n.Parameters)
+	case *TraitStatement:
+		Walk(v, &n.Name)
+		for _, m := range n.Methods {
+			Walk(v, m)
+		}
+	case *ImportStatement:
+		Walk(v, n.Path)
+		if n.Alias != nil {
+			Walk(v, n.Alias)
+		}
+	case *ExportStatement:
+		Walk(v, n.Statement)
+	case *MemberExpression:
+		Walk(v, n.Object)
+		Walk(v, n.Member)
+	case *AssignmentExpression:
+		Walk(v, n.Target)
+		Walk(v, n.Value)
+	case *ArrayLiteral:
+		walkList(v, n.Elements)
+	case *MapLiteral:
+		for _, p := range n.Pairs {
+			Walk(v, p.Key)
+			Walk(v, p.Value)
+		}
+	case *IndexExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Index)
+	case *Comment, *TypeAnnotation, *IntLiteral, *FloatLiteral, *BoolLiteral, *StringLiteral:
+		//No children
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkList(v Visitor, nodes []Node) {
+	for _, n := range nodes {
+		Walk(v, n)
+	}
+}
+
+func walkIdentifiers(v Visitor, identifiers []*Identifier) {
+	for _, i := range identifiers {
+		Walk(v, i)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree like Walk, calling f for each node. The children
+// of a node are only visited when f returns true, f(nil) follows them.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
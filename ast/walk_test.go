@@ -0,0 +1,203 @@
+package ast_test
+
+import (
+	"Simply/ast"
+	"Simply/lexer"
+	"Simply/parser"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Uses every node type of the package
+const everything = `// comment
+import "lib/math.simply" as m;
+export let x: int = -1;
+func add(a, b): int { return a + b; }
+trait Shape { area() }
+struct Square impl Shape { side; func area() { side * side } }
+let f = func(n) { if (n < 2) { true } else { false } };
+let a = [1, 2.5, "s"];
+let h = {"k": a[0]};
+x = m.sqrt(4);
+`
+
+func parse(t *testing.T, source string) *ast.Program {
+	t.Helper()
+
+	p := parser.NewParser(lexer.NewTokenizer(source))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		t.Fatalf("parse errors: %v", p.Errors)
+	}
+	return program
+}
+
+func TestInspectVisitsEveryNode(t *testing.T) {
+	program := parse(t, everything)
+
+	seen := map[string]bool{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n != nil {
+			seen[fmt.Sprintf("%T", n)] = true
+		}
+		return true
+	})
+
+	expected := []string{
+		"*ast.Program", "*ast.Comment", "*ast.DeclarativeStatement", "*ast.ReturnStatement",
+		"*ast.ExpressionStatement", "*ast.PrefixExpression", "*ast.ConditionalExpression",
+		"*ast.CallExpression", "*ast.Identifier", "*ast.TypeAnnotation", "*ast.FunctionLiteral",
+		"*ast.CodeBlock", "*ast.IntLiteral", "*ast.FloatLiteral", "*ast.BoolLiteral",
+		"*ast.StringLiteral", "*ast.InfixExpression", "*ast.FunctionStatement",
+		"*ast.StructStatement", "*ast.TraitMethod", "*ast.TraitStatement", "*ast.ImportStatement",
+		"*ast.ExportStatement", "*ast.MemberExpression", "*ast.AssignmentExpression",
+		"*ast.ArrayLiteral", "*ast.MapLiteral", "*ast.IndexExpression",
+	}
+	for _, e := range expected {
+		if !seen[e] {
+			t.Errorf("%s was not visited", e)
+		}
+	}
+}
+
+type recorder struct {
+	events *[]string
+}
+
+func (r recorder) Visit(n ast.Node) ast.Visitor {
+	if n == nil {
+		*r.events = append(*r.events, "end")
+		return nil
+	}
+	*r.events = append(*r.events, n.String())
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2;", "1 + 2 | 1 + 2 | 1 | end | 2 | end | end | end"},
+		{"f(a, 1);", "f(a, 1) | f(a, 1) | f | end | a | end | 1 | end | end | end"},
+		{"x[0] = -y;", "x[0] = -y | x[0] = -y | x[0] | x | end | 0 | end | end | -y | y | end | end | end | end"},
+		{"let a: int = 1;", "let a: int = 1 | a | int | end | end | 1 | end | end"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+
+		var events []string
+		ast.Walk(recorder{&events}, program.Statements[0])
+
+		if got := strings.Join(events, " | "); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parse(t, "let f = func(a) { a + 1 }; let g = 2 + 3;")
+
+	var ints []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FunctionLiteral); ok {
+			return false
+		}
+		if i, ok := n.(*ast.IntLiteral); ok {
+			ints = append(ints, i.String())
+		}
+		return true
+	})
+
+	if got := strings.Join(ints, ","); got != "2,3" {
+		t.Errorf("expected literals outside the function only, got %q", got)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	//Folds additions of integer literals
+	fold := func(n ast.Node) ast.Node {
+		infix, ok := n.(*ast.InfixExpression)
+		if !ok || infix.Operator != "+" {
+			return n
+		}
+		left, lok := infix.Left.(*ast.IntLiteral)
+		right, rok := infix.Right.(*ast.IntLiteral)
+		if !lok || !rok {
+			return n
+		}
+		return &ast.IntLiteral{Position: infix.Position, Value: left.Value + right.Value}
+	}
+	//Renames x to y, including declarations
+	rename := func(n ast.Node) ast.Node {
+		if i, ok := n.(*ast.Identifier); ok && i.Value == "x" {
+			return &ast.Identifier{Position: i.Position, Value: "y", Type: i.Type}
+		}
+		return n
+	}
+	//Drops expression statements calling debug
+	drop := func(n ast.Node) ast.Node {
+		if s, ok := n.(*ast.ExpressionStatement); ok {
+			if c, ok := s.Expression.(*ast.CallExpression); ok && c.Function.String() == "debug" {
+				return nil
+			}
+		}
+		return n
+	}
+
+	tests := []struct {
+		input    string
+		f        func(ast.Node) ast.Node
+		expected string
+	}{
+		{"let a = 1 + 2 + 3; f(4 + x, [5 + 6]);", fold, "let a = 6\nf(4 + x, [11])"},
+		{"func g(x) { if (x) { {\"k\": x[x]} } }", fold, "func g(x) { if (x) { {\"k\": x[x]} } }"},
+		{"let x = 1; func f(x) { x.x = x; }", rename, "let y = 1\nfunc f(y) { y.y = y }"},
+		{"struct S { x; func m(x) { x } }", rename, "struct S { y; func m(y) { y } }"},
+		{"debug(1); let a = 1; func f() { debug(a); a }", drop, "let a = 1\nfunc f() { a }"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+
+		result := ast.Rewrite(program, tt.f)
+		if got := result.String(); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRewriteComments(t *testing.T) {
+	program := parse(t, "// a\nlet x = 1; // b\n")
+
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		if c, ok := n.(*ast.Comment); ok && c.Text == "// a" {
+			return nil
+		}
+		return n
+	})
+
+	if len(program.Comments) != 1 || program.Comments[0].Text != "// b" {
+		t.Errorf("expected only the second comment to remain, got %v", program.Comments)
+	}
+}
+
+func TestRewriteTypeMismatch(t *testing.T) {
+	program := parse(t, "let f = func() { 1 };")
+
+	defer func() {
+		r := recover()
+		if r != "ast.Rewrite: cannot replace *ast.CodeBlock with *ast.IntLiteral" {
+			t.Errorf("unexpected panic %v", r)
+		}
+	}()
+
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		if _, ok := n.(*ast.CodeBlock); ok {
+			return &ast.IntLiteral{Value: 1}
+		}
+		return n
+	})
+}
@@ -0,0 +1,651 @@
+package checker
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/types"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type Error struct {
+	Position ast.Position
+	Message  string
+}
+
+func (e Error) Error() string { return fmt.Sprintf("%s: %s", e.Position.String(), e.Message) }
+
+var builtins = builtinTypes()
+
+// methods lists the members of built-in types
+var methods = map[Type]map[string]Type{
+	String: methodTypes(&types.String{}),
+	Array:  methodTypes(&types.Array{}),
+	Map:    methodTypes(types.NewMap()),
+}
+
+// builtinTypes derives the types of the default builtins from their signatures.
+func builtinTypes() map[string]Type {
+	result := map[string]Type{}
+
+	b := evaluator.DefaultBuiltins()
+	for _, name := range b.Names() {
+		obj, _ := b.Lookup(name)
+		switch obj := obj.(type) {
+		case *types.InternalCall:
+			result[name] = Func
+			if obj.Signature != nil {
+				result[name] = fromSignature(obj.Signature)
+			}
+		case *types.Module:
+			result[name] = Module
+		case *types.Array:
+			result[name] = Array
+		default:
+			result[name] = Any
+		}
+	}
+
+	return result
+}
+
+func methodTypes(receiver types.Object) map[string]Type {
+	result := map[string]Type{}
+	for _, sig := range evaluator.MethodSignatures(receiver) {
+		name := sig.Name[strings.LastIndex(sig.Name, ".")+1:]
+		result[name] = fromSignature(sig)
+	}
+	return result
+}
+
+func fromSignature(sig *types.Signature) *Function {
+	f := &Function{Return: typeFromNames([]string{sig.Returns})}
+	for _, p := range sig.Params {
+		f.Params = append(f.Params, typeFromNames(p.Types))
+		if p.Optional {
+			f.Optional++
+		}
+		if p.Variadic {
+			f.Variadic = true
+		}
+	}
+	return f
+}
+
+// typeFromNames maps the accepted types of a signature parameter to a checker type.
+func typeFromNames(names []string) Type {
+	if len(names) == 1 {
+		if t, ok := basicTypes[names[0]]; ok {
+			return t
+		}
+	}
+	return Any
+}
+
+type binding struct {
+	typ      Type
+	declared Type //Annotated type, nil when inferred
+}
+
+type scope struct {
+	vars   map[string]*binding
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: map[string]*binding{}, parent: parent}
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	for c := s; c != nil; c = c.parent {
+		if b, ok := c.vars[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+type checker struct {
+	errors    []Error
+	functions []*Function //Signatures of the functions being checked, innermost last
+}
+
+// Check infers the types of the program bindings and reports operations,
+// calls and declarations that would fail when the program is evaluated.
+func Check(program *ast.Program) []Error {
+	_, errs := Infer(program, nil)
+	return errs
+}
+
+// Infer checks the program as if the values were bound in its global scope, as
+// they are in a REPL session, and returns the type of its last statement.
+func Infer(program *ast.Program, globals map[string]types.Object) (Type, []Error) {
+	c := &checker{}
+
+	global := newScope(nil)
+	for name, t := range builtins {
+		global.vars[name] = &binding{typ: t}
+	}
+	values := newValueTypes()
+	for name, v := range globals {
+		global.vars[name] = &binding{typ: values.typeOf(v)}
+	}
+
+	result := c.checkStatements(program.Statements, global)
+
+	//Declarations are checked ahead of the statements that use them
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i].Position, c.errors[j].Position
+		return a.Row < b.Row || a.Row == b.Row && a.Col < b.Col
+	})
+
+	return result, c.errors
+}
+
+func (c *checker) errorf(pos ast.Position, format string, args ...interface{}) {
+	c.errors = append(c.errors, Error{Position: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkStatements hoists the declarations of a block so that closures can
+// refer to names bound later, then checks statements in order.
+func (c *checker) checkStatements(statements []ast.Node, s *scope) Type {
+	c.hoist(statements, s)
+
+	var result Type = Null
+	for _, n := range statements {
+		result = c.checkStatement(n, s)
+	}
+	return result
+}
+
+func (c *checker) hoist(statements []ast.Node, s *scope) {
+	statements = unwrapExports(statements)
+
+	for _, n := range statements {
+		switch n := n.(type) {
+		case *ast.TraitStatement:
+			c.declareTrait(n, s)
+		case *ast.StructStatement:
+			s.vars[n.Name.Value] = &binding{typ: &Constructor{Struct: &Struct{Name: n.Name.Value}}}
+		}
+	}
+
+	for _, n := range statements {
+		switch n := n.(type) {
+		case *ast.StructStatement:
+			c.declareStruct(n, s)
+		case *ast.FunctionStatement:
+			s.vars[n.Name.Value] = &binding{typ: c.signature(n.Function, s)}
+		case *ast.DeclarativeStatement:
+			if _, ok := s.vars[n.Name.Value]; ok {
+				continue
+			}
+			var t Type = Any
+			if fl, ok := n.Value.(*ast.FunctionLiteral); ok {
+				t = c.signature(fl, s)
+			}
+			s.vars[n.Name.Value] = &binding{typ: t}
+		}
+	}
+}
+
+func unwrapExports(statements []ast.Node) []ast.Node {
+	result := make([]ast.Node, len(statements))
+	for i, n := range statements {
+		if export, ok := n.(*ast.ExportStatement); ok {
+			n = export.Statement
+		}
+		result[i] = n
+	}
+	return result
+}
+
+func (c *checker) declareTrait(n *ast.TraitStatement, s *scope) {
+	t := &Trait{Name: n.Name.Value, Methods: map[string]int{}}
+	for _, m := range n.Methods {
+		t.Names = append(t.Names, m.Name.Value)
+		t.Methods[m.Name.Value] = len(m.Parameters)
+	}
+	s.vars[t.Name] = &binding{typ: &TraitValue{Trait: t}}
+}
+
+func (c *checker) declareStruct(n *ast.StructStatement, s *scope) {
+	ctor := s.vars[n.Name.Value].typ.(*Constructor)
+	st := ctor.Struct
+	st.Fields = map[string]Type{}
+	st.Methods = map[string]*Function{}
+
+	for _, f := range n.Fields {
+		st.Fields[f.Value] = c.resolve(f.Type, s)
+	}
+	for _, m := range n.Methods {
+		st.Methods[m.Name.Value] = c.signature(m.Function, s)
+	}
+
+	for _, t := range n.Traits {
+		b, ok := s.lookup(t.Value)
+		if !ok {
+			c.errorf(t.Pos(), "undefined: %s", t.Value)
+			continue
+		}
+		tv, ok := b.typ.(*TraitValue)
+		if !ok {
+			c.errorf(t.Pos(), "%s is not a trait", t.Value)
+			continue
+		}
+		for _, name := range tv.Trait.Names {
+			arity := tv.Trait.Methods[name]
+			m, ok := st.Methods[name]
+			if !ok {
+				c.errorf(n.Pos(), "%s does not implement %s: missing method %s", st.Name, tv.Trait.Name, name)
+			} else if len(m.Params) != arity {
+				c.errorf(n.Pos(), "%s does not implement %s: method %s takes %d parameters, want %d",
+					st.Name, tv.Trait.Name, name, len(m.Params), arity)
+			}
+		}
+		st.Traits = append(st.Traits, tv.Trait)
+	}
+}
+
+func (c *checker) signature(fl *ast.FunctionLiteral, s *scope) *Function {
+	f := &Function{Return: c.resolve(fl.ReturnType, s)}
+	for _, p := range fl.Parameters {
+		f.Params = append(f.Params, c.resolve(p.Type, s))
+	}
+	return f
+}
+
+func (c *checker) resolve(t *ast.TypeAnnotation, s *scope) Type {
+	if t == nil {
+		return Any
+	}
+
+	if b, ok := basicTypes[t.Name]; ok {
+		return b
+	}
+
+	if b, ok := s.lookup(t.Name); ok {
+		switch typ := b.typ.(type) {
+		case *Constructor:
+			return typ.Struct
+		case *TraitValue:
+			return typ.Trait
+		}
+	}
+
+	c.errorf(t.Pos(), "unknown type %s", t.Name)
+	return Any
+}
+
+func (c *checker) checkStatement(n ast.Node, s *scope) Type {
+	switch n := n.(type) {
+	case *ast.DeclarativeStatement:
+		c.checkDeclaration(n, s)
+		return Null
+	case *ast.FunctionStatement:
+		f := c.checkFunction(n.Function, s)
+		s.vars[n.Name.Value] = &binding{typ: f}
+		return Null
+	case *ast.StructStatement:
+		c.checkStruct(n, s)
+		return Null
+	case *ast.TraitStatement:
+		return Null
+	case *ast.ExportStatement:
+		return c.checkStatement(n.Statement, s)
+	case *ast.ImportStatement:
+		//Modules are checked on their own, their members are not known here
+		name := strings.TrimSuffix(filepath.Base(n.Path.Value), filepath.Ext(n.Path.Value))
+		if n.Alias != nil {
+			name = n.Alias.Value
+		}
+		s.vars[name] = &binding{typ: Any}
+		return Null
+	case *ast.ReturnStatement:
+		t := c.checkExpression(n.Value, s)
+		c.checkReturn(n.Value.Pos(), t)
+		return t
+	case *ast.ExpressionStatement:
+		return c.checkExpression(n.Expression, s)
+	}
+
+	return c.checkExpression(n, s)
+}
+
+func (c *checker) checkDeclaration(n *ast.DeclarativeStatement, s *scope) {
+	t := c.checkExpression(n.Value, s)
+
+	b := &binding{typ: t}
+	if n.Name.Type != nil {
+		b.declared = c.resolve(n.Name.Type, s)
+		if !assignable(t, b.declared) {
+			c.errorf(n.Value.Pos(), "cannot use %s as %s in declaration of %s", t, b.declared, n.Name.Value)
+		}
+		b.typ = b.declared
+	}
+
+	s.vars[n.Name.Value] = b
+}
+
+func (c *checker) checkStruct(n *ast.StructStatement, s *scope) {
+	b, _ := s.lookup(n.Name.Value)
+	ctor := b.typ.(*Constructor)
+
+	for _, m := range n.Methods {
+		methodScope := newScope(s)
+		methodScope.vars["self"] = &binding{typ: ctor.Struct}
+		c.checkFunction(m.Function, methodScope)
+	}
+}
+
+func (c *checker) checkReturn(pos ast.Position, t Type) {
+	if len(c.functions) == 0 {
+		return
+	}
+
+	want := c.functions[len(c.functions)-1].Return
+	if !assignable(t, want) {
+		c.errorf(pos, "cannot use %s as %s in return", t, want)
+	}
+}
+
+func (c *checker) checkFunction(fl *ast.FunctionLiteral, s *scope) *Function {
+	f := c.signature(fl, s)
+
+	body := newScope(s)
+	for i, p := range fl.Parameters {
+		body.vars[p.Value] = &binding{typ: f.Params[i], declared: f.Params[i]}
+	}
+
+	c.functions = append(c.functions, f)
+	result := c.checkStatements(fl.Body.Statements, body)
+	c.functions = c.functions[:len(c.functions)-1]
+
+	//Without a return statement the value of the last statement is returned
+	if len(fl.Body.Statements) > 0 {
+		last := fl.Body.Statements[len(fl.Body.Statements)-1]
+		if _, ok := last.(*ast.ExpressionStatement); ok && !assignable(result, f.Return) {
+			c.errorf(last.Pos(), "cannot use %s as %s in return", result, f.Return)
+		}
+	}
+
+	return f
+}
+
+func (c *checker) checkExpression(n ast.Node, s *scope) Type {
+	switch n := n.(type) {
+	case *ast.IntLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.ArrayLiteral:
+		for _, el := range n.Elements {
+			c.checkExpression(el, s)
+		}
+		return Array
+	case *ast.MapLiteral:
+		for _, p := range n.Pairs {
+			c.checkExpression(p.Key, s)
+			c.checkExpression(p.Value, s)
+		}
+		return Map
+	case *ast.IndexExpression:
+		return c.checkIndex(n, s)
+	case *ast.StringLiteral:
+		return String
+	case *ast.BoolLiteral:
+		return Bool
+	case *ast.Identifier:
+		if b, ok := s.lookup(n.Value); ok {
+			return b.typ
+		}
+		c.errorf(n.Pos(), "undefined: %s", n.Value)
+		return Any
+	case *ast.FunctionLiteral:
+		return c.checkFunction(n, s)
+	case *ast.PrefixExpression:
+		return c.checkPrefix(n, s)
+	case *ast.InfixExpression:
+		return c.checkInfix(n, s)
+	case *ast.ConditionalExpression:
+		return c.checkConditional(n, s)
+	case *ast.CallExpression:
+		return c.checkCall(n, s)
+	case *ast.MemberExpression:
+		return c.checkMember(n, s)
+	case *ast.AssignmentExpression:
+		return c.checkAssignment(n, s)
+	}
+
+	return Any
+}
+
+func (c *checker) checkPrefix(n *ast.PrefixExpression, s *scope) Type {
+	t := c.checkExpression(n.Expression, s)
+
+	switch n.Prefix {
+	case "!":
+		return Bool
+	case "-":
+		if !isNumber(t) {
+			c.errorf(n.Pos(), "invalid operation: -%s", t)
+		}
+		if t == Float {
+			return Float
+		}
+		return Int
+	}
+
+	return Any
+}
+
+func (c *checker) checkInfix(n *ast.InfixExpression, s *scope) Type {
+	left := c.checkExpression(n.Left, s)
+	right := c.checkExpression(n.Right, s)
+
+	comparison := n.Operator == "<" || n.Operator == ">" || n.Operator == "==" || n.Operator == "!="
+	equality := n.Operator == "==" || n.Operator == "!="
+
+	var result Type
+	switch {
+	case isNumber(left) && isNumber(right):
+		result = Int
+		if left == Float || right == Float {
+			result = Float
+		} else if left == Any || right == Any {
+			result = Any
+		}
+	case left == String && right == String && (n.Operator == "+" || equality):
+		result = String
+	case equality && left == right && (left == Bool || left == Null):
+		result = Bool
+	case left == Any && (right == String || right == Bool) || right == Any && (left == String || left == Bool):
+		result = Any
+	default:
+		c.errorf(n.Pos(), "invalid operation: %s %s %s", left, n.Operator, right)
+		result = Any
+	}
+
+	if comparison {
+		return Bool
+	}
+	return result
+}
+
+func (c *checker) checkConditional(n *ast.ConditionalExpression, s *scope) Type {
+	c.checkExpression(n.Condition, s)
+
+	//Blocks share the scope of the enclosing code
+	trueType := c.checkStatements(n.True.Statements, s)
+	var falseType Type = Null
+	if n.False != nil {
+		falseType = c.checkStatements(n.False.Statements, s)
+	}
+
+	if trueType == falseType {
+		return trueType
+	}
+	return Any
+}
+
+func (c *checker) checkCall(n *ast.CallExpression, s *scope) Type {
+	ft := c.checkExpression(n.Function, s)
+
+	args := make([]Type, len(n.Arguments))
+	for i, a := range n.Arguments {
+		args[i] = c.checkExpression(a, s)
+	}
+
+	name := "function"
+	switch f := n.Function.(type) {
+	case *ast.Identifier:
+		name = f.Value
+	case *ast.MemberExpression:
+		name = f.Member.Value
+	}
+
+	switch ft := ft.(type) {
+	case *Function:
+		c.checkArguments(n, name, ft, args)
+		return ft.Return
+	case *Constructor:
+		if init, ok := ft.Struct.Methods["init"]; ok {
+			c.checkArguments(n, name, init, args)
+		} else if len(args) > len(ft.Struct.Fields) {
+			c.errorf(n.Pos(), "too many arguments in call to %s: got %d, want %d", name, len(args), len(ft.Struct.Fields))
+		}
+		return ft.Struct
+	}
+
+	if ft != Any && ft != Func {
+		c.errorf(n.Pos(), "cannot call non-function %s (%s)", name, ft)
+	}
+	return Any
+}
+
+func (c *checker) checkIndex(n *ast.IndexExpression, s *scope) Type {
+	left := c.checkExpression(n.Left, s)
+	index := c.checkExpression(n.Index, s)
+
+	switch left {
+	case Array, String:
+		if !assignable(index, Int) {
+			c.errorf(n.Index.Pos(), "index must be int, got %s", index)
+		}
+		if left == String {
+			return String
+		}
+	case Map, Any:
+	default:
+		c.errorf(n.Pos(), "index operator not supported: %s", left)
+	}
+
+	return Any
+}
+
+func (c *checker) checkArguments(n *ast.CallExpression, name string, f *Function, args []Type) {
+	if f.Variadic && len(args) < len(f.Params)-1 {
+		c.errorf(n.Pos(), "wrong number of arguments in call to %s: got %d, want at least %d", name, len(args), len(f.Params)-1)
+		return
+	}
+	if !f.Variadic && (len(args) > len(f.Params) || len(args) < len(f.Params)-f.Optional) {
+		want := fmt.Sprint(len(f.Params))
+		if f.Optional > 0 {
+			want = fmt.Sprintf("%d to %d", len(f.Params)-f.Optional, len(f.Params))
+		}
+		c.errorf(n.Pos(), "wrong number of arguments in call to %s: got %d, want %s", name, len(args), want)
+		return
+	}
+
+	for i, a := range args {
+		want := f.Params[len(f.Params)-1]
+		if i < len(f.Params) {
+			want = f.Params[i]
+		}
+		if !assignable(a, want) {
+			c.errorf(n.Arguments[i].Pos(), "cannot use %s as %s in argument %d to %s", a, want, i+1, name)
+		}
+	}
+}
+
+func (c *checker) checkMember(n *ast.MemberExpression, s *scope) Type {
+	obj := c.checkExpression(n.Object, s)
+	name := n.Member.Value
+
+	switch obj := obj.(type) {
+	case *Struct:
+		if t, ok := obj.Fields[name]; ok {
+			return t
+		}
+		if m, ok := obj.Methods[name]; ok {
+			return m
+		}
+		c.errorf(n.Member.Pos(), "%s has no member %s", obj.Name, name)
+	case *Trait:
+		if _, ok := obj.Methods[name]; ok {
+			return Any
+		}
+		c.errorf(n.Member.Pos(), "%s has no method %s", obj.Name, name)
+	case *TraitValue:
+		if arity, ok := obj.Trait.Methods[name]; ok {
+			params := make([]Type, arity+1)
+			params[0] = obj.Trait
+			for i := 1; i < len(params); i++ {
+				params[i] = Any
+			}
+			return &Function{Params: params, Return: Any}
+		}
+		c.errorf(n.Member.Pos(), "trait %s has no method %s", obj.Trait.Name, name)
+	default:
+		if members, ok := methods[obj]; ok {
+			if m, ok := members[name]; ok {
+				return m
+			}
+			c.errorf(n.Member.Pos(), "no such method: %s.%s", obj, name)
+		} else if obj != Any && obj != Module {
+			c.errorf(n.Member.Pos(), "cannot access member %s of %s", name, obj)
+		}
+	}
+
+	return Any
+}
+
+func (c *checker) checkAssignment(n *ast.AssignmentExpression, s *scope) Type {
+	t := c.checkExpression(n.Value, s)
+
+	switch target := n.Target.(type) {
+	case *ast.Identifier:
+		b, ok := s.lookup(target.Value)
+		if !ok {
+			c.errorf(target.Pos(), "undefined: %s", target.Value)
+			break
+		}
+		if b.declared != nil && !assignable(t, b.declared) {
+			c.errorf(n.Value.Pos(), "cannot use %s as %s in assignment to %s", t, b.declared, target.Value)
+		} else if b.declared == nil && b.typ != t {
+			//The assignment may not run, the binding can hold either type
+			b.typ = Any
+		}
+	case *ast.IndexExpression:
+		c.checkIndex(target, s)
+	case *ast.MemberExpression:
+		obj := c.checkExpression(target.Object, s)
+		st, ok := obj.(*Struct)
+		if !ok {
+			if obj != Any {
+				c.errorf(target.Pos(), "cannot assign member %s of %s", target.Member.Value, obj)
+			}
+			break
+		}
+		want, ok := st.Fields[target.Member.Value]
+		if !ok {
+			c.errorf(target.Member.Pos(), "%s has no field %s", st.Name, target.Member.Value)
+		} else if !assignable(t, want) {
+			c.errorf(n.Value.Pos(), "cannot use %s as %s in assignment to %s.%s", t, want, st.Name, target.Member.Value)
+		}
+	}
+
+	return t
+}
@@ -0,0 +1,64 @@
+package checker
+
+import (
+	"Simply/lexer"
+	"Simply/parser"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x: int = 5; let y = x + 1;", nil},
+		{"func add(a: int, b: int): int { return a + b }; add(1, 2);", nil},
+		{"let f = func(n) { if (n < 2) { 1 } else { n * f(n - 1) } }; f(3);", nil},
+		{`if (true) { let c = "" } else { let c = "s" }; print(c);`, nil},
+		{`let x: int = "five";`, []string{"1:14: cannot use string as int in declaration of x"}},
+		{`let s = "a" + 1;`, []string{"1:9: invalid operation: string + int"}},
+		{"func add(a: int, b: int): int { a + b }; add(1);",
+			[]string{"1:42: wrong number of arguments in call to add: got 1, want 2"}},
+		{`func add(a: int, b: int): int { a + b }; add(1, "2");`,
+			[]string{"1:49: cannot use string as int in argument 2 to add"}},
+		{`func name(): string { return 1 }`, []string{"1:30: cannot use int as string in return"}},
+		{"let x = 5; x();", []string{"1:12: cannot call non-function x (int)"}},
+		{"missing(1);", []string{"1:1: undefined: missing"}},
+		{"let x: foo = 1;", []string{"1:8: unknown type foo"}},
+		{"struct P { x: int }; let p = P(1); p.x = true; p.y;", []string{
+			"1:42: cannot use bool as int in assignment to P.x",
+			"1:50: P has no member y",
+		}},
+		{"trait T { f() }; struct S impl T { }; let s: T = S();",
+			[]string{"1:18: S does not implement T: missing method f"}},
+		{"trait T { f() }; struct S impl T { func f() { 1 } }; let s: T = S(); T.f(s);", nil},
+		{`let a = [1, 2]; a.push(3); let n: int = a.len(); let f: float = 1 + 0.5; let s = "a" + "b";`, nil},
+		{`"abc".reverse();`, []string{"1:7: no such method: string.reverse"}},
+		{`let a = [1]; a["x"];`, []string{"1:16: index must be int, got string"}},
+		{`let x = [1] + 1;`, []string{"1:9: invalid operation: array + int"}},
+		{"sort();", []string{"1:1: wrong number of arguments in call to sort: got 0, want 1 to 2"}},
+		{`"a".split(1);`, []string{"1:11: cannot use int as string in argument 1 to split"}},
+		{`sort([2, 1], func(a, b) { a < b }); print(); input("name: ");`, nil},
+		{`let x = 1; let c = false; if (c) { x = "a" }; println(x + 1);`, nil},
+		{`let x = 1; x = 2; let s: string = x;`, []string{"1:35: cannot use int as string in declaration of s"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewTokenizer(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parse errors %v", tt.input, p.Errors)
+		}
+
+		errs := Check(program)
+		if len(errs) != len(tt.expected) {
+			t.Errorf("%q: expected %d errors, got %v", tt.input, len(tt.expected), errs)
+			continue
+		}
+		for i, e := range errs {
+			if e.Error() != tt.expected[i] {
+				t.Errorf("%q: expected %q, got %q", tt.input, tt.expected[i], e.Error())
+			}
+		}
+	}
+}
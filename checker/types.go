@@ -0,0 +1,130 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Type interface {
+	String() string
+}
+
+type basic string
+
+func (b basic) String() string { return string(b) }
+
+var (
+	Any    Type = basic("any")
+	Int    Type = basic("int")
+	Float  Type = basic("float")
+	String Type = basic("string")
+	Bool   Type = basic("bool")
+	Null   Type = basic("null")
+	Func   Type = basic("func") //Any function, used for `func` annotations
+	Array  Type = basic("array")
+	Map    Type = basic("map")
+	Module Type = basic("module")
+)
+
+var basicTypes = map[string]Type{
+	"any":    Any,
+	"int":    Int,
+	"float":  Float,
+	"string": String,
+	"bool":   Bool,
+	"null":   Null,
+	"func":   Func,
+	"array":  Array,
+	"map":    Map,
+}
+
+type Function struct {
+	Params   []Type
+	Return   Type
+	Optional int  //Number of trailing parameters that can be omitted
+	Variadic bool //The last parameter accepts any number of arguments
+}
+
+func (f *Function) String() string {
+	params := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		params[i] = p.String()
+	}
+	if f.Variadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+
+	return fmt.Sprintf("func(%s): %s", strings.Join(params, ", "), f.Return.String())
+}
+
+// Struct is the type of an instance of a user defined struct.
+type Struct struct {
+	Name    string
+	Fields  map[string]Type
+	Methods map[string]*Function
+	Traits  []*Trait
+}
+
+func (s *Struct) String() string { return s.Name }
+
+func (s *Struct) Implements(t *Trait) bool {
+	for _, v := range s.Traits {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Constructor is the type of the struct name itself, calling it creates an instance.
+type Constructor struct {
+	Struct *Struct
+}
+
+func (c *Constructor) String() string { return "struct " + c.Struct.Name }
+
+// Trait is the type of any value implementing the trait.
+type Trait struct {
+	Name    string
+	Names   []string //Method names in declaration order
+	Methods map[string]int
+}
+
+func (t *Trait) String() string { return t.Name }
+
+// TraitValue is the type of the trait name itself, used for dispatch like Shape.area(s).
+type TraitValue struct {
+	Trait *Trait
+}
+
+func (t *TraitValue) String() string { return "trait " + t.Trait.Name }
+
+func isNumber(t Type) bool {
+	return t == Int || t == Float || t == Any
+}
+
+func assignable(from, to Type) bool {
+	if from == Any || to == Any {
+		return true
+	}
+
+	switch to := to.(type) {
+	case *Trait:
+		if s, ok := from.(*Struct); ok {
+			return s.Implements(to)
+		}
+	case *Function:
+		if f, ok := from.(*Function); ok {
+			return f.String() == to.String()
+		}
+	}
+
+	if to == Func {
+		switch from.(type) {
+		case *Function, *Constructor:
+			return true
+		}
+	}
+
+	return from == to
+}
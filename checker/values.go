@@ -0,0 +1,85 @@
+package checker
+
+import "Simply/types"
+
+// valueTypes derives checker types from evaluated values. Structs and traits are
+// converted once so values of the same struct get the same type.
+type valueTypes struct {
+	structs map[*types.StructType]*Struct
+	traits  map[*types.Trait]*Trait
+}
+
+func newValueTypes() *valueTypes {
+	return &valueTypes{structs: map[*types.StructType]*Struct{}, traits: map[*types.Trait]*Trait{}}
+}
+
+func (v *valueTypes) typeOf(o types.Object) Type {
+	switch o := o.(type) {
+	case *types.Function:
+		return anyFunction(len(o.Parameters))
+	case *types.InternalCall:
+		if o.Signature != nil {
+			return fromSignature(o.Signature)
+		}
+		return Func
+	case *types.TraitMethod:
+		return Func
+	case *types.StructType:
+		return &Constructor{Struct: v.structOf(o)}
+	case *types.Instance:
+		return v.structOf(o.Type)
+	case *types.Trait:
+		return &TraitValue{Trait: v.traitOf(o)}
+	case *types.Module:
+		return Module
+	}
+
+	if t, ok := basicTypes[types.TypeName(o)]; ok {
+		return t
+	}
+	return Any
+}
+
+// Script functions are not annotated at runtime, only their arity is known
+func anyFunction(arity int) *Function {
+	f := &Function{Return: Any}
+	for i := 0; i < arity; i++ {
+		f.Params = append(f.Params, Any)
+	}
+	return f
+}
+
+func (v *valueTypes) structOf(st *types.StructType) *Struct {
+	if s, ok := v.structs[st]; ok {
+		return s
+	}
+
+	s := &Struct{Name: st.Name, Fields: map[string]Type{}, Methods: map[string]*Function{}}
+	v.structs[st] = s
+
+	for _, f := range st.Fields {
+		s.Fields[f] = Any
+	}
+	for name, m := range st.Methods {
+		s.Methods[name] = anyFunction(len(m.Parameters))
+	}
+	for _, t := range st.Traits {
+		s.Traits = append(s.Traits, v.traitOf(t))
+	}
+
+	return s
+}
+
+func (v *valueTypes) traitOf(t *types.Trait) *Trait {
+	if tr, ok := v.traits[t]; ok {
+		return tr
+	}
+
+	tr := &Trait{Name: t.Name, Names: t.Methods, Methods: map[string]int{}}
+	for name, arity := range t.Arity {
+		tr.Methods[name] = arity
+	}
+	v.traits[t] = tr
+
+	return tr
+}
@@ -0,0 +1,242 @@
+// Package coverage records which statements and branches of scripts ran, and
+// reports them as a summary, an annotated HTML page or LCOV.
+package coverage
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"sort"
+)
+
+// statement counts how often a statement ran.
+type statement struct {
+	pos   ast.Position
+	count int
+}
+
+// branch counts how often each arm of a conditional was taken, an if without
+// else still has a false arm.
+type branch struct {
+	pos   ast.Position
+	taken [2]int //True and false arm
+}
+
+type file struct {
+	path       string
+	statements map[ast.Position]*statement
+	branches   map[ast.Position]*branch
+}
+
+// Coverage is an evaluator.CoverageHook recording what ran. It only records the
+// modules it is told about, so scripts read from stdin are left out. The same
+// Coverage can observe several evaluators, one after the other, to cover a
+// whole test session.
+type Coverage struct {
+	Ignore func(path string) bool //Modules not to record, such as tests, nil records all
+
+	files map[string]*file
+}
+
+func New() *Coverage {
+	return &Coverage{files: map[string]*file{}}
+}
+
+// Module implements evaluator.CoverageHook, it records the statements and
+// conditionals of the program so that those that never run are reported too.
+func (c *Coverage) Module(path string, program *ast.Program) {
+	if _, ok := c.files[path]; ok || c.Ignore != nil && c.Ignore(path) {
+		return
+	}
+
+	f := &file{path: path, statements: map[ast.Position]*statement{}, branches: map[ast.Position]*branch{}}
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Program:
+			f.addStatements(n.Statements)
+		case *ast.CodeBlock:
+			f.addStatements(n.Statements)
+		case *ast.ConditionalExpression:
+			f.branch(n.Pos())
+		}
+		return true
+	})
+	c.files[path] = f
+}
+
+// Statement implements evaluator.Hook.
+func (c *Coverage) Statement(n ast.Node, stack []evaluator.Frame) {
+	if f := c.fileOf(stack); f != nil {
+		f.statement(n.Pos()).count++
+	}
+}
+
+// Branch implements evaluator.CoverageHook.
+func (c *Coverage) Branch(n *ast.ConditionalExpression, taken bool, stack []evaluator.Frame) {
+	f := c.fileOf(stack)
+	if f == nil {
+		return
+	}
+
+	b := f.branch(n.Pos())
+	if taken {
+		b.taken[0]++
+	} else {
+		b.taken[1]++
+	}
+}
+
+func (c *Coverage) fileOf(stack []evaluator.Frame) *file {
+	if len(stack) == 0 {
+		return nil
+	}
+	return c.files[stack[len(stack)-1].File]
+}
+
+func (f *file) addStatements(nodes []ast.Node) {
+	for _, n := range nodes {
+		if n != nil {
+			f.statement(n.Pos())
+		}
+	}
+}
+
+func (f *file) statement(pos ast.Position) *statement {
+	s, ok := f.statements[pos]
+	if !ok {
+		s = &statement{pos: pos}
+		f.statements[pos] = s
+	}
+	return s
+}
+
+func (f *file) branch(pos ast.Position) *branch {
+	b, ok := f.branches[pos]
+	if !ok {
+		b = &branch{pos: pos}
+		f.branches[pos] = b
+	}
+	return b
+}
+
+// sortedBranches returns the conditionals in source order.
+func (f *file) sortedBranches() []*branch {
+	result := make([]*branch, 0, len(f.branches))
+	for _, b := range f.branches {
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool { return before(result[i].pos, result[j].pos) })
+	return result
+}
+
+func before(a, b ast.Position) bool {
+	if a.Row != b.Row {
+		return a.Row < b.Row
+	}
+	return a.Col < b.Col
+}
+
+// sortedFiles returns the files by path.
+func (c *Coverage) sortedFiles() []*file {
+	result := make([]*file, 0, len(c.files))
+	for _, f := range c.files {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].path < result[j].path })
+	return result
+}
+
+// Stats are the statements and branch arms of a file, and how many of them ran.
+type Stats struct {
+	File          string
+	Statements    int
+	StatementsRun int
+	Branches      int //Two arms for each conditional
+	BranchesTaken int
+}
+
+func (s *Stats) add(o Stats) {
+	s.Statements += o.Statements
+	s.StatementsRun += o.StatementsRun
+	s.Branches += o.Branches
+	s.BranchesTaken += o.BranchesTaken
+}
+
+// Files returns the stats of each file, by path.
+func (c *Coverage) Files() []Stats {
+	var result []Stats
+	for _, f := range c.sortedFiles() {
+		result = append(result, f.stats())
+	}
+	return result
+}
+
+// Total returns the stats of all files together, File is empty.
+func (c *Coverage) Total() Stats {
+	var total Stats
+	for _, s := range c.Files() {
+		total.add(s)
+	}
+	return total
+}
+
+func (f *file) stats() Stats {
+	s := Stats{File: f.path, Statements: len(f.statements), Branches: 2 * len(f.branches)}
+	for _, st := range f.statements {
+		if st.count > 0 {
+			s.StatementsRun++
+		}
+	}
+	for _, b := range f.branches {
+		for _, taken := range b.taken {
+			if taken > 0 {
+				s.BranchesTaken++
+			}
+		}
+	}
+	return s
+}
+
+// lineCoverage is what ran of the statements and conditionals starting on a line.
+type lineCoverage struct {
+	statements int
+	run        int //Statements that ran
+	hits       int //Runs of the statement that ran the most
+	branches   []*branch
+}
+
+// lines returns the coverage of each line that has a statement or a conditional.
+func (f *file) lines() map[int]*lineCoverage {
+	result := map[int]*lineCoverage{}
+	line := func(row int) *lineCoverage {
+		l, ok := result[row]
+		if !ok {
+			l = &lineCoverage{}
+			result[row] = l
+		}
+		return l
+	}
+
+	for _, s := range f.statements {
+		l := line(s.pos.Row)
+		l.statements++
+		if s.count > 0 {
+			l.run++
+		}
+		l.hits = max(l.hits, s.count)
+	}
+	for _, b := range f.sortedBranches() {
+		l := line(b.pos.Row)
+		l.branches = append(l.branches, b)
+	}
+	return result
+}
+
+// sortedRows returns the rows of the lines in order.
+func sortedRows(lines map[int]*lineCoverage) []int {
+	rows := make([]int, 0, len(lines))
+	for row := range lines {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+	return rows
+}
@@ -0,0 +1,168 @@
+package coverage
+
+import (
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/types"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const lib = `export func abs(n) {
+  if (n < 0) { return -n; }
+  n
+}
+export func unused() {
+  1
+}
+`
+
+const main = `import "lib.syn";
+let x = lib.abs(3);
+if (x > 10) {
+  println("big");
+}
+lib.abs(-1);
+`
+
+// run evaluates main.syn, importing lib.syn, with the coverage as hook.
+func run(t *testing.T, c *Coverage) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	mainPath, libPath := filepath.Join(dir, "main.syn"), filepath.Join(dir, "lib.syn")
+	for path, content := range map[string]string{mainPath: main, libPath: lib} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := evaluator.New()
+	e.SetIO(strings.NewReader(""), &strings.Builder{})
+	e.SetHook(c)
+	program := parser.NewParser(lexer.NewTokenizer(main)).ParseProgram()
+	if result, ok := e.EvalModule(program, mainPath, types.NewContext(nil)).(*types.Error); ok {
+		t.Fatalf("unexpected error %s", result)
+	}
+	return mainPath, libPath
+}
+
+func TestCoverage(t *testing.T) {
+	c := New()
+	mainPath, libPath := run(t, c)
+
+	expected := []Stats{
+		{File: libPath, Statements: 6, StatementsRun: 5, Branches: 2, BranchesTaken: 2},
+		{File: mainPath, Statements: 5, StatementsRun: 4, Branches: 2, BranchesTaken: 1},
+	}
+	if files := c.Files(); !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %+v, got %+v", expected, files)
+	}
+	if total := c.Total(); total != (Stats{Statements: 11, StatementsRun: 9, Branches: 4, BranchesTaken: 3}) {
+		t.Errorf("unexpected total %+v", total)
+	}
+
+	var summary strings.Builder
+	if err := c.WriteSummary(&summary); err != nil {
+		t.Fatal(err)
+	}
+	width := len(mainPath)
+	expectedSummary := fmt.Sprintf("%-*s  %16s  %16s\n", width, "file", "statements", "branches") +
+		fmt.Sprintf("%-*s  %16s  %16s\n", width, libPath, "5/6   83.3%", "2/2  100.0%") +
+		fmt.Sprintf("%-*s  %16s  %16s\n", width, mainPath, "4/5   80.0%", "1/2   50.0%") +
+		fmt.Sprintf("%-*s  %16s  %16s\n", width, "total", "9/11   81.8%", "3/4   75.0%")
+	if summary.String() != expectedSummary {
+		t.Errorf("unexpected summary:\n%s\nexpected:\n%s", summary.String(), expectedSummary)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	//Runs of several evaluators add up
+	c := New()
+	mainPath, _ := run(t, c)
+
+	e := evaluator.New()
+	e.SetIO(strings.NewReader(""), &strings.Builder{})
+	e.SetHook(c)
+	program := parser.NewParser(lexer.NewTokenizer(main)).ParseProgram()
+	e.EvalModule(program, mainPath, types.NewContext(nil))
+
+	f := c.files[mainPath]
+	if count := f.statements[program.Statements[1].Pos()].count; count != 2 {
+		t.Errorf("expected the statement to run twice, got %d", count)
+	}
+	if taken := f.branches[program.Statements[2].Pos()].taken; taken != [2]int{0, 2} {
+		t.Errorf("expected the false arm to be taken twice, got %v", taken)
+	}
+
+	//Code outside of the recorded modules is ignored
+	e = evaluator.New()
+	e.SetHook(c)
+	e.Eval(parser.NewParser(lexer.NewTokenizer("if (true) { 1 }")).ParseProgram(), types.NewContext(nil))
+	if len(c.Files()) != 2 {
+		t.Errorf("expected only the modules to be covered, got %+v", c.Files())
+	}
+
+	//Modules can be left out too
+	c = New()
+	c.Ignore = func(path string) bool { return filepath.Base(path) == "lib.syn" }
+	run(t, c)
+	if files := c.Files(); len(files) != 1 || filepath.Base(files[0].File) != "main.syn" {
+		t.Errorf("expected lib.syn to be ignored, got %+v", files)
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	c := New()
+	mainPath, libPath := run(t, c)
+
+	var out strings.Builder
+	if err := c.WriteLCOV(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := "TN:\n" +
+		"SF:" + libPath + "\n" +
+		"BRDA:2,0,0,1\nBRDA:2,0,1,1\nBRF:2\nBRH:2\n" +
+		"DA:1,1\nDA:2,2\nDA:3,1\nDA:5,1\nDA:6,0\nLF:5\nLH:4\n" +
+		"end_of_record\n" +
+		"SF:" + mainPath + "\n" +
+		"BRDA:3,0,0,0\nBRDA:3,0,1,1\nBRF:2\nBRH:1\n" +
+		"DA:1,1\nDA:2,1\nDA:3,1\nDA:4,0\nDA:6,1\nLF:5\nLH:4\n" +
+		"end_of_record\n"
+	if out.String() != expected {
+		t.Errorf("unexpected LCOV:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	c := New()
+	mainPath, _ := run(t, c)
+
+	var out strings.Builder
+	if err := c.WriteHTML(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<td>total</td><td>9/11   81.8%</td><td>3/4   75.0%</td>`,
+		`<tr class="covered" title="2 of 2 statements ran; if at column 3: true 1 times, false 1 times"><td class="number">2</td><td class="hits">2</td>` +
+			`<td><pre>  if (n &lt; 0) { return -n; }</pre></td></tr>`,
+		`<tr class="partial" title="1 of 1 statements ran; if at column 1: true 0 times, false 1 times"><td class="number">3</td>`,
+		`<tr class="uncovered" title="0 of 1 statements ran"><td class="number">4</td><td class="hits">0</td><td><pre>  println(&#34;big&#34;);</pre></td></tr>`,
+		`<tr><td class="number">5</td><td class="hits"></td><td><pre>}</pre></td></tr>`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected the report to contain %q", expected)
+		}
+	}
+
+	//The sources are needed for the report
+	os.Remove(mainPath)
+	if err := c.WriteHTML(&strings.Builder{}); err == nil {
+		t.Error("expected an error when a source is missing")
+	}
+}
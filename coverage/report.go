@@ -0,0 +1,235 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WriteSummary writes the statements and branches that ran in each file, and in all of them.
+func (c *Coverage) WriteSummary(w io.Writer) error {
+	files := c.Files()
+	names := make([]string, len(files))
+	width := len("total")
+	for i, s := range files {
+		names[i] = displayPath(s.File)
+		width = max(width, len(names[i]))
+	}
+
+	var b strings.Builder
+	row := func(name string, s Stats) {
+		fmt.Fprintf(&b, "%-*s  %16s  %16s\n", width, name,
+			ratio(s.StatementsRun, s.Statements), ratio(s.BranchesTaken, s.Branches))
+	}
+
+	fmt.Fprintf(&b, "%-*s  %16s  %16s\n", width, "file", "statements", "branches")
+	for i, s := range files {
+		row(names[i], s)
+	}
+	row("total", c.Total())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func ratio(n, total int) string {
+	return fmt.Sprintf("%d/%d %7s", n, total, percent(n, total))
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
+
+// displayPath shortens paths below the working directory.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+// WriteLCOV writes the coverage as an LCOV tracefile, with the lines of each
+// file and both arms of its conditionals as branches.
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("TN:\n")
+	for _, f := range c.sortedFiles() {
+		fmt.Fprintf(&b, "SF:%s\n", f.path)
+
+		branches := f.sortedBranches()
+		taken := 0
+		for i, br := range branches {
+			for arm, count := range br.taken {
+				//A condition that never ran has no arm taken, not arms taken zero times
+				hits := "-"
+				if br.taken[0]+br.taken[1] > 0 {
+					hits = strconv.Itoa(count)
+				}
+				if count > 0 {
+					taken++
+				}
+				fmt.Fprintf(&b, "BRDA:%d,%d,%d,%s\n", br.pos.Row, i, arm, hits)
+			}
+		}
+		fmt.Fprintf(&b, "BRF:%d\nBRH:%d\n", 2*len(branches), taken)
+
+		lines := f.lines()
+		found, hit := 0, 0
+		for _, row := range sortedRows(lines) {
+			l := lines[row]
+			if l.statements == 0 {
+				continue
+			}
+			found++
+			if l.hits > 0 {
+				hit++
+			}
+			fmt.Fprintf(&b, "DA:%d,%d\n", row, l.hits)
+		}
+		fmt.Fprintf(&b, "LF:%d\nLH:%d\n", found, hit)
+		b.WriteString("end_of_record\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type htmlReport struct {
+	Files []htmlFile
+	Total htmlStats
+}
+
+type htmlStats struct {
+	Statements string
+	Branches   string
+}
+
+type htmlFile struct {
+	ID    string
+	Name  string
+	Stats htmlStats
+	Lines []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Class  string //Empty for lines without statements
+	Hits   string
+	Title  string
+	Text   string
+}
+
+func newHTMLStats(s Stats) htmlStats {
+	return htmlStats{ratio(s.StatementsRun, s.Statements), ratio(s.BranchesTaken, s.Branches)}
+}
+
+// WriteHTML writes a page with the source of each file, its lines marked by
+// whether they ran. The sources are read again from their files.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	report := htmlReport{Total: newHTMLStats(c.Total())}
+	for i, f := range c.sortedFiles() {
+		content, err := os.ReadFile(f.path)
+		if err != nil {
+			return err
+		}
+
+		hf := htmlFile{ID: fmt.Sprintf("file%d", i), Name: displayPath(f.path), Stats: newHTMLStats(f.stats())}
+		lines := f.lines()
+		for j, text := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			line := htmlLine{Number: j + 1, Text: strings.TrimSuffix(text, "\r")}
+			if l, ok := lines[j+1]; ok {
+				line.Class, line.Title = l.class(), l.title()
+				if l.statements > 0 {
+					line.Hits = strconv.Itoa(l.hits)
+				}
+			}
+			hf.Lines = append(hf.Lines, line)
+		}
+		report.Files = append(report.Files, hf)
+	}
+
+	return htmlTemplate.Execute(w, report)
+}
+
+// class tells whether everything on the line ran, some of it or none of it.
+func (l *lineCoverage) class() string {
+	total, done := l.statements, l.run
+	for _, b := range l.branches {
+		for _, taken := range b.taken {
+			total++
+			if taken > 0 {
+				done++
+			}
+		}
+	}
+
+	switch {
+	case done == 0:
+		return "uncovered"
+	case done < total:
+		return "partial"
+	}
+	return "covered"
+}
+
+func (l *lineCoverage) title() string {
+	var parts []string
+	if l.statements > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d statements ran", l.run, l.statements))
+	}
+	for _, b := range l.branches {
+		parts = append(parts, fmt.Sprintf("if at column %d: true %d times, false %d times", b.pos.Col, b.taken[0], b.taken[1]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+.summary td, .summary th { padding: 0.2em 1em; text-align: right; }
+.summary td:first-child, .summary th:first-child { text-align: left; }
+.source td { padding: 0 0.5em; vertical-align: top; }
+.source pre { margin: 0; }
+.number, .hits { color: #888; text-align: right; }
+.covered { background: #dfd; }
+.partial { background: #ffd; }
+.uncovered { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>Coverage</h1>
+<table class="summary">
+<tr><th>file</th><th>statements</th><th>branches</th></tr>
+{{- range .Files}}
+<tr><td><a href="#{{.ID}}">{{.Name}}</a></td><td>{{.Stats.Statements}}</td><td>{{.Stats.Branches}}</td></tr>
+{{- end}}
+<tr><td>total</td><td>{{.Total.Statements}}</td><td>{{.Total.Branches}}</td></tr>
+</table>
+{{- range .Files}}
+<h2 id="{{.ID}}">{{.Name}}</h2>
+<table class="source">
+{{- range .Lines}}
+<tr{{if .Class}} class="{{.Class}}" title="{{.Title}}"{{end}}><td class="number">{{.Number}}</td><td class="hits">{{.Hits}}</td><td><pre>{{.Text}}</pre></td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,319 @@
+package debugger
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/types"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const prompt = "(debug) "
+
+// listContext is the number of lines list shows around the current one.
+const listContext = 3
+
+// CLI is the command line front end of a Debugger. It pauses before the first
+// statement and reads commands until execution continues.
+type CLI struct {
+	d   *Debugger
+	in  *bufio.Reader
+	out io.Writer
+	dir string //Of the script, files are named relative to it
+
+	stop    Stop
+	frame   int //Selected frame, 0 is the innermost
+	repeat  string
+	sources map[string][]string
+}
+
+type cliCommand struct {
+	names []string
+	args  string
+	help  string
+	run   func(c *CLI, arg string) (action Action, resume bool)
+}
+
+var cliCommands []cliCommand
+
+func init() {
+	cliCommands = []cliCommand{
+		{[]string{"break", "b"}, "[[file:]line]", "set a breakpoint, or list them", (*CLI).breakCommand},
+		{[]string{"clear"}, "[[file:]line]", "remove a breakpoint, or all of them", (*CLI).clearCommand},
+		{[]string{"continue", "c"}, "", "run until a breakpoint", resume(Continue)},
+		{[]string{"step", "s"}, "", "step into the next statement", resume(StepIn)},
+		{[]string{"next", "n"}, "", "step over calls to the next statement", resume(StepOver)},
+		{[]string{"out", "o"}, "", "run until the current function returns", resume(StepOut)},
+		{[]string{"stack", "bt"}, "", "show the active calls", (*CLI).stackCommand},
+		{[]string{"frame", "f"}, "<n>", "select the frame of print, vars and list", (*CLI).frameCommand},
+		{[]string{"vars", "v"}, "", "show the variables of the frame", (*CLI).varsCommand},
+		{[]string{"print", "p"}, "<expr>", "evaluate an expression in the frame", (*CLI).printCommand},
+		{[]string{"list", "l"}, "", "show the source around the frame", (*CLI).listCommand},
+		{[]string{"help", "h"}, "", "show this help", (*CLI).helpCommand},
+		{[]string{"quit", "q"}, "", "stop the script", resume(Quit)},
+	}
+}
+
+func resume(action Action) func(c *CLI, arg string) (Action, bool) {
+	return func(c *CLI, arg string) (Action, bool) {
+		return action, true
+	}
+}
+
+// NewCLI returns a front end reading commands from in and writing to out. The
+// script should read its input from the same reader.
+func NewCLI(in *bufio.Reader, out io.Writer) *CLI {
+	c := &CLI{in: in, out: out, sources: map[string][]string{}}
+	c.d = New(c.paused)
+	c.d.StopOnEntry = true
+	return c
+}
+
+// Run debugs the program loaded from path, see Debugger.Run.
+func (c *CLI) Run(e *evaluator.Evaluator, program *ast.Program, path string, ctx *types.Context) (types.Object, bool) {
+	if abs, err := filepath.Abs(path); err == nil {
+		c.dir = filepath.Dir(abs)
+	}
+	return c.d.Run(e, program, path, ctx)
+}
+
+func (c *CLI) paused(stop Stop) Action {
+	c.stop, c.frame = stop, 0
+	fmt.Fprintf(c.out, "%s at %s\n", stop.Reason, c.where(stop.Top()))
+	c.printLine(stop.Top().File, stop.Top().Pos.Row)
+
+	for {
+		fmt.Fprint(c.out, prompt)
+		line, err := c.in.ReadString('\n')
+		if err != nil && line == "" {
+			//The commands ended
+			fmt.Fprintln(c.out)
+			return Quit
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = c.repeat
+		}
+		c.repeat = line
+		if line == "" {
+			continue
+		}
+
+		name, arg, _ := strings.Cut(line, " ")
+		cmd, ok := lookupCommand(name)
+		if !ok {
+			fmt.Fprintf(c.out, "unknown command %s, try help\n", name)
+			continue
+		}
+		if action, resume := cmd.run(c, strings.TrimSpace(arg)); resume {
+			return action
+		}
+	}
+}
+
+func lookupCommand(name string) (cliCommand, bool) {
+	for _, cmd := range cliCommands {
+		for _, n := range cmd.names {
+			if n == name {
+				return cmd, true
+			}
+		}
+	}
+	return cliCommand{}, false
+}
+
+// selected returns the frame chosen with the frame command.
+func (c *CLI) selected() evaluator.Frame {
+	return c.stop.Stack[len(c.stop.Stack)-1-c.frame]
+}
+
+// where names the line of the frame and its function.
+func (c *CLI) where(f evaluator.Frame) string {
+	result := fmt.Sprintf("%s:%d", c.relative(f.File), f.Pos.Row)
+	if f.Function != "" {
+		result += " in " + f.Function
+	}
+	return result
+}
+
+func (c *CLI) relative(file string) string {
+	if rel, err := filepath.Rel(c.dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return file
+}
+
+// location parses [file:]line, the file is relative to the script.
+func (c *CLI) location(arg string) (string, int, error) {
+	file, line := c.selected().File, arg
+	if i := strings.LastIndexByte(arg, ':'); i >= 0 {
+		file, line = arg[:i], arg[i+1:]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(c.dir, file)
+		}
+	}
+
+	n, err := strconv.Atoi(line)
+	if err != nil || n < 1 {
+		return "", 0, fmt.Errorf("invalid line %q", line)
+	}
+	return file, n, nil
+}
+
+func (c *CLI) breakCommand(arg string) (Action, bool) {
+	if arg == "" {
+		breakpoints := c.d.Breakpoints()
+		if len(breakpoints) == 0 {
+			fmt.Fprintln(c.out, "no breakpoints")
+		}
+		for _, b := range breakpoints {
+			fmt.Fprintf(c.out, "%s:%d\n", c.relative(b.File), b.Line)
+		}
+		return Continue, false
+	}
+
+	file, line, err := c.location(arg)
+	if err == nil {
+		line, err = c.d.SetBreakpoint(file, line)
+	}
+	if err != nil {
+		fmt.Fprintln(c.out, "error:", err)
+		return Continue, false
+	}
+	fmt.Fprintf(c.out, "breakpoint at %s:%d\n", c.relative(file), line)
+	return Continue, false
+}
+
+func (c *CLI) clearCommand(arg string) (Action, bool) {
+	if arg == "" {
+		c.d.ClearBreakpoints("")
+		fmt.Fprintln(c.out, "cleared all breakpoints")
+		return Continue, false
+	}
+
+	file, line, err := c.location(arg)
+	if err != nil {
+		fmt.Fprintln(c.out, "error:", err)
+		return Continue, false
+	}
+	if !c.d.ClearBreakpoint(file, line) {
+		fmt.Fprintf(c.out, "no breakpoint at %s:%d\n", c.relative(file), line)
+		return Continue, false
+	}
+	fmt.Fprintf(c.out, "cleared %s:%d\n", c.relative(file), line)
+	return Continue, false
+}
+
+func (c *CLI) stackCommand(arg string) (Action, bool) {
+	for i := range c.stop.Stack {
+		marker := "  "
+		if i == c.frame {
+			marker = "=>"
+		}
+		f := c.stop.Stack[len(c.stop.Stack)-1-i]
+		fmt.Fprintf(c.out, "%s #%d %s\n", marker, i, c.where(f))
+	}
+	return Continue, false
+}
+
+func (c *CLI) frameCommand(arg string) (Action, bool) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 || n >= len(c.stop.Stack) {
+		fmt.Fprintf(c.out, "error: frame must be between 0 and %d\n", len(c.stop.Stack)-1)
+		return Continue, false
+	}
+
+	c.frame = n
+	fmt.Fprintf(c.out, "#%d %s\n", n, c.where(c.selected()))
+	return Continue, false
+}
+
+func (c *CLI) varsCommand(arg string) (Action, bool) {
+	ctx := c.selected().Ctx
+	names := ctx.Names()
+	if len(names) == 0 {
+		fmt.Fprintln(c.out, "no variables")
+	}
+	for _, name := range names {
+		value, _ := ctx.Get(name)
+		fmt.Fprintf(c.out, "%s = %s\n", name, types.Inspect(value))
+	}
+	return Continue, false
+}
+
+func (c *CLI) printCommand(arg string) (Action, bool) {
+	if arg == "" {
+		fmt.Fprintln(c.out, "error: print needs an expression")
+		return Continue, false
+	}
+
+	value, err := c.d.Eval(arg, c.selected())
+	if err != nil {
+		fmt.Fprintln(c.out, "error:", err)
+		return Continue, false
+	}
+	fmt.Fprintln(c.out, types.Inspect(value))
+	return Continue, false
+}
+
+func (c *CLI) listCommand(arg string) (Action, bool) {
+	f := c.selected()
+	lines, err := c.source(f.File)
+	if err != nil {
+		fmt.Fprintln(c.out, "error:", err)
+		return Continue, false
+	}
+
+	for row := max(f.Pos.Row-listContext, 1); row <= min(f.Pos.Row+listContext, len(lines)); row++ {
+		marker := "  "
+		if row == f.Pos.Row {
+			marker = "=>"
+		}
+		fmt.Fprintln(c.out, strings.TrimRight(fmt.Sprintf("%s %3d\t%s", marker, row, lines[row-1]), " \t"))
+	}
+	return Continue, false
+}
+
+func (c *CLI) helpCommand(arg string) (Action, bool) {
+	for _, cmd := range cliCommands {
+		usage := strings.Join(cmd.names, ", ")
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		fmt.Fprintf(c.out, "  %-28s%s\n", usage, cmd.help)
+	}
+	fmt.Fprintln(c.out, "An empty line repeats the last command.")
+	return Continue, false
+}
+
+// printLine writes a line of the file, indented like list.
+func (c *CLI) printLine(file string, row int) {
+	lines, err := c.source(file)
+	if err != nil || row < 1 || row > len(lines) {
+		return
+	}
+	fmt.Fprintf(c.out, "=> %3d\t%s\n", row, lines[row-1])
+}
+
+func (c *CLI) source(file string) ([]string, error) {
+	if lines, ok := c.sources[file]; ok {
+		return lines, nil
+	}
+	if file == "" {
+		return nil, fmt.Errorf("the source is not a file")
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+	c.sources[file] = lines
+	return lines, nil
+}
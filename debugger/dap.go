@@ -0,0 +1,473 @@
+package debugger
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/types"
+	"Simply/wire"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+)
+
+// threadID names the only thread of a script.
+const threadID = 1
+
+// Launcher prepares the script at path for debugging, with its output written to out.
+type Launcher func(path string, args []string, out io.Writer) (*evaluator.Evaluator, *ast.Program, error)
+
+type adapter struct {
+	conn   *wire.Conn
+	launch Launcher
+	d      *Debugger
+	seqMu  sync.Mutex //Serializes numbering and writing messages
+	seq    int
+
+	//Columns and lines are numbered from 1 unless the client asks otherwise
+	lineBase, columnBase int
+
+	e                    *evaluator.Evaluator
+	program              *ast.Program
+	path                 string
+	launched, configured bool
+	done                 chan struct{} //Closed when the script ended, nil until it started
+	resume               chan Action
+	after                []func() //Run once the response to the current request is written
+
+	mu   sync.Mutex //Guards stop, which the goroutine of the script sets
+	stop *Stop
+	refs []any //Contexts and objects the client may list the variables of, numbered from 1
+}
+
+type dapHandler func(a *adapter, args json.RawMessage) (any, error)
+
+// handleDAP decodes the arguments of a request before calling fn.
+func handleDAP[A any](fn func(a *adapter, args A) (any, error)) dapHandler {
+	return func(a *adapter, raw json.RawMessage) (any, error) {
+		var args A
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, err
+			}
+		}
+		return fn(a, args)
+	}
+}
+
+var dapHandlers map[string]dapHandler
+
+func init() {
+	dapHandlers = map[string]dapHandler{
+		"initialize":        handleDAP((*adapter).initialize),
+		"launch":            handleDAP((*adapter).launchScript),
+		"setBreakpoints":    handleDAP((*adapter).setBreakpoints),
+		"configurationDone": handleDAP((*adapter).configurationDone),
+		"threads":           handleDAP((*adapter).threads),
+		"stackTrace":        handleDAP((*adapter).stackTrace),
+		"scopes":            handleDAP((*adapter).scopes),
+		"variables":         handleDAP((*adapter).variables),
+		"evaluate":          handleDAP((*adapter).evaluate),
+		"continue":          resumeWith(Continue),
+		"next":              resumeWith(StepOver),
+		"stepIn":            resumeWith(StepIn),
+		"stepOut":           resumeWith(StepOut),
+		"pause":             handleDAP((*adapter).interrupt),
+		"terminate":         handleDAP((*adapter).terminate),
+		"disconnect":        handleDAP((*adapter).terminate),
+	}
+}
+
+// ServeDAP speaks the debug adapter protocol over in and out until the client
+// disconnects. The client names the script in its launch request.
+func ServeDAP(in io.Reader, out io.Writer, launch Launcher) error {
+	a := &adapter{conn: wire.NewConn(in, out), launch: launch, lineBase: 1, columnBase: 1, resume: make(chan Action, 1)}
+	a.d = New(a.paused)
+	defer a.terminate(nil)
+
+	for {
+		content, err := a.conn.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req dapRequest
+		if err := json.Unmarshal(content, &req); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		var body any
+		h, ok := dapHandlers[req.Command]
+		if ok {
+			body, err = h(a, req.Arguments)
+		} else {
+			err = fmt.Errorf("unsupported request %s", req.Command)
+		}
+		if err := a.respond(req, body, err); err != nil {
+			return err
+		}
+
+		for _, fn := range a.after {
+			fn()
+		}
+		a.after = nil
+
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+func (a *adapter) write(fill func(seq int) any) error {
+	a.seqMu.Lock()
+	defer a.seqMu.Unlock()
+
+	a.seq++
+	return a.conn.Write(fill(a.seq))
+}
+
+func (a *adapter) respond(req dapRequest, body any, err error) error {
+	return a.write(func(seq int) any {
+		resp := dapResponse{Seq: seq, Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+		if err != nil {
+			resp.Message, resp.Body = err.Error(), nil
+		}
+		return resp
+	})
+}
+
+// event sends an event, the script goroutine sends them too.
+func (a *adapter) event(name string, body any) {
+	a.write(func(seq int) any {
+		return dapEvent{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+func (a *adapter) initialize(args initializeArguments) (any, error) {
+	if args.LinesStartAt1 != nil && !*args.LinesStartAt1 {
+		a.lineBase = 0
+	}
+	if args.ColumnsStartAt1 != nil && !*args.ColumnsStartAt1 {
+		a.columnBase = 0
+	}
+
+	a.after = append(a.after, func() { a.event("initialized", nil) })
+	return capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsEvaluateForHovers:        true,
+		SupportsTerminateRequest:         true,
+	}, nil
+}
+
+func (a *adapter) launchScript(args launchArguments) (any, error) {
+	if a.launched {
+		return nil, errors.New("the script is already launched")
+	}
+	if args.Program == "" {
+		return nil, errors.New("launch needs the path of a program")
+	}
+
+	e, program, err := a.launch(args.Program, args.Args, outputWriter{a})
+	if err != nil {
+		return nil, err
+	}
+
+	a.e, a.program, a.path = e, program, args.Program
+	a.d.StopOnEntry = args.StopOnEntry
+	a.launched = true
+	a.after = append(a.after, a.start)
+	return nil, nil
+}
+
+func (a *adapter) configurationDone(args json.RawMessage) (any, error) {
+	a.configured = true
+	a.after = append(a.after, a.start)
+	return nil, nil
+}
+
+// start runs the script once it is launched and the breakpoints are configured.
+func (a *adapter) start() {
+	if !a.launched || !a.configured || a.done != nil {
+		return
+	}
+
+	a.done = make(chan struct{})
+	go func() {
+		defer close(a.done)
+
+		result, finished := a.d.Run(a.e, a.program, a.path, types.NewContext(nil))
+		exitCode := 0
+		if err, ok := result.(*types.Error); ok && finished {
+			a.event("output", outputEvent{Category: "stderr", Output: errorText(a.path, err)})
+			exitCode = 1
+		}
+		a.event("exited", exitedEvent{ExitCode: exitCode})
+		a.event("terminated", nil)
+	}()
+}
+
+func errorText(path string, err *types.Error) string {
+	if err.Position.Row == 0 {
+		return fmt.Sprintf("%s: %s\n", path, err.Value)
+	}
+	return fmt.Sprintf("%s:%s: %s\n", path, err.Position, err.Value)
+}
+
+// outputWriter sends what the script prints as output events.
+type outputWriter struct {
+	a *adapter
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	w.a.event("output", outputEvent{Category: "stdout", Output: string(p)})
+	return len(p), nil
+}
+
+func (a *adapter) setBreakpoints(args setBreakpointsArguments) (any, error) {
+	path := args.Source.Path
+	if path == "" {
+		return nil, errors.New("setBreakpoints needs the path of the source")
+	}
+
+	a.d.ClearBreakpoints(path)
+	result := breakpointsBody{Breakpoints: []breakpoint{}}
+	for _, b := range args.Breakpoints {
+		line, err := a.d.SetBreakpoint(path, b.Line-a.lineBase+1)
+		if err != nil {
+			result.Breakpoints = append(result.Breakpoints, breakpoint{Line: b.Line, Message: err.Error()})
+			continue
+		}
+		result.Breakpoints = append(result.Breakpoints, breakpoint{
+			Verified: true,
+			Line:     line + a.lineBase - 1,
+			Source:   &source{Name: filepath.Base(path), Path: path},
+		})
+	}
+	return result, nil
+}
+
+func (a *adapter) threads(args json.RawMessage) (any, error) {
+	return threadsBody{Threads: []thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+// paused is called by the goroutine of the script and waits for the client to resume it.
+func (a *adapter) paused(stop Stop) Action {
+	a.mu.Lock()
+	a.stop = &stop
+	a.mu.Unlock()
+
+	a.event("stopped", stoppedEvent{Reason: string(stop.Reason), ThreadID: threadID, AllThreadsStopped: true})
+	return <-a.resume
+}
+
+// stopped returns the pause the script is in.
+func (a *adapter) stopped() (*Stop, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stop == nil {
+		return nil, errors.New("the script is not paused")
+	}
+	return a.stop, nil
+}
+
+// frame returns a frame by its id, 0 is the innermost.
+func (a *adapter) frame(id int) (evaluator.Frame, error) {
+	stop, err := a.stopped()
+	if err != nil {
+		return evaluator.Frame{}, err
+	}
+	if id < 0 || id >= len(stop.Stack) {
+		return evaluator.Frame{}, fmt.Errorf("no frame %d", id)
+	}
+	return stop.Stack[len(stop.Stack)-1-id], nil
+}
+
+func (a *adapter) stackTrace(args stackTraceArguments) (any, error) {
+	stop, err := a.stopped()
+	if err != nil {
+		return nil, err
+	}
+
+	result := stackTraceBody{StackFrames: []stackFrame{}, TotalFrames: len(stop.Stack)}
+	for id := args.StartFrame; id < len(stop.Stack); id++ {
+		if args.Levels > 0 && len(result.StackFrames) == args.Levels {
+			break
+		}
+
+		f := stop.Stack[len(stop.Stack)-1-id]
+		sf := stackFrame{
+			ID:     id,
+			Name:   f.Name(),
+			Line:   f.Pos.Row + a.lineBase - 1,
+			Column: f.Pos.Col + a.columnBase - 1,
+		}
+		if f.File != "" {
+			sf.Source = &source{Name: filepath.Base(f.File), Path: f.File}
+		}
+		result.StackFrames = append(result.StackFrames, sf)
+	}
+	return result, nil
+}
+
+func (a *adapter) scopes(args scopesArguments) (any, error) {
+	f, err := a.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	globals := f.Ctx
+	for globals.Parent() != nil {
+		globals = globals.Parent()
+	}
+
+	result := scopesBody{}
+	if f.Ctx != globals {
+		result.Scopes = append(result.Scopes, scope{Name: "Locals", VariablesReference: a.reference(f.Ctx)})
+	}
+	result.Scopes = append(result.Scopes, scope{Name: "Globals", VariablesReference: a.reference(globals)})
+	return result, nil
+}
+
+// reference numbers a context or object so that the client can list its variables.
+func (a *adapter) reference(v any) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.refs = append(a.refs, v)
+	return len(a.refs)
+}
+
+func (a *adapter) variables(args variablesArguments) (any, error) {
+	if _, err := a.stopped(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	if args.VariablesReference < 1 || args.VariablesReference > len(a.refs) {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("no variables %d", args.VariablesReference)
+	}
+	container := a.refs[args.VariablesReference-1]
+	a.mu.Unlock()
+
+	result := variablesBody{Variables: []variable{}}
+	add := func(name string, value types.Object) {
+		result.Variables = append(result.Variables, variable{
+			Name:               name,
+			Value:              types.Inspect(value),
+			Type:               types.TypeName(value),
+			VariablesReference: a.children(value),
+		})
+	}
+
+	switch c := container.(type) {
+	case *types.Context:
+		for _, name := range c.Names() {
+			value, _ := c.Get(name)
+			add(name, value)
+		}
+	case *types.Array:
+		for i, el := range c.Elements {
+			add(fmt.Sprint(i), el)
+		}
+	case *types.Map:
+		for _, pair := range c.Pairs() {
+			add(types.Inspect(pair.Key), pair.Value)
+		}
+	case *types.Instance:
+		for _, field := range c.Type.Fields {
+			add(field, c.Fields[field])
+		}
+	}
+	return result, nil
+}
+
+// children returns the reference of the elements or fields of a value, 0 when it has none.
+func (a *adapter) children(value types.Object) int {
+	switch v := value.(type) {
+	case *types.Array:
+		if len(v.Elements) > 0 {
+			return a.reference(v)
+		}
+	case *types.Map:
+		if v.Len() > 0 {
+			return a.reference(v)
+		}
+	case *types.Instance:
+		if len(v.Type.Fields) > 0 {
+			return a.reference(v)
+		}
+	}
+	return 0
+}
+
+func (a *adapter) evaluate(args evaluateArguments) (any, error) {
+	id := 0
+	if args.FrameID != nil {
+		id = *args.FrameID
+	}
+	f, err := a.frame(id)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := a.d.Eval(args.Expression, f)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateBody{Result: types.Inspect(value), Type: types.TypeName(value), VariablesReference: a.children(value)}, nil
+}
+
+func resumeWith(action Action) dapHandler {
+	return handleDAP(func(a *adapter, args threadArguments) (any, error) {
+		if _, err := a.stopped(); err != nil {
+			return nil, err
+		}
+
+		a.mu.Lock()
+		a.stop, a.refs = nil, nil
+		a.mu.Unlock()
+
+		//Resumed once the response is written, so that it comes before the next stop
+		a.after = append(a.after, func() { a.resume <- action })
+		if action == Continue {
+			return continueBody{AllThreadsContinued: true}, nil
+		}
+		return nil, nil
+	})
+}
+
+func (a *adapter) interrupt(args threadArguments) (any, error) {
+	a.d.Interrupt()
+	return nil, nil
+}
+
+// terminate stops the script and waits for it to end.
+func (a *adapter) terminate(args json.RawMessage) (any, error) {
+	if a.done == nil {
+		return nil, nil
+	}
+
+	a.d.Stop()
+	a.mu.Lock()
+	a.stop, a.refs = nil, nil
+	a.mu.Unlock()
+
+	//The script may be pausing right now, a pending action is enough to end it then
+	select {
+	case a.resume <- Quit:
+	default:
+	}
+
+	<-a.done
+	return nil, nil
+}
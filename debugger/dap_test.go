@@ -0,0 +1,328 @@
+package debugger
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/wire"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type dapMessage struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// dapClient sends requests to an adapter and reads its responses and events.
+type dapClient struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *wire.Conn
+	seq    int
+	events []dapMessage //Read while waiting for a response
+	output string
+	served chan error
+}
+
+func newDAPClient(t *testing.T) *dapClient {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &dapClient{t: t, in: inW, out: wire.NewConn(outR, nil), served: make(chan error, 1)}
+
+	go func() {
+		c.served <- ServeDAP(inR, outW, loadScript)
+		outW.Close()
+	}()
+	return c
+}
+
+func loadScript(path string, args []string, out io.Writer) (*evaluator.Evaluator, *ast.Program, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := parser.NewParser(lexer.NewTokenizer(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		return nil, nil, errors.New(p.Errors[0])
+	}
+
+	e := evaluator.New()
+	e.SetIO(strings.NewReader(""), out)
+	return e, program, nil
+}
+
+func (c *dapClient) read() dapMessage {
+	c.t.Helper()
+
+	content, err := c.out.Read()
+	if err != nil {
+		c.t.Fatalf("reading a message: %v", err)
+	}
+	var msg dapMessage
+	if err := json.Unmarshal(content, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	if msg.Event == "output" {
+		var body outputEvent
+		json.Unmarshal(msg.Body, &body)
+		c.output += body.Output
+	}
+	return msg
+}
+
+// request sends a request and decodes the body of its response into body.
+func (c *dapClient) request(command string, args any, body any) dapMessage {
+	c.t.Helper()
+
+	c.seq++
+	req := map[string]any{"seq": c.seq, "type": "request", "command": command}
+	if args != nil {
+		req["arguments"] = args
+	}
+	if err := wire.NewConn(nil, c.in).Write(req); err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		msg := c.read()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		if msg.RequestSeq != c.seq {
+			c.t.Fatalf("%s: unexpected response to request %d", command, msg.RequestSeq)
+		}
+		if body != nil && msg.Success {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return msg
+	}
+}
+
+// wait returns the next event with the name, skipping others.
+func (c *dapClient) wait(name string) dapMessage {
+	c.t.Helper()
+
+	for {
+		var msg dapMessage
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.read()
+		}
+		if msg.Event == name {
+			return msg
+		}
+	}
+}
+
+// stopped waits until the script pauses and returns why.
+func (c *dapClient) stopped() string {
+	c.t.Helper()
+
+	var body stoppedEvent
+	json.Unmarshal(c.wait("stopped").Body, &body)
+	return body.Reason
+}
+
+// top returns the name and line of the innermost frame.
+func (c *dapClient) top() (string, int) {
+	c.t.Helper()
+
+	var trace stackTraceBody
+	c.request("stackTrace", map[string]any{"threadId": threadID}, &trace)
+	return trace.StackFrames[0].Name, trace.StackFrames[0].Line
+}
+
+func (c *dapClient) variables(ref int) map[string]string {
+	c.t.Helper()
+
+	var body variablesBody
+	if resp := c.request("variables", variablesArguments{VariablesReference: ref}, &body); !resp.Success {
+		c.t.Fatalf("variables %d: %s", ref, resp.Message)
+	}
+	result := map[string]string{}
+	for _, v := range body.Variables {
+		result[v.Name] = v.Value
+	}
+	return result
+}
+
+func (c *dapClient) disconnect() {
+	c.t.Helper()
+
+	c.request("disconnect", nil, nil)
+	if err := <-c.served; err != nil {
+		c.t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDAP(t *testing.T) {
+	dir := writeScripts(t)
+	main, lib := filepath.Join(dir, "main.syn"), filepath.Join(dir, "lib.syn")
+
+	c := newDAPClient(t)
+	var caps capabilities
+	c.request("initialize", map[string]any{"adapterID": "simply"}, &caps)
+	if !caps.SupportsConfigurationDoneRequest {
+		t.Error("expected configurationDone to be supported")
+	}
+	c.wait("initialized")
+
+	if resp := c.request("launch", launchArguments{Program: main}, nil); !resp.Success {
+		t.Fatal(resp.Message)
+	}
+
+	var bps breakpointsBody
+	c.request("setBreakpoints", map[string]any{
+		"source":      map[string]string{"path": main},
+		"breakpoints": []map[string]int{{"line": 4}, {"line": 6}, {"line": 40}},
+	}, &bps)
+	var verified []int
+	for _, b := range bps.Breakpoints {
+		if b.Verified {
+			verified = append(verified, b.Line)
+		}
+	}
+	if !reflect.DeepEqual(verified, []int{4, 7}) || bps.Breakpoints[2].Message != "no statement at or after line 40 of main.syn" {
+		t.Errorf("unexpected breakpoints %+v", bps.Breakpoints)
+	}
+	c.request("setBreakpoints", map[string]any{
+		"source":      map[string]string{"path": main},
+		"breakpoints": []map[string]int{{"line": 4}},
+	}, nil)
+	c.request("setBreakpoints", map[string]any{
+		"source":      map[string]string{"path": lib},
+		"breakpoints": []map[string]int{{"line": 2}},
+	}, nil)
+	c.request("configurationDone", nil, nil)
+
+	if reason := c.stopped(); reason != "breakpoint" {
+		t.Errorf("expected to stop at a breakpoint, got %s", reason)
+	}
+
+	var trace stackTraceBody
+	c.request("stackTrace", map[string]any{"threadId": threadID}, &trace)
+	if len(trace.StackFrames) != 2 || trace.TotalFrames != 2 {
+		t.Fatalf("expected 2 frames, got %+v", trace)
+	}
+	for i, expected := range []stackFrame{
+		{ID: 0, Name: "add", Source: &source{Name: "main.syn", Path: main}, Line: 4, Column: 3},
+		{ID: 1, Name: "main.syn", Source: &source{Name: "main.syn", Path: main}, Line: 7, Column: 1},
+	} {
+		if !reflect.DeepEqual(trace.StackFrames[i], expected) {
+			t.Errorf("frame %d: expected %+v, got %+v", i, expected, trace.StackFrames[i])
+		}
+	}
+
+	var scopes scopesBody
+	c.request("scopes", scopesArguments{FrameID: 0}, &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" || scopes.Scopes[1].Name != "Globals" {
+		t.Fatalf("unexpected scopes %+v", scopes)
+	}
+	if locals := c.variables(scopes.Scopes[0].VariablesReference); !reflect.DeepEqual(locals, map[string]string{"a": "1", "b": "2", "sum": "3"}) {
+		t.Errorf("unexpected locals %v", locals)
+	}
+	if globals := c.variables(scopes.Scopes[1].VariablesReference); globals["add"] != "func(a, b)" || globals["lib"] != "module lib" {
+		t.Errorf("unexpected globals %v", globals)
+	}
+
+	var value evaluateBody
+	c.request("evaluate", map[string]any{"expression": `[sum, {"k": a}]`, "frameId": 0}, &value)
+	if value.Result != `[3, {"k": 1}]` || value.Type != "array" || value.VariablesReference == 0 {
+		t.Fatalf("unexpected value %+v", value)
+	}
+	elements := c.variables(value.VariablesReference)
+	if !reflect.DeepEqual(elements, map[string]string{"0": "3", "1": `{"k": 1}`}) {
+		t.Errorf("unexpected elements %v", elements)
+	}
+	if resp := c.request("evaluate", map[string]any{"expression": "nope", "frameId": 1}, nil); resp.Success || resp.Message != "identifier not found: nope" {
+		t.Errorf("expected evaluating an undefined name to fail, got %+v", resp)
+	}
+
+	c.request("next", map[string]any{"threadId": threadID}, nil)
+	if reason := c.stopped(); reason != "step" {
+		t.Errorf("expected to stop after a step, got %s", reason)
+	}
+	if name, line := c.top(); name != "main.syn" || line != 8 {
+		t.Errorf("expected to step to main.syn:8, got %s:%d", name, line)
+	}
+
+	c.request("continue", map[string]any{"threadId": threadID}, nil)
+	c.stopped()
+	if name, line := c.top(); name != "double" || line != 2 {
+		t.Errorf("expected to stop in double at line 2, got %s:%d", name, line)
+	}
+
+	c.request("stepOut", map[string]any{"threadId": threadID}, nil)
+	c.stopped()
+	if name, line := c.top(); name != "main.syn" || line != 9 {
+		t.Errorf("expected to step out to main.syn:9, got %s:%d", name, line)
+	}
+
+	c.request("continue", map[string]any{"threadId": threadID}, nil)
+	var exited exitedEvent
+	json.Unmarshal(c.wait("exited").Body, &exited)
+	c.wait("terminated")
+	if exited.ExitCode != 0 || c.output != "6\n" {
+		t.Errorf("expected exit code 0 and output 6, got %d and %q", exited.ExitCode, c.output)
+	}
+
+	if resp := c.request("stackTrace", map[string]any{"threadId": threadID}, nil); resp.Success || resp.Message != "the script is not paused" {
+		t.Errorf("expected stackTrace to fail once the script ended, got %+v", resp)
+	}
+	c.disconnect()
+}
+
+func TestDAPErrors(t *testing.T) {
+	dir := writeScripts(t)
+	broken := filepath.Join(dir, "broken.syn")
+	if err := os.WriteFile(broken, []byte("let a = 1;\nnope();\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newDAPClient(t)
+	if resp := c.request("launch", launchArguments{Program: filepath.Join(dir, "missing.syn")}, nil); resp.Success {
+		t.Error("expected launching a missing script to fail")
+	}
+	if resp := c.request("restart", nil, nil); resp.Success || resp.Message != "unsupported request restart" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	c.request("launch", launchArguments{Program: broken}, nil)
+	c.request("configurationDone", nil, nil)
+
+	var exited exitedEvent
+	json.Unmarshal(c.wait("exited").Body, &exited)
+	if exited.ExitCode != 1 || c.output != broken+":2:1: identifier not found: nope\n" {
+		t.Errorf("expected exit code 1 and the error, got %d and %q", exited.ExitCode, c.output)
+	}
+	c.disconnect()
+
+	//Disconnecting while the script is paused stops it
+	c = newDAPClient(t)
+	c.request("launch", launchArguments{Program: filepath.Join(dir, "main.syn"), StopOnEntry: true}, nil)
+	c.request("configurationDone", nil, nil)
+	if reason := c.stopped(); reason != "entry" {
+		t.Errorf("expected to stop on entry, got %s", reason)
+	}
+	c.disconnect()
+	if c.output != "" {
+		t.Errorf("expected the script to be stopped before printing, got %q", c.output)
+	}
+}
@@ -0,0 +1,358 @@
+// Package debugger pauses the evaluation of scripts at breakpoints and steps
+// through them. A front end, the command line or a debug adapter, decides how
+// to continue each time execution pauses.
+package debugger
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/types"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Action says how execution continues after a pause.
+type Action int
+
+const (
+	Continue Action = iota //Run until a breakpoint
+	StepIn                 //Pause at the next statement
+	StepOver               //Pause at the next statement of the current or an outer frame
+	StepOut                //Pause at the next statement of an outer frame
+	Quit                   //Stop the script
+)
+
+// Reason is why execution paused, named as in the debug adapter protocol.
+type Reason string
+
+const (
+	ReasonEntry      Reason = "entry"
+	ReasonBreakpoint Reason = "breakpoint"
+	ReasonStep       Reason = "step"
+	ReasonPause      Reason = "pause"
+)
+
+// Stop describes a pause, Stack holds the active frames, innermost last.
+type Stop struct {
+	Reason Reason
+	Stack  []evaluator.Frame
+}
+
+// Top is the frame execution paused in.
+func (s Stop) Top() evaluator.Frame {
+	return s.Stack[len(s.Stack)-1]
+}
+
+// location is a line of a file in a frame at some depth of the stack.
+type location struct {
+	depth int
+	file  string
+	row   int
+}
+
+// Debugger is an evaluator.Hook that pauses the script and asks its front end,
+// the pause function, how to continue. Breakpoints may be changed and the script
+// interrupted from other goroutines while it runs.
+type Debugger struct {
+	// StopOnEntry pauses before the first statement of the script.
+	StopOnEntry bool
+
+	pause  func(Stop) Action
+	e      *evaluator.Evaluator
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	breakpoints map[string]map[int]bool //Lines of each file
+	statements  map[string]map[int]bool //Lines of each file a statement starts on
+	interrupted bool
+	quit        bool
+
+	action     Action
+	depth      int      //Of the frame the last action was given in
+	last       location //Of the last pause, not paused at again until execution moves on
+	evaluating bool     //Evaluating an expression for the front end, which never pauses
+}
+
+// New returns a debugger calling pause whenever execution pauses.
+func New(pause func(Stop) Action) *Debugger {
+	return &Debugger{
+		pause:       pause,
+		breakpoints: map[string]map[int]bool{},
+		statements:  map[string]map[int]bool{},
+	}
+}
+
+// Run evaluates the program loaded from path in ctx with e, pausing as the
+// breakpoints and the actions of the front end ask. finished is false when the
+// script was stopped before it ended.
+func (d *Debugger) Run(e *evaluator.Evaluator, program *ast.Program, path string, ctx *types.Context) (result types.Object, finished bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return &types.Error{Value: fmt.Sprintf("invalid path %s: %s", path, err)}, true
+	}
+
+	d.mu.Lock()
+	d.statements[abs] = statementLines(program)
+	d.mu.Unlock()
+
+	var stop context.Context
+	stop, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+
+	d.e = e
+	d.action = Continue
+	if d.StopOnEntry {
+		d.action = StepIn
+	}
+	e.SetContext(stop)
+	e.SetHook(d)
+	defer func() {
+		e.SetHook(nil)
+		e.SetContext(nil)
+	}()
+
+	result = e.EvalModule(program, path, ctx)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return result, !d.quit
+}
+
+// Stop ends the script at its next statement, it may be called while the script runs.
+func (d *Debugger) Stop() {
+	d.mu.Lock()
+	d.quit = true
+	cancel := d.cancel
+	d.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Interrupt pauses the script at its next statement, it may be called while the script runs.
+func (d *Debugger) Interrupt() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.interrupted = true
+}
+
+// SetBreakpoint pauses execution at line of file, or at the first statement after
+// it when no statement starts on the line. It returns the line of the breakpoint.
+func (d *Debugger) SetBreakpoint(file string, line int) (int, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return 0, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	lines, err := d.statementLines(file)
+	if err != nil {
+		return 0, err
+	}
+
+	actual := 0
+	for l := range lines {
+		if l >= line && (actual == 0 || l < actual) {
+			actual = l
+		}
+	}
+	if actual == 0 {
+		return 0, fmt.Errorf("no statement at or after line %d of %s", line, filepath.Base(file))
+	}
+
+	if d.breakpoints[file] == nil {
+		d.breakpoints[file] = map[int]bool{}
+	}
+	d.breakpoints[file][actual] = true
+	return actual, nil
+}
+
+// ClearBreakpoint removes the breakpoint at line of file and reports whether there was one.
+func (d *Debugger) ClearBreakpoint(file string, line int) bool {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.breakpoints[file][line] {
+		return false
+	}
+	delete(d.breakpoints[file], line)
+	return true
+}
+
+// ClearBreakpoints removes the breakpoints of file, or of every file when it is empty.
+func (d *Debugger) ClearBreakpoints(file string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if file == "" {
+		d.breakpoints = map[string]map[int]bool{}
+		return
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		delete(d.breakpoints, abs)
+	}
+}
+
+// Breakpoint is a line of a file execution pauses at.
+type Breakpoint struct {
+	File string
+	Line int
+}
+
+// Breakpoints returns the breakpoints ordered by file and line.
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var result []Breakpoint
+	for file, lines := range d.breakpoints {
+		for line := range lines {
+			result = append(result, Breakpoint{File: file, Line: line})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].File != result[j].File {
+			return result[i].File < result[j].File
+		}
+		return result[i].Line < result[j].Line
+	})
+	return result
+}
+
+// statementLines returns the lines statements of file start on, parsing it the first time.
+func (d *Debugger) statementLines(file string) (map[int]bool, error) {
+	if lines, ok := d.statements[file]; ok {
+		return lines, nil
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	source := string(content)
+	if strings.HasPrefix(source, "#!") {
+		//Keep the newline so that lines still match the file
+		source = source[strings.IndexByte(source+"\n", '\n'):]
+	}
+
+	p := parser.NewParser(lexer.NewTokenizer(source))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		return nil, fmt.Errorf("%s: %s", filepath.Base(file), p.Errors[0])
+	}
+
+	lines := statementLines(program)
+	d.statements[file] = lines
+	return lines, nil
+}
+
+func statementLines(program *ast.Program) map[int]bool {
+	lines := map[int]bool{}
+	add := func(statements []ast.Node) {
+		for _, s := range statements {
+			lines[s.Pos().Row] = true
+		}
+	}
+
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Program:
+			add(n.Statements)
+		case *ast.CodeBlock:
+			add(n.Statements)
+		}
+		return true
+	})
+	return lines
+}
+
+// Statement implements evaluator.Hook.
+func (d *Debugger) Statement(n ast.Node, stack []evaluator.Frame) {
+	if d.evaluating {
+		return
+	}
+
+	top := stack[len(stack)-1]
+	here := location{depth: len(stack), file: top.File, row: top.Pos.Row}
+	if here == d.last {
+		return
+	}
+	d.last = location{}
+
+	reason, ok := d.shouldPause(here)
+	if !ok {
+		return
+	}
+
+	d.last = here
+	action := d.pause(Stop{Reason: reason, Stack: append([]evaluator.Frame(nil), stack...)})
+	if action == Quit {
+		d.Stop()
+	}
+	d.action, d.depth = action, len(stack)
+}
+
+func (d *Debugger) shouldPause(here location) (Reason, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.quit {
+		return "", false
+	}
+
+	switch {
+	case d.interrupted:
+		d.interrupted = false
+		return ReasonPause, true
+	case d.breakpoints[here.file][here.row]:
+		return ReasonBreakpoint, true
+	case d.action == StepIn && d.StopOnEntry && d.depth == 0:
+		return ReasonEntry, true
+	case d.action == StepIn,
+		d.action == StepOver && here.depth <= d.depth,
+		d.action == StepOut && here.depth < d.depth:
+		return ReasonStep, true
+	}
+	return "", false
+}
+
+// Eval evaluates the expression in the scope of the frame, while execution is paused.
+func (d *Debugger) Eval(expr string, frame evaluator.Frame) (types.Object, error) {
+	p := parser.NewParser(lexer.NewTokenizer(expr))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		return nil, errors.New(p.Errors[0])
+	}
+	if d.e == nil || frame.Ctx == nil {
+		return nil, errors.New("the script is not running")
+	}
+
+	d.evaluating = true
+	defer func() { d.evaluating = false }()
+
+	result := d.e.Eval(program, frame.Ctx)
+	if err, ok := result.(*types.Error); ok {
+		return nil, errors.New(err.Value)
+	}
+	if result == nil {
+		result = types.NULL
+	}
+	return result, nil
+}
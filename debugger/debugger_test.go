@@ -0,0 +1,322 @@
+package debugger
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/types"
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mainScript = `import "lib.syn";
+func add(a, b) {
+  let sum = a + b;
+  return sum;
+}
+
+let x = add(1, 2);
+let y = lib.double(x);
+println(y);
+`
+
+const libScript = `export func double(n) {
+  return n * 2;
+}
+`
+
+// writeScripts writes the scripts to a temporary directory and returns it.
+func writeScripts(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"main.syn": mainScript, "lib.syn": libScript} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func parse(t *testing.T, source string) *ast.Program {
+	t.Helper()
+
+	p := parser.NewParser(lexer.NewTokenizer(source))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		t.Fatal(p.Errors)
+	}
+	return program
+}
+
+// run debugs the main script, answering each pause with the next action and
+// recording where it paused.
+func run(t *testing.T, d *Debugger, dir string, actions []Action, onPause func(Stop)) (pauses []string, output string) {
+	t.Helper()
+
+	d.pause = func(stop Stop) Action {
+		top := stop.Top()
+		pause := fmt.Sprintf("%s %s:%d", stop.Reason, filepath.Base(top.File), top.Pos.Row)
+		if top.Function != "" {
+			pause += " " + top.Function
+		}
+		pauses = append(pauses, pause)
+
+		if onPause != nil {
+			onPause(stop)
+		}
+		if len(actions) == 0 {
+			return Continue
+		}
+		action := actions[0]
+		actions = actions[1:]
+		return action
+	}
+
+	var out bytes.Buffer
+	e := evaluator.New()
+	e.SetIO(strings.NewReader(""), &out)
+	result, finished := d.Run(e, parse(t, mainScript), filepath.Join(dir, "main.syn"), types.NewContext(nil))
+	if !finished {
+		output = "stopped"
+	} else if isError(result) {
+		t.Fatalf("unexpected error %s", result)
+	}
+	return pauses, output + out.String()
+}
+
+func TestStepping(t *testing.T) {
+	dir := writeScripts(t)
+
+	tests := []struct {
+		name     string
+		actions  []Action
+		expected []string
+		output   string
+	}{
+		{
+			"step over and into calls",
+			[]Action{StepOver, StepOver, StepIn, StepOver, StepOut, StepIn, StepOver},
+			[]string{
+				"entry main.syn:1", "step main.syn:2", "step main.syn:7", "step main.syn:3 add", "step main.syn:4 add",
+				"step main.syn:8", "step lib.syn:2 double", "step main.syn:9",
+			},
+			"6\n",
+		},
+		{
+			"step into imports",
+			[]Action{StepIn, StepOut},
+			[]string{"entry main.syn:1", "step lib.syn:1", "step main.syn:2"},
+			"6\n",
+		},
+		{
+			"quit",
+			[]Action{StepOver, Quit},
+			[]string{"entry main.syn:1", "step main.syn:2"},
+			"stopped",
+		},
+	}
+
+	for _, tt := range tests {
+		d := New(nil)
+		d.StopOnEntry = true
+		pauses, output := run(t, d, dir, tt.actions, nil)
+		if !reflect.DeepEqual(pauses, tt.expected) {
+			t.Errorf("%s: expected pauses %q, got %q", tt.name, tt.expected, pauses)
+		}
+		if output != tt.output {
+			t.Errorf("%s: expected output %q, got %q", tt.name, tt.output, output)
+		}
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	dir := writeScripts(t)
+	main, lib := filepath.Join(dir, "main.syn"), filepath.Join(dir, "lib.syn")
+
+	d := New(nil)
+	tests := []struct {
+		file     string
+		line     int
+		expected string
+	}{
+		{main, 6, "7"},
+		{lib, 2, "2"},
+		{main, 3, "3"},
+		{main, 10, "error: no statement at or after line 10 of main.syn"},
+		{filepath.Join(dir, "missing.syn"), 1, "error: open " + filepath.Join(dir, "missing.syn") + ": no such file or directory"},
+	}
+	for _, tt := range tests {
+		line, err := d.SetBreakpoint(tt.file, tt.line)
+		actual := fmt.Sprint(line)
+		if err != nil {
+			actual = "error: " + err.Error()
+		}
+		if actual != tt.expected {
+			t.Errorf("%s:%d: expected %s, got %s", filepath.Base(tt.file), tt.line, tt.expected, actual)
+		}
+	}
+
+	expected := []Breakpoint{{lib, 2}, {main, 3}, {main, 7}}
+	if breakpoints := d.Breakpoints(); !reflect.DeepEqual(breakpoints, expected) {
+		t.Errorf("expected breakpoints %v, got %v", expected, breakpoints)
+	}
+
+	if !d.ClearBreakpoint(main, 3) || d.ClearBreakpoint(main, 3) {
+		t.Error("expected the breakpoint to be cleared once")
+	}
+
+	pauses, _ := run(t, d, dir, nil, nil)
+	expectedPauses := []string{"breakpoint main.syn:7", "breakpoint lib.syn:2 double"}
+	if !reflect.DeepEqual(pauses, expectedPauses) {
+		t.Errorf("expected pauses %q, got %q", expectedPauses, pauses)
+	}
+
+	d.ClearBreakpoints(lib)
+	if breakpoints := d.Breakpoints(); !reflect.DeepEqual(breakpoints, []Breakpoint{{main, 7}}) {
+		t.Errorf("expected the breakpoints of main.syn to be kept, got %v", breakpoints)
+	}
+	d.ClearBreakpoints("")
+	if breakpoints := d.Breakpoints(); len(breakpoints) != 0 {
+		t.Errorf("expected no breakpoints, got %v", breakpoints)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	dir := writeScripts(t)
+
+	d := New(nil)
+	d.Interrupt()
+	pauses, _ := run(t, d, dir, nil, nil)
+	if !reflect.DeepEqual(pauses, []string{"pause main.syn:1"}) {
+		t.Errorf("expected to pause at the first statement, got %q", pauses)
+	}
+}
+
+func TestEval(t *testing.T) {
+	dir := writeScripts(t)
+
+	d := New(nil)
+	if _, err := d.SetBreakpoint(filepath.Join(dir, "main.syn"), 4); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr     string
+		frame    int
+		expected string
+	}{
+		{"sum * 10", 0, "30"},
+		{`"a" + "b"`, 0, `"ab"`},
+		{"let z = 1;", 0, "null"},
+		{"sum", 1, "error: identifier not found: sum"},
+		{"add", 1, "func(a, b)"},
+		{"1 +", 0, "error: Missing prefix parser for EOF"},
+	}
+
+	var results []string
+	run(t, d, dir, nil, func(stop Stop) {
+		for _, tt := range tests {
+			value, err := d.Eval(tt.expr, stop.Stack[len(stop.Stack)-1-tt.frame])
+			if err != nil {
+				results = append(results, "error: "+err.Error())
+			} else {
+				results = append(results, types.Inspect(value))
+			}
+		}
+	})
+
+	for i, tt := range tests {
+		if i >= len(results) || results[i] != tt.expected {
+			t.Errorf("%q: expected %s, got %q", tt.expr, tt.expected, results)
+			break
+		}
+	}
+}
+
+func TestCLI(t *testing.T) {
+	dir := writeScripts(t)
+
+	commands := strings.Join([]string{
+		"b 3", "b lib.syn:2", "b 20", "b", "c",
+		"bt", "vars", "p a * b + 1", "p nope",
+		"frame 1", "vars", "list", "frame 5", "bogus",
+		"n", "", "clear lib.syn:2", "clear 9", "out",
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	in := bufio.NewReader(strings.NewReader(commands))
+	e := evaluator.New()
+	e.SetIO(in, &out)
+	result, finished := NewCLI(in, &out).Run(e, parse(t, mainScript), filepath.Join(dir, "main.syn"), types.NewContext(nil))
+	if isError(result) || !finished {
+		t.Fatalf("expected the script to finish, got %v", result)
+	}
+
+	expected := `entry at main.syn:1
+=>   1	import "lib.syn";
+(debug) breakpoint at main.syn:3
+(debug) breakpoint at lib.syn:2
+(debug) error: no statement at or after line 20 of main.syn
+(debug) lib.syn:2
+main.syn:3
+(debug) breakpoint at main.syn:3 in add
+=>   3	  let sum = a + b;
+(debug) => #0 main.syn:3 in add
+   #1 main.syn:7
+(debug) a = 1
+b = 2
+(debug) 3
+(debug) error: identifier not found: nope
+(debug) #1 main.syn:7
+(debug) add = func(a, b)
+lib = module lib
+(debug)      4	  return sum;
+     5	}
+     6
+=>   7	let x = add(1, 2);
+     8	let y = lib.double(x);
+     9	println(y);
+    10
+(debug) error: frame must be between 0 and 1
+(debug) unknown command bogus, try help
+(debug) step at main.syn:4 in add
+=>   4	  return sum;
+(debug) step at main.syn:8
+=>   8	let y = lib.double(x);
+(debug) cleared lib.syn:2
+(debug) no breakpoint at main.syn:9
+(debug) 6
+`
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestCLIEndOfInput(t *testing.T) {
+	dir := writeScripts(t)
+
+	var out bytes.Buffer
+	in := bufio.NewReader(strings.NewReader("help\n"))
+	e := evaluator.New()
+	e.SetIO(in, &out)
+	_, finished := NewCLI(in, &out).Run(e, parse(t, mainScript), filepath.Join(dir, "main.syn"), types.NewContext(nil))
+	if finished {
+		t.Error("expected the script to be stopped when the commands end")
+	}
+	if !strings.Contains(out.String(), "  break, b [[file:]line]      set a breakpoint, or list them\n") {
+		t.Errorf("expected help in the output, got:\n%s", out.String())
+	}
+}
+
+func isError(obj types.Object) bool {
+	_, ok := obj.(*types.Error)
+	return ok
+}
@@ -0,0 +1,196 @@
+package evaluator
+
+import (
+	"Simply/types"
+	"fmt"
+	"strings"
+)
+
+func internal_assert(in types.Interpreter, args ...types.Object) types.Object {
+	if isConditionTrue(args[0]) {
+		return types.NULL
+	}
+	return newError("%s", failure("assertion failed", args[1:]))
+}
+
+func internal_assert_eq(in types.Interpreter, args ...types.Object) types.Object {
+	actual, expected := args[0], args[1]
+	if valuesEqual(actual, expected) {
+		return types.NULL
+	}
+	return newError("%s\n%s", failure("assert_eq failed", args[2:]), diff(expected, actual))
+}
+
+func internal_assert_error(in types.Interpreter, args ...types.Object) types.Object {
+	result := in.Call(args[0])
+	err, ok := result.(*types.Error)
+	if !ok {
+		return newError("assert_error failed: expected an error, got %s", types.Inspect(result))
+	}
+
+	if len(args) > 1 {
+		if expected := args[1].(*types.String).Value; !strings.Contains(err.Value, expected) {
+			return newError("assert_error failed: expected an error containing %q, got %q", expected, err.Value)
+		}
+	}
+	return &types.String{Value: err.Value}
+}
+
+// failure appends the optional message given to an assertion to what failed.
+func failure(what string, message []types.Object) string {
+	if len(message) == 0 {
+		return what
+	}
+	return what + ": " + message[0].(*types.String).Value
+}
+
+// valuesEqual compares numbers by value, arrays, maps and struct instances by
+// content, and everything else by identity.
+func valuesEqual(a, b types.Object) bool {
+	switch a := a.(type) {
+	case *types.Int:
+		if b, ok := b.(*types.Int); ok {
+			return a.Value == b.Value
+		}
+		return isNumber(b) && toFloat(a) == toFloat(b)
+	case *types.Float:
+		return isNumber(b) && a.Value == toFloat(b)
+	case *types.String:
+		b, ok := b.(*types.String)
+		return ok && a.Value == b.Value
+	case *types.Array:
+		b, ok := b.(*types.Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !valuesEqual(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *types.Map:
+		b, ok := b.(*types.Map)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for _, p := range a.Pairs() {
+			v, ok := b.Get(p.Key)
+			if !ok || !valuesEqual(p.Value, v) {
+				return false
+			}
+		}
+		return true
+	case *types.Instance:
+		b, ok := b.(*types.Instance)
+		if !ok || a.Type != b.Type {
+			return false
+		}
+		for _, f := range a.Type.Fields {
+			if !valuesEqual(a.Fields[f], b.Fields[f]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// diff shows how the actual value differs from the expected one, line by line
+// for values that do not fit on one.
+func diff(expected, actual types.Object) string {
+	e, a := layout(expected), layout(actual)
+	if s, ok := expected.(*types.String); ok && strings.Contains(s.Value, "\n") {
+		e = strings.Split(s.Value, "\n")
+	}
+	if s, ok := actual.(*types.String); ok && strings.Contains(s.Value, "\n") {
+		a = strings.Split(s.Value, "\n")
+	}
+
+	if len(e) == 1 && len(a) == 1 {
+		return fmt.Sprintf("expected: %s\n  actual: %s", e[0], a[0])
+	}
+
+	lines := append([]string{"--- expected", "+++ actual"}, diffLines(e, a)...)
+	return strings.Join(lines, "\n")
+}
+
+// layout formats a value with one element of arrays, maps and struct instances per line.
+func layout(o types.Object) []string {
+	var lines []string
+	switch o := o.(type) {
+	case *types.Array:
+		if len(o.Elements) == 0 {
+			return []string{"[]"}
+		}
+		lines = append(lines, "[")
+		for _, el := range o.Elements {
+			lines = append(lines, entry("", el)...)
+		}
+		return append(lines, "]")
+	case *types.Map:
+		if o.Len() == 0 {
+			return []string{"{}"}
+		}
+		lines = append(lines, "{")
+		for _, p := range o.Pairs() {
+			lines = append(lines, entry(types.Inspect(p.Key)+": ", p.Value)...)
+		}
+		return append(lines, "}")
+	case *types.Instance:
+		lines = append(lines, o.Type.Name+"{")
+		for _, f := range o.Type.Fields {
+			lines = append(lines, entry(f+": ", o.Fields[f])...)
+		}
+		return append(lines, "}")
+	}
+	return []string{types.Inspect(o)}
+}
+
+// entry lays out an element of a collection, indented and followed by a comma.
+func entry(prefix string, value types.Object) []string {
+	lines := layout(value)
+	for i := range lines {
+		lines[i] = "  " + lines[i]
+	}
+	lines[0] = "  " + prefix + strings.TrimPrefix(lines[0], "  ")
+	lines[len(lines)-1] += ","
+	return lines
+}
+
+// diffLines marks the lines only in a with -, those only in b with + and the
+// common ones, found as their longest common subsequence, with a space.
+func diffLines(a, b []string) []string {
+	//common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var result []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			result = append(result, "  "+a[i])
+			i++
+			j++
+		case j == len(b) || i < len(a) && common[i+1][j] >= common[i][j+1]:
+			result = append(result, "- "+a[i])
+			i++
+		default:
+			result = append(result, "+ "+b[j])
+			j++
+		}
+	}
+	return result
+}
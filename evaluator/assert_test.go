@@ -0,0 +1,40 @@
+package evaluator
+
+import "testing"
+
+func TestAsserts(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`assert(1 < 2);`, "null"},
+		{`assert(1 > 2);`, "assertion failed"},
+		{`assert(false, "no value");`, "assertion failed: no value"},
+		{`assert_eq(1 + 1, 2);`, "null"},
+		{`assert_eq(2, 2.0);`, "null"},
+		{`assert_eq([1, {"a": [2]}], [1, {"a": [2]}]);`, "null"},
+		{`assert_eq({"a": 1, "b": 2}, {"b": 2, "a": 1});`, "null"},
+		{`struct P { x }; assert_eq(P(1), P(1));`, "null"},
+		{`let f = func() { 1 }; assert_eq(f, f);`, "null"},
+		{`assert_eq(func() { 1 }, func() { 1 });`, "assert_eq failed\nexpected: func()\n  actual: func()"},
+		{`assert_eq(1 + 2, 4, "sum");`, "assert_eq failed: sum\nexpected: 4\n  actual: 3"},
+		{`assert_eq("a", 1);`, "assert_eq failed\nexpected: 1\n  actual: \"a\""},
+		{`assert_eq([], [1]);`, "assert_eq failed\n--- expected\n+++ actual\n- [\n-   1,\n- ]\n+ []"},
+		{`assert_eq([1, [2, 3], 4], [1, [2, 5], 4]);`,
+			"assert_eq failed\n--- expected\n+++ actual\n  [\n    1,\n    [\n      2,\n-     5,\n+     3,\n    ],\n    4,\n  ]"},
+		{`struct P { x, y }; assert_eq(P(1, {"k": 2}), P(1, {"k": 3}));`,
+			"assert_eq failed\n--- expected\n+++ actual\n  P{\n    x: 1,\n    y: {\n-     \"k\": 3,\n+     \"k\": 2,\n    },\n  }"},
+		{"assert_eq(\"a\nb\nc\", \"a\nc\");", "assert_eq failed\n--- expected\n+++ actual\n  a\n+ b\n  c"},
+		{`assert_error(func() { 1 / 0 });`, "division by zero"},
+		{`assert_error(func() { 1 / 0 }, "division");`, "division by zero"},
+		{`assert_error(func() { 1 / 0 }, "nope");`, `assert_error failed: expected an error containing "nope", got "division by zero"`},
+		{`assert_error(func() { "ok" });`, `assert_error failed: expected an error, got "ok"`},
+		{`assert_eq(1);`, "wrong number of arguments to `assert_eq`. got=1, want=2 to 3"},
+	}
+	for _, tt := range tests {
+		result := testEvaluator(t, tt.input)
+		if result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, result)
+		}
+	}
+}
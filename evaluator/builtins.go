@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"Simply/ast"
+	"Simply/types"
+	"bufio"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Builtins holds the names resolved when an identifier is not bound in the script.
+// Every Evaluator has its own registry so hosts can extend or restrict it.
+// A registry may be changed while scripts that use it are running.
+type Builtins struct {
+	mu     sync.RWMutex
+	values map[string]types.Object
+}
+
+func NewBuiltins() *Builtins {
+	return &Builtins{values: map[string]types.Object{}}
+}
+
+func (b *Builtins) Register(name string, value types.Object) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[name] = value
+}
+
+func (b *Builtins) RegisterFunc(name string, fn types.InternalCallFunc) {
+	b.Register(name, &types.InternalCall{Fn: fn})
+}
+
+// Define registers fn under the name in spec, its arguments are checked against
+// the signature (see types.ParseSignature) before fn is called.
+func (b *Builtins) Define(spec, doc string, fn types.InternalCallFunc) error {
+	sig, err := types.ParseSignature(spec)
+	if err != nil {
+		return err
+	}
+	sig.Doc = doc
+
+	b.Register(sig.Name, &types.InternalCall{Fn: fn, Signature: sig})
+	return nil
+}
+
+func (b *Builtins) Remove(names ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, name := range names {
+		delete(b.values, name)
+	}
+}
+
+// Restrict removes every builtin except the given names.
+func (b *Builtins) Restrict(names ...string) {
+	keep := map[string]bool{}
+	for _, name := range names {
+		keep[name] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name := range b.values {
+		if !keep[name] {
+			delete(b.values, name)
+		}
+	}
+}
+
+func (b *Builtins) Lookup(name string) (types.Object, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	v, ok := b.values[name]
+	return v, ok
+}
+
+// Names returns the registered names in sorted order.
+func (b *Builtins) Names() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	names := make([]string, 0, len(b.values))
+	for name := range b.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultBuiltins returns a registry with the standard builtins.
+func DefaultBuiltins() *Builtins {
+	b := NewBuiltins()
+	b.registerDefaults()
+	return b
+}
+
+func (b *Builtins) registerDefaults() {
+	for _, d := range internalCalls {
+		sig := mustParseSignature(d.spec, d.doc)
+		b.Register(sig.Name, &types.InternalCall{Fn: d.fn, Signature: sig})
+	}
+
+	//args holds the command line arguments of a script, hosts replace it
+	b.Register("args", &types.Array{})
+
+	for name, module := range newBuiltinModules() {
+		b.Register(name, module)
+	}
+}
+
+// callSite implements types.Interpreter for a single builtin call.
+type callSite struct {
+	e   *Evaluator
+	pos ast.Position
+}
+
+func (c *callSite) Call(fn types.Object, args ...types.Object) types.Object {
+	return c.e.executeFunction(fn, args, c.pos)
+}
+
+func (c *callSite) Position() ast.Position { return c.pos }
+
+func (c *callSite) In() *bufio.Reader { return c.e.in }
+
+func (c *callSite) Out() io.Writer { return c.e.out }
@@ -0,0 +1,126 @@
+package evaluator
+
+import (
+	"Simply/ast"
+	"Simply/types"
+)
+
+func (e *Evaluator) evalArrayLiteral(node *ast.ArrayLiteral, ctx *types.Context) types.Object {
+	elements := make([]types.Object, 0, len(node.Elements))
+	for _, el := range node.Elements {
+		evaluated := e.Eval(el, ctx)
+		if isError(evaluated) {
+			return evaluated
+		}
+		elements = append(elements, evaluated)
+	}
+
+	return &types.Array{Elements: elements}
+}
+
+func (e *Evaluator) evalMapLiteral(node *ast.MapLiteral, ctx *types.Context) types.Object {
+	m := types.NewMap()
+	for _, p := range node.Pairs {
+		key := e.Eval(p.Key, ctx)
+		if isError(key) {
+			return key
+		}
+		if !types.IsHashable(key) {
+			return newError("unusable as map key: %s", types.TypeName(key))
+		}
+
+		value := e.Eval(p.Value, ctx)
+		if isError(value) {
+			return value
+		}
+
+		m.Set(key, value)
+	}
+
+	return m
+}
+
+func (e *Evaluator) evalIndexExpression(node *ast.IndexExpression, ctx *types.Context) types.Object {
+	left := e.Eval(node.Left, ctx)
+	if isError(left) {
+		return left
+	}
+
+	index := e.Eval(node.Index, ctx)
+	if isError(index) {
+		return index
+	}
+
+	switch left := left.(type) {
+	case *types.Array:
+		i, err := arrayIndex(index, len(left.Elements))
+		if err != nil {
+			return err
+		}
+		return left.Elements[i]
+	case *types.String:
+		i, err := arrayIndex(index, len(left.Value))
+		if err != nil {
+			return err
+		}
+		return &types.String{Value: string(left.Value[i])}
+	case *types.Map:
+		if !types.IsHashable(index) {
+			return newError("unusable as map key: %s", types.TypeName(index))
+		}
+		if val, ok := left.Get(index); ok {
+			return val
+		}
+		return types.NULL
+	default:
+		return newError("index operator not supported: %s", types.TypeName(left))
+	}
+}
+
+func (e *Evaluator) evalIndexAssignment(node *ast.IndexExpression, val types.Object, ctx *types.Context) types.Object {
+	left := e.Eval(node.Left, ctx)
+	if isError(left) {
+		return left
+	}
+
+	index := e.Eval(node.Index, ctx)
+	if isError(index) {
+		return index
+	}
+
+	switch left := left.(type) {
+	case *types.Array:
+		i, err := arrayIndex(index, len(left.Elements))
+		if err != nil {
+			return err
+		}
+		left.Elements[i] = val
+	case *types.Map:
+		if !types.IsHashable(index) {
+			return newError("unusable as map key: %s", types.TypeName(index))
+		}
+		left.Set(index, val)
+	default:
+		return newError("index assignment not supported: %s", types.TypeName(left))
+	}
+
+	return val
+}
+
+// arrayIndex validates the index, negative indexes count from the end.
+func arrayIndex(index types.Object, length int) (int, types.Object) {
+	i, ok := index.(*types.Int)
+	if !ok {
+		return 0, newError("index must be int, got %s", types.TypeName(index))
+	}
+
+	idx := int(i.Value)
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return 0, newError("index out of range: %d with length %d", i.Value, length)
+	}
+
+	return idx, nil
+}
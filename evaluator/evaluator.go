@@ -3,50 +3,156 @@ package evaluator
 import (
 	"Simply/ast"
 	"Simply/types"
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"os"
 )
 
+// DefaultMaxCallDepth is the MaxCallDepth of new evaluators, well below the
+// nesting that would overflow the Go stack.
+const DefaultMaxCallDepth = 10000
+
+type Evaluator struct {
+	// SearchPath lists directories searched for imports not found next to the importing file.
+	SearchPath []string
+	Builtins   *Builtins
+	// MaxCallDepth limits the nesting of function calls, deeper calls fail.
+	MaxCallDepth int
+
+	modules  *moduleLoader
+	in       *bufio.Reader
+	out      io.Writer
+	cancel   context.Context
+	hook     Hook
+	callHook CallHook //The hook when it observes calls too
+	coverage CoverageHook
+	frames   []Frame //Active calls, innermost last
+	depth    int     //Nesting of the function calls being evaluated
+}
+
+func New() *Evaluator {
+	e := &Evaluator{modules: newModuleLoader(), Builtins: DefaultBuiltins(), MaxCallDepth: DefaultMaxCallDepth}
+	e.SetIO(os.Stdin, os.Stdout)
+	return e
+}
+
+// SetIO sets the streams used by the input and output builtins.
+func (e *Evaluator) SetIO(in io.Reader, out io.Writer) {
+	if r, ok := in.(*bufio.Reader); ok {
+		e.in = r
+	} else {
+		e.in = bufio.NewReader(in)
+	}
+	e.out = out
+}
+
+// SetContext makes evaluation stop with an error once c is done.
+func (e *Evaluator) SetContext(c context.Context) {
+	e.cancel = c
+}
+
+// Call invokes a function, builtin or struct constructor with the arguments.
+func (e *Evaluator) Call(fn types.Object, args ...types.Object) types.Object {
+	return e.executeFunction(fn, args, ast.Position{})
+}
+
+// Eval evaluates the node with a new Evaluator.
 func Eval(n ast.Node, ctx *types.Context) types.Object {
+	return New().Eval(n, ctx)
+}
+
+func (e *Evaluator) Eval(n ast.Node, ctx *types.Context) types.Object {
+	if e.cancel != nil {
+		select {
+		case <-e.cancel.Done():
+			return &types.Error{Value: "evaluation stopped: " + e.cancel.Err().Error(), Position: n.Pos()}
+		default:
+		}
+	}
+
+	result := e.evalNode(n, ctx)
+
+	//The innermost node that failed is where the error happened
+	if err, ok := result.(*types.Error); ok && err.Position.Row == 0 {
+		err.Position = n.Pos()
+	}
+
+	return result
+}
 
+func (e *Evaluator) evalNode(n ast.Node, ctx *types.Context) types.Object {
 	switch node := n.(type) {
 	case *ast.Program:
-		return evalProgram(node, ctx)
+		return e.evalProgram(node, ctx)
 	case *ast.DeclarativeStatement:
-		return evalDeclarativeStatement(node, ctx)
+		return e.evalDeclarativeStatement(node, ctx)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, ctx)
+		return e.Eval(node.Expression, ctx)
 	case *ast.PrefixExpression:
-		return evalPrefixExpression(node, ctx)
+		return e.evalPrefixExpression(node, ctx)
 	case *ast.CallExpression:
-		return evalCallExpression(node, ctx)
+		return e.evalCallExpression(node, ctx)
 	case *ast.InfixExpression:
-		return evalInfixExpression(node, ctx)
+		return e.evalInfixExpression(node, ctx)
 	case *ast.ConditionalExpression:
-		return evalConditionalExpression(node, ctx)
+		return e.evalConditionalExpression(node, ctx)
 	case *ast.Identifier:
-		return evalIdentifier(node, ctx)
+		return e.evalIdentifier(node, ctx)
 	case *ast.IntLiteral:
 		return &types.Int{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &types.Float{Value: node.Value}
+	case *ast.ArrayLiteral:
+		return e.evalArrayLiteral(node, ctx)
+	case *ast.MapLiteral:
+		return e.evalMapLiteral(node, ctx)
+	case *ast.IndexExpression:
+		return e.evalIndexExpression(node, ctx)
 	case *ast.BoolLiteral:
 		return getBoolType(node.Value)
 	case *ast.StringLiteral:
 		return &types.String{Value: node.Value}
 	case *ast.FunctionLiteral:
-		return &types.Function{Parameters: node.Parameters, Body: node.Body, Ctx: ctx}
+		return e.newFunction("", node, ctx)
 	case *ast.ReturnStatement:
-		return evalReturnStatement(node, ctx)
+		return e.evalReturnStatement(node, ctx)
 	case *ast.CodeBlock:
-		return evalCodeBlock(node, ctx)
+		return e.evalCodeBlock(node, ctx)
+	case *ast.FunctionStatement:
+		ctx.Set(node.Name.Value, e.newFunction(node.Name.Value, node.Function, ctx))
+		return types.NULL
+	case *ast.StructStatement:
+		return e.evalStructStatement(node, ctx)
+	case *ast.TraitStatement:
+		return e.evalTraitStatement(node, ctx)
+	case *ast.ImportStatement:
+		return e.evalImportStatement(node, ctx)
+	case *ast.ExportStatement:
+		return e.evalExportStatement(node, ctx)
+	case *ast.MemberExpression:
+		return e.evalMemberExpression(node, ctx)
+	case *ast.AssignmentExpression:
+		return e.evalAssignmentExpression(node, ctx)
 	}
 
 	return newError("Failed execute node %t", n)
 }
 
-func evalProgram(p *ast.Program, ctx *types.Context) types.Object {
+func (e *Evaluator) evalProgram(p *ast.Program, ctx *types.Context) types.Object {
 	var result types.Object
 
+	file := ""
+	if current := e.modules.current(); current != nil {
+		file = current.Path
+	}
+	e.pushFrame("", file)
+	defer e.popFrame()
+
 	for _, v := range p.Statements {
-		result = Eval(v, ctx)
+		e.statement(v, ctx)
+		result = e.Eval(v, ctx)
 
 		switch result := result.(type) {
 		case *types.ReturnValue:
@@ -59,32 +165,36 @@ func evalProgram(p *ast.Program, ctx *types.Context) types.Object {
 	return result
 }
 
-func evalDeclarativeStatement(d *ast.DeclarativeStatement, ctx *types.Context) types.Object {
-	result := Eval(d.Value, ctx)
+func (e *Evaluator) evalDeclarativeStatement(d *ast.DeclarativeStatement, ctx *types.Context) types.Object {
+	result := e.Eval(d.Value, ctx)
 
 	if isError(result) {
 		return result
 	}
 
+	if fn, ok := result.(*types.Function); ok && isType[*ast.FunctionLiteral](d.Value) {
+		fn.Name = d.Name.Value
+	}
+
 	ctx.Set(d.Name.Value, result)
 
-	return nil //Good job? Here is nothing :P
+	return types.NULL
 }
 
-func evalIdentifier(node *ast.Identifier, ctx *types.Context) types.Object {
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, ctx *types.Context) types.Object {
 	if val, ok := ctx.Get(node.Value); ok {
 		return val
 	}
 
-	if builtin, ok := internalCalls[node.Value]; ok {
+	if builtin, ok := e.Builtins.Lookup(node.Value); ok {
 		return builtin
 	}
 
 	return newError("identifier not found: " + node.Value)
 }
 
-func evalPrefixExpression(node *ast.PrefixExpression, ctx *types.Context) types.Object {
-	exp := Eval(node.Expression, ctx)
+func (e *Evaluator) evalPrefixExpression(node *ast.PrefixExpression, ctx *types.Context) types.Object {
+	exp := e.Eval(node.Expression, ctx)
 	if isError(exp) {
 		return exp
 	}
@@ -97,6 +207,9 @@ func evalPrefixExpression(node *ast.PrefixExpression, ctx *types.Context) types.
 
 		return getBoolType(!exp.(*types.Bool).Value)
 	case "-":
+		if f, ok := exp.(*types.Float); ok {
+			return &types.Float{Value: -f.Value}
+		}
 		if !isType[*types.Int](exp) {
 			return newError("unknown operator: -%s", exp.String())
 		}
@@ -106,37 +219,82 @@ func evalPrefixExpression(node *ast.PrefixExpression, ctx *types.Context) types.
 	}
 }
 
-func evalCallExpression(node *ast.CallExpression, ctx *types.Context) types.Object {
-	f := Eval(node.Function, ctx)
+func (e *Evaluator) evalCallExpression(node *ast.CallExpression, ctx *types.Context) types.Object {
+	f := e.Eval(node.Function, ctx)
 	if isError(f) {
 		return f
 	}
 
 	var args []types.Object
-	for _, e := range node.Arguments {
-		evaluated := Eval(e, ctx)
+	for _, arg := range node.Arguments {
+		evaluated := e.Eval(arg, ctx)
 		if isError(evaluated) {
 			return evaluated
 		}
 		args = append(args, evaluated)
 	}
 
-	return executeFunction(f, args)
+	return e.executeFunction(f, args, node.Pos())
 }
 
-func executeFunction(fn types.Object, args []types.Object) types.Object {
+// executeFunction calls fn, pos is the position of the call used by builtins and errors.
+func (e *Evaluator) executeFunction(fn types.Object, args []types.Object, pos ast.Position) types.Object {
 	switch fn := fn.(type) {
 	case *types.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+		}
+		if e.depth >= e.MaxCallDepth {
+			return newError("maximum call depth exceeded")
+		}
+		e.depth++
+		defer func() { e.depth-- }()
+
 		newCtx := createFuncCtx(fn, args)
-		evaluated := Eval(fn.Body, newCtx)
-		return unwrapReturnValue(evaluated)
+		e.pushFrame(functionName(fn), fn.File)
+		defer e.popFrame()
+		if e.callHook != nil {
+			e.callHook.Call(e.frames, args)
+		}
+		evaluated := unwrapReturnValue(e.Eval(fn.Body, newCtx))
+		if evaluated == nil {
+			evaluated = types.NULL
+		}
+		if e.callHook != nil {
+			e.callHook.Return(e.frames, evaluated)
+		}
+		return evaluated
 	case *types.InternalCall:
-		return fn.Fn(args...)
+		if fn.Signature != nil {
+			if err := checkArguments(fn.Signature, args); err != nil {
+				return err
+			}
+		}
+		if result := fn.Fn(&callSite{e: e, pos: pos}, args...); result != nil {
+			return result
+		}
+		return types.NULL
+	case *types.StructType:
+		return e.newInstance(fn, args, pos)
+	case *types.TraitMethod:
+		return e.callTraitMethod(fn, args, pos)
 	default:
 		return newError("not a function: %s", fn.String())
 	}
 }
 
+// functionName names anonymous functions after the line they start on.
+func functionName(fn *types.Function) string {
+	if fn.Name == "" {
+		return fmt.Sprintf("func@%d", fn.Body.Pos().Row)
+	}
+	return fn.Name
+}
+
+func (e *Evaluator) newFunction(name string, fl *ast.FunctionLiteral, ctx *types.Context) *types.Function {
+	return &types.Function{Name: name, File: e.file(), Parameters: fl.Parameters, Body: fl.Body, Ctx: ctx}
+}
+
 func unwrapReturnValue(obj types.Object) types.Object {
 	if returnValue, ok := obj.(*types.ReturnValue); ok {
 		return returnValue.Value
@@ -152,14 +310,14 @@ func createFuncCtx(fn *types.Function, args []types.Object) *types.Context {
 	return env
 }
 
-func evalInfixExpression(node *ast.InfixExpression, ctx *types.Context) types.Object {
-	left := Eval(node.Left, ctx)
+func (e *Evaluator) evalInfixExpression(node *ast.InfixExpression, ctx *types.Context) types.Object {
+	left := e.Eval(node.Left, ctx)
 
 	if isError(left) {
 		return left
 	}
 
-	right := Eval(node.Right, ctx)
+	right := e.Eval(node.Right, ctx)
 
 	if isError(right) {
 		return right
@@ -169,8 +327,20 @@ func evalInfixExpression(node *ast.InfixExpression, ctx *types.Context) types.Ob
 }
 
 func evalInfixOperation(op string, left, right types.Object) types.Object {
-	if isTypeEqual[*types.Int](left, right) {
+	switch {
+	case isTypeEqual[*types.Int](left, right):
 		return evalIntInfixExpression(op, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(op, toFloat(left), toFloat(right))
+	case isTypeEqual[*types.String](left, right):
+		return evalStringInfixExpression(op, left, right)
+	case isTypeEqual[*types.Bool](left, right) || isTypeEqual[*types.Null](left, right):
+		switch op {
+		case "==":
+			return getBoolType(left == right)
+		case "!=":
+			return getBoolType(left != right)
+		}
 	}
 
 	return newError("Unknown inflix operation %s %s %s", left.String(), op, right.String())
@@ -188,7 +358,10 @@ func evalIntInfixExpression(op string, left, right types.Object) types.Object {
 	case "*":
 		return &types.Int{Value: leftValue * rightValue}
 	case "/":
-		return &types.Int{Value: leftValue + rightValue}
+		if rightValue == 0 {
+			return newError("division by zero")
+		}
+		return &types.Int{Value: leftValue / rightValue}
 	case "<":
 		return getBoolType(leftValue < rightValue)
 	case ">":
@@ -202,6 +375,59 @@ func evalIntInfixExpression(op string, left, right types.Object) types.Object {
 	}
 }
 
+func evalFloatInfixExpression(op string, leftValue, rightValue float64) types.Object {
+	switch op {
+	case "+":
+		return &types.Float{Value: leftValue + rightValue}
+	case "-":
+		return &types.Float{Value: leftValue - rightValue}
+	case "*":
+		return &types.Float{Value: leftValue * rightValue}
+	case "/":
+		return &types.Float{Value: leftValue / rightValue}
+	case "<":
+		return getBoolType(leftValue < rightValue)
+	case ">":
+		return getBoolType(leftValue > rightValue)
+	case "==":
+		return getBoolType(leftValue == rightValue)
+	case "!=":
+		return getBoolType(leftValue != rightValue)
+	default:
+		return newError("operator %s not supported for float", op)
+	}
+}
+
+func evalStringInfixExpression(op string, left, right types.Object) types.Object {
+	leftValue := left.(*types.String).Value
+	rightValue := right.(*types.String).Value
+
+	switch op {
+	case "+":
+		return &types.String{Value: leftValue + rightValue}
+	case "==":
+		return getBoolType(leftValue == rightValue)
+	case "!=":
+		return getBoolType(leftValue != rightValue)
+	default:
+		return newError("operator %s not supported for string", op)
+	}
+}
+
+func isNumber(o types.Object) bool {
+	return isType[*types.Int](o) || isType[*types.Float](o)
+}
+
+func toFloat(o types.Object) float64 {
+	switch o := o.(type) {
+	case *types.Int:
+		return float64(o.Value)
+	case *types.Float:
+		return o.Value
+	}
+	return 0
+}
+
 func getBoolType(b bool) *types.Bool {
 	if b {
 		return types.TRUE
@@ -210,17 +436,22 @@ func getBoolType(b bool) *types.Bool {
 	}
 }
 
-func evalConditionalExpression(node *ast.ConditionalExpression, ctx *types.Context) types.Object {
-	condition := Eval(node.Condition, ctx)
+func (e *Evaluator) evalConditionalExpression(node *ast.ConditionalExpression, ctx *types.Context) types.Object {
+	condition := e.Eval(node.Condition, ctx)
 
 	if isError(condition) {
 		return condition
 	}
 
-	if isConditionTrue(condition) {
-		return Eval(node.True, ctx)
+	taken := isConditionTrue(condition)
+	if e.coverage != nil {
+		e.coverage.Branch(node, taken, e.frames)
+	}
+
+	if taken {
+		return e.Eval(node.True, ctx)
 	} else if node.False != nil {
-		return Eval(node.False, ctx)
+		return e.Eval(node.False, ctx)
 	} else {
 		return types.NULL
 	}
@@ -239,22 +470,21 @@ func isConditionTrue(o types.Object) bool {
 	}
 }
 
-func evalReturnStatement(node *ast.ReturnStatement, ctx *types.Context) types.Object {
-	val := Eval(node.Value, ctx)
+func (e *Evaluator) evalReturnStatement(node *ast.ReturnStatement, ctx *types.Context) types.Object {
+	val := e.Eval(node.Value, ctx)
 	if isError(val) {
 		return val
 	}
 	return &types.ReturnValue{Value: val}
 }
 
-func evalCodeBlock(block *ast.CodeBlock, env *types.Context) types.Object {
-	var result types.Object
+func (e *Evaluator) evalCodeBlock(block *ast.CodeBlock, env *types.Context) types.Object {
+	var result types.Object = types.NULL
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
-		if result != nil {
-			if isType[*types.ReturnValue](result) || isType[*types.Error](result) {
-				return result
-			}
+		e.statement(statement, env)
+		result = e.Eval(statement, env)
+		if isType[*types.ReturnValue](result) || isType[*types.Error](result) {
+			return result
 		}
 	}
 	return result
@@ -1,37 +1,45 @@
 package evaluator
 
 import (
+	"Simply/ast"
 	"Simply/lexer"
 	"Simply/parser"
 	"Simply/types"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestOfEverything(t *testing.T) {
 	tests := []struct {
-		input         string
-		expectedValue interface{}
-	}{
-		{"let x = if (3 > 4) { 3; } else {4}; x;", 4},
-		{"let x = if (3 > 4) { 3; }; x;", nil},
-		{"let x = if (3 > 2) { 3; }; x;", 3},
-		{"let x = (5 + 5) * 3; x;", 30},
-		{"let x = 5 + 5 * 3; x;", 20},
-		{"let x = true; x;", true},
-		{"let x = !true; x;", false},
-		{"let x = -5; x;", -5},
-		{"let x = func(x,y){ return 3; x+y;}; x(5,5);", 3},
-		{"let x = func(x,y){ x+y;}; x(5,5);", 10},
-		{"let x = 5 == 6; x;", false},
-		{"let x = 5 + 5; x;", 10},
-		{"let x = 5; x;", 5},
+		input    string
+		expected string
+	}{
+		{"let x = if (3 > 4) { 3; } else {4}; x;", "4"},
+		{"let x = if (3 > 4) { 3; }; x;", "null"},
+		{"let x = if (3 > 2) { 3; }; x;", "3"},
+		{"let x = (5 + 5) * 3; x;", "30"},
+		{"let x = 5 + 5 * 3; x;", "20"},
+		{"let x = true; x;", "true"},
+		{"let x = !true; x;", "false"},
+		{"let x = -5; x;", "-5"},
+		{"let x = func(x,y){ return 3; x+y;}; x(5,5);", "3"},
+		{"let x = func(x,y){ x+y;}; x(5,5);", "10"},
+		{"let x = 5 == 6; x;", "false"},
+		{"let x = 5 + 5; x;", "10"},
+		{"let x = 5; x;", "5"},
+		{"func f() { let x = 1 }; [f()];", "[null]"},
+		{"func f() { let x = 1 }; f() + 1;", "Unknown inflix operation null + 1"},
+		{"func f() { }; let y = f(); [y, if (true) { }];", "[null, null]"},
 	}
 	for _, tt := range tests {
 		result := testEvaluator(t, tt.input)
-
-		//TODO maybe impement testing that works
-		if result == tt.expectedValue {
-			t.Fatal("No match :()")
+		if result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %s, got %v", tt.input, tt.expected, result)
 		}
 	}
 }
@@ -56,3 +64,379 @@ func checkErrors(t *testing.T, p *parser.Parser) {
 	}
 
 }
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }; Point(1, 2);", "Point{x: 1, y: 2}"},
+		{"struct Point { x, y }; Point(1);", "Point{x: 1, y: null}"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.y;", "2"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.x = 7; p;", "Point{x: 7, y: 2}"},
+		{"struct Point { x, y func sum() { return self.x + self.y } }; Point(3, 4).sum();", "7"},
+		{"struct C { n func init(v) { self.n = v * 2 } }; C(4).n;", "8"},
+		{"struct Point { x }; Point(1).z;", "Point has no member z"},
+		{"struct Point { x }; let p = Point(1); p.z = 3;", "Point has no field z"},
+		{"struct Point { x }; Point(1, 2);", "too many arguments for Point. got=2, want=1"},
+	}
+	for _, tt := range tests {
+		result := testEvaluator(t, tt.input)
+		if result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestTraits(t *testing.T) {
+	shapes := `trait Shape { area() }
+struct Square impl Shape { side func area() { return self.side * self.side } }
+struct Rect impl Shape { w, h func area() { return self.w * self.h } }
+struct Plain { v }
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{shapes + "Shape.area(Square(3));", "9"},
+		{shapes + "Shape.area(Rect(2, 5));", "10"},
+		{shapes + "implements(Rect(2, 5), Shape);", "true"},
+		{shapes + "implements(Plain(1), Shape);", "false"},
+		{shapes + "Shape.area(Plain(1));", "Plain{v: 1} does not implement Shape"},
+		{shapes + "Shape.perimeter;", "trait Shape has no method perimeter"},
+		{"trait T { f(a) }; struct S impl T { };", "S does not implement T: missing method f"},
+		{"trait T { f(a) }; struct S impl T { func f() { 1 } };", "S does not implement T: method f takes 0 parameters, want 1"},
+	}
+	for _, tt := range tests {
+		result := testEvaluator(t, tt.input)
+		if result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"utils.syn":   "export let count = 0; let secret = 1; export func bump() { count = count + 1 };",
+		"lib/sq.syn":  "export func sq(x) { x * x };",
+		"a.syn":       `import "b.syn";`,
+		"b.syn":       `import "a.syn";`,
+		"counted.syn": `import "utils.syn" as u; u.bump();`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "utils.syn" as u; u.bump(); u.count;`, "1"},
+		{`import "utils.syn"; utils.count;`, "0"},
+		{`import "utils.syn" as u; u.secret;`, "module utils does not export secret"},
+		{`import "sq.syn"; sq.sq(3);`, "9"},
+		{`import "missing.syn";`, "module not found: missing.syn"},
+		{`import "a.syn";`, "import cycle: a.syn -> b.syn -> a.syn"},
+		{`import "counted.syn"; import "utils.syn" as u; u.count;`, "1"},
+		{`let f = func() { export let x = 1; }; f();`, "export is only allowed at the top level of a module"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewTokenizer(tt.input)
+		p := parser.NewParser(l)
+		program := p.ParseProgram()
+		checkErrors(t, p)
+
+		e := New()
+		e.SearchPath = []string{filepath.Join(dir, "lib")}
+		result := e.EvalModule(program, filepath.Join(dir, "main.syn"), types.NewContext(nil))
+		if result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestCollectionsAndMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[1, "a", 2.5];`, `[1, "a", 2.5]`},
+		{`let a = [1, 2, 3]; a[-1];`, "3"},
+		{`let a = [1, 2, 3]; a[3];`, "index out of range: 3 with length 3"},
+		{`let a = [1, 2]; a[0] = 5; a.push(6); a;`, "[5, 2, 6]"},
+		{`let a = [1, 2]; a.pop(); a;`, "[1]"},
+		{`[1, 2, 3].join("-");`, "1-2-3"},
+		{`[1, 2, 3].contains(2);`, "true"},
+		{`let m = {"a": 1, 2: true}; m["c"] = 3; m;`, `{"a": 1, 2: true, "c": 3}`},
+		{`let m = {"a": 1}; m["b"];`, "null"},
+		{`{"b": 1, "a": 2}.keys();`, `["b", "a"]`},
+		{`let m = {"a": 1}; m.delete("a"); m.len();`, "0"},
+		{`{[1]: 2};`, "unusable as map key: array"},
+		{`"Hello".upper();`, "HELLO"},
+		{`"a,b".split(",");`, `["a", "b"]`},
+		{`"abc"[1];`, "b"},
+		{`"abc".reverse();`, "no such method: string.reverse"},
+		{`5.foo;`, "cannot access member foo of 5"},
+		{"math.sqrt(16);", "4.0"},
+		{"math.pow(2, 3);", "8.0"},
+		{"math.tau;", "module math does not export tau"},
+		{"7 / 2;", "3"},
+		{"7 / 0;", "division by zero"},
+		{"1 + 0.5;", "1.5"},
+		{`"a" + "b";`, "ab"},
+		{`"a" == "a";`, "true"},
+		{`"a" + 1;`, "Unknown inflix operation a + 1"},
+	}
+	for _, tt := range tests {
+		result := testEvaluator(t, tt.input)
+		if result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestBuiltinRegistryAndIO(t *testing.T) {
+	var out bytes.Buffer
+	e := New()
+	e.SetIO(strings.NewReader("alice\nbob\n"), &out)
+	e.Builtins.RegisterFunc("double", func(in types.Interpreter, args ...types.Object) types.Object {
+		return &types.Int{Value: args[0].(*types.Int).Value * 2}
+	})
+	e.Builtins.Remove("len")
+
+	program := parser.NewParser(lexer.NewTokenizer(`print(input(), "-"); println(input(), double(21)); len("x");`)).ParseProgram()
+	result := e.Eval(program, types.NewContext(nil))
+
+	if out.String() != "alice-bob\n42\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if result == nil || result.String() != "identifier not found: len" {
+		t.Errorf("expected removed builtin to be unknown, got %v", result)
+	}
+
+	e.Builtins.Restrict("print")
+	if names := e.Builtins.Names(); len(names) != 1 || names[0] != "print" {
+		t.Errorf("expected only print after Restrict, got %v", names)
+	}
+	if _, ok := New().Builtins.Lookup("len"); !ok {
+		t.Error("registries must not be shared between evaluators")
+	}
+}
+
+func TestBuiltinCallbacks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], func(x) { x * 2 });", "[2, 4, 6]"},
+		{"filter([1, 2, 3, 4], func(x) { x > 2 });", "[3, 4]"},
+		{"reduce([1, 2, 3], func(acc, x) { acc + x }, 10);", "16"},
+		{"sort([3, 1, 2]);", "[1, 2, 3]"},
+		{"sort([1, 3, 2], func(a, b) { a > b });", "[3, 2, 1]"},
+		{`sort(["pear", "apple", "Fig", "app"]);`, `["Fig", "app", "apple", "pear"]`},
+		{`sort([1, "a"]);`, "Unknown inflix operation a < 1"},
+		{`let a = [2, 1]; sort(a); a;`, "[2, 1]"},
+		{`map([1], func(x) { x + "a" });`, "Unknown inflix operation 1 + a"},
+		{`map([1], func(x, y) { x });`, "wrong number of arguments. got=1, want=2"},
+		{`struct P { v }; map([1, 2], P);`, "[P{v: 1}, P{v: 2}]"},
+		{`struct P { v }; map([1, 2], P(1));`, "argument 2 (fn) to `map` must be func, got P"},
+		{"map(1, len);", "argument 1 (arr) to `map` must be array, got int"},
+	}
+	for _, tt := range tests {
+		result := testEvaluator(t, tt.input)
+		if result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, result)
+		}
+	}
+
+	e := New()
+	e.Builtins.RegisterFunc("where", func(in types.Interpreter, args ...types.Object) types.Object {
+		return &types.String{Value: in.Position().String()}
+	})
+	program := parser.NewParser(lexer.NewTokenizer("let x = 1;\n  where();")).ParseProgram()
+	if result := e.Eval(program, types.NewContext(nil)); result.String() != "2:3" {
+		t.Errorf("expected call site 2:3, got %s", result)
+	}
+}
+
+func TestCallDepth(t *testing.T) {
+	if result := testEvaluator(t, "let f = func() { f() }; f();"); result == nil || result.String() != "maximum call depth exceeded" {
+		t.Errorf("expected the recursion to fail, got %v", result)
+	}
+
+	e := New()
+	e.MaxCallDepth = 3
+	ctx := types.NewContext(nil)
+	program := parser.NewParser(lexer.NewTokenizer("func down(n) { if (n == 0) { 0 } else { down(n - 1) } }")).ParseProgram()
+	e.Eval(program, ctx)
+	for _, tt := range []struct {
+		input    string
+		expected string
+	}{
+		{"down(2);", "0"},
+		{"down(3);", "maximum call depth exceeded"},
+		{"down(2);", "0"},
+	} {
+		program := parser.NewParser(lexer.NewTokenizer(tt.input)).ParseProgram()
+		if result := e.Eval(program, ctx); result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestBuiltinSignatures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len("abc", 1);`, "wrong number of arguments to `len`. got=2, want=1"},
+		{"len(5);", "argument 1 (value) to `len` must be string or array or map, got int"},
+		{"sort();", "wrong number of arguments to `sort`. got=0, want=1 to 2"},
+		{"sort([2, 1], 3);", "argument 2 (cmp) to `sort` must be func, got int"},
+		{`"a".split(1);`, "argument 1 (sep) to `string.split` must be string, got int"},
+		{`[].push();`, "[]"},
+		{"math.sqrt(true);", "argument 1 (x) to `math.sqrt` must be int or float, got bool"},
+		{"len;", "builtin len"},
+	}
+	for _, tt := range tests {
+		result := testEvaluator(t, tt.input)
+		if result == nil || result.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, result)
+		}
+	}
+
+	var out bytes.Buffer
+	e := New()
+	e.SetIO(strings.NewReader(""), &out)
+	if err := e.Builtins.Define("greet(name: string, times?: int): string", "Greets by name.",
+		func(in types.Interpreter, args ...types.Object) types.Object {
+			return &types.String{Value: "hi " + args[0].String()}
+		}); err != nil {
+		t.Fatal(err)
+	}
+
+	program := parser.NewParser(lexer.NewTokenizer(`help(greet); help(sort); help(func(a: int, b) { a });`)).ParseProgram()
+	e.Eval(program, types.NewContext(nil))
+
+	expected := "greet(name: string, times?: int): string\n    Greets by name.\n" +
+		"sort(arr: array, cmp?: func): array\n    Returns a sorted copy of the array of numbers or strings, cmp(a, b) returns true when a goes before b.\n" +
+		"func(a: int, b)\n"
+	if out.String() != expected {
+		t.Errorf("unexpected help output %q", out.String())
+	}
+}
+
+type statementRecorder []string
+
+func (r *statementRecorder) Statement(n ast.Node, stack []Frame) {
+	top := stack[len(stack)-1]
+	*r = append(*r, fmt.Sprintf("%d %s/%d", n.Pos().Row, top.Function, len(stack)))
+}
+
+func TestHook(t *testing.T) {
+	input := `struct P { v; func get() { self.v } }
+func twice(x) {
+  let y = x * 2;
+  y
+}
+let p = P(twice(1));
+p.get();
+let f = func() { 1 }; f();`
+
+	var r statementRecorder
+	e := New()
+	e.SetHook(&r)
+	program := parser.NewParser(lexer.NewTokenizer(input)).ParseProgram()
+	if result := e.Eval(program, types.NewContext(nil)); result.String() != "1" {
+		t.Fatalf("expected 1, got %s", result)
+	}
+
+	expected := []string{"1 /1", "2 /1", "6 /1", "3 twice/2", "4 twice/2", "7 /1", "1 P.get/2", "8 /1", "8 /1", "8 f/2"}
+	if !reflect.DeepEqual([]string(r), expected) {
+		t.Errorf("expected statements %q, got %q", expected, r)
+	}
+	if stack := e.Stack(); len(stack) != 0 {
+		t.Errorf("expected no frames after evaluation, got %v", stack)
+	}
+}
+
+type callRecorder struct {
+	statementRecorder
+}
+
+func (r *callRecorder) Call(stack []Frame, args []types.Object) {
+	r.statementRecorder = append(r.statementRecorder, fmt.Sprintf("call %s%v/%d", stack[len(stack)-1].Name(), args, len(stack)))
+}
+
+func (r *callRecorder) Return(stack []Frame, result types.Object) {
+	r.statementRecorder = append(r.statementRecorder, fmt.Sprintf("return %s %s/%d", stack[len(stack)-1].Name(), result, len(stack)))
+}
+
+func TestCallHook(t *testing.T) {
+	input := `func fact(n) {
+  if (n < 2) { return 1; }
+  n * fact(n - 1)
+}
+fact(2);`
+
+	var r callRecorder
+	e := New()
+	e.SetHook(&r)
+	program := parser.NewParser(lexer.NewTokenizer(input)).ParseProgram()
+	e.Eval(program, types.NewContext(nil))
+
+	expected := []string{
+		"1 /1", "5 /1",
+		"call fact[2]/2", "2 fact/2", "3 fact/2",
+		"call fact[1]/3", "2 fact/3", "2 fact/3", "return fact 1/3",
+		"return fact 2/2",
+	}
+	if !reflect.DeepEqual([]string(r.statementRecorder), expected) {
+		t.Errorf("expected events %q, got %q", expected, r.statementRecorder)
+	}
+}
+
+type coverageRecorder struct {
+	statementRecorder
+}
+
+func (r *coverageRecorder) Module(path string, program *ast.Program) {
+	r.statementRecorder = append(r.statementRecorder, fmt.Sprintf("module %s %d", filepath.Base(path), len(program.Statements)))
+}
+
+func (r *coverageRecorder) Branch(n *ast.ConditionalExpression, taken bool, stack []Frame) {
+	r.statementRecorder = append(r.statementRecorder, fmt.Sprintf("branch %d %t/%d", n.Pos().Row, taken, len(stack)))
+}
+
+func TestCoverageHook(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.syn"), []byte("export let one = 1;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input := `import "lib.syn";
+func sign(n) {
+  if (n < 0) { -1 } else { 1 }
+}
+sign(lib.one);`
+
+	var r coverageRecorder
+	e := New()
+	e.SetHook(&r)
+	program := parser.NewParser(lexer.NewTokenizer(input)).ParseProgram()
+	e.EvalModule(program, filepath.Join(dir, "main.syn"), types.NewContext(nil))
+
+	expected := []string{
+		"module main.syn 3", "1 /1", "module lib.syn 1", "1 /2", "2 /1", "5 /1",
+		"3 sign/2", "branch 3 false/2", "3 sign/2",
+	}
+	if !reflect.DeepEqual([]string(r.statementRecorder), expected) {
+		t.Errorf("expected events %q, got %q", expected, r.statementRecorder)
+	}
+}
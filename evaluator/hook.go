@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"Simply/ast"
+	"Simply/types"
+	"path/filepath"
+)
+
+// Frame is a call of a script function, or the top level of a program, being evaluated.
+type Frame struct {
+	Function string         //Empty at the top level, func@line for anonymous functions
+	File     string         //Absolute path of the module the code is in, empty when it is not from a file
+	Pos      ast.Position   //Of the statement being evaluated
+	Ctx      *types.Context //Scope of the statement
+}
+
+// Name is the name of the function, or of the file at the top level.
+func (f Frame) Name() string {
+	switch {
+	case f.Function != "":
+		return f.Function
+	case f.File != "":
+		return filepath.Base(f.File)
+	}
+	return "<top level>"
+}
+
+// Hook observes evaluation, see SetHook.
+type Hook interface {
+	// Statement is called before n is evaluated. stack holds the active frames,
+	// innermost last, and is only valid until Statement returns.
+	Statement(n ast.Node, stack []Frame)
+}
+
+// CallHook is a Hook that is also notified of calls to script functions.
+type CallHook interface {
+	Hook
+	// Call is called once the frame of the function is on the stack, before its body runs.
+	Call(stack []Frame, args []types.Object)
+	// Return is called with the result of the function while its frame is still on the stack.
+	Return(stack []Frame, result types.Object)
+}
+
+// CoverageHook is a Hook that is also notified of the modules being evaluated and
+// of the arm each conditional takes.
+type CoverageHook interface {
+	Hook
+	// Module is called before the program of a module is evaluated, path is absolute.
+	Module(path string, program *ast.Program)
+	// Branch is called once the condition of n is evaluated, taken tells which arm runs.
+	Branch(n *ast.ConditionalExpression, taken bool, stack []Frame)
+}
+
+// SetHook makes the evaluator notify h of every statement it evaluates, and of
+// calls, modules and branches when it is a CallHook or a CoverageHook. nil removes it.
+func (e *Evaluator) SetHook(h Hook) {
+	e.hook = h
+	e.callHook, _ = h.(CallHook)
+	e.coverage, _ = h.(CoverageHook)
+}
+
+// Stack returns a copy of the active frames, innermost last.
+func (e *Evaluator) Stack() []Frame {
+	return append([]Frame(nil), e.frames...)
+}
+
+func (e *Evaluator) pushFrame(function, file string) {
+	e.frames = append(e.frames, Frame{Function: function, File: file})
+}
+
+func (e *Evaluator) popFrame() {
+	e.frames = e.frames[:len(e.frames)-1]
+}
+
+// file is the path of the module whose code is being evaluated.
+func (e *Evaluator) file() string {
+	if len(e.frames) > 0 {
+		return e.frames[len(e.frames)-1].File
+	}
+	if current := e.modules.current(); current != nil {
+		return current.Path
+	}
+	return ""
+}
+
+// statement records where the innermost frame is before n is evaluated.
+func (e *Evaluator) statement(n ast.Node, ctx *types.Context) {
+	if len(e.frames) > 0 {
+		top := &e.frames[len(e.frames)-1]
+		top.Pos, top.Ctx = n.Pos(), ctx
+	}
+	if e.hook != nil {
+		e.hook.Statement(n, e.frames)
+	}
+}
@@ -2,48 +2,233 @@ package evaluator
 
 import (
 	"Simply/types"
-	"bufio"
 	"fmt"
-	"os"
+	"sort"
+	"strings"
 )
 
-var internalCalls = map[string]*types.InternalCall{
-	"len":     {Fn: internal_len},
-	"println": {Fn: internal_println},
-	"print":   {Fn: internal_print},
-	"input":   {Fn: internal_input},
+type builtinDefinition struct {
+	spec string
+	doc  string
+	fn   types.InternalCallFunc
 }
 
-func internal_len(args ...types.Object) types.Object {
-	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
-	}
+var internalCalls = []builtinDefinition{
+	{"len(value: string|array|map): int", "Returns the number of bytes in a string or elements in an array or map.", internal_len},
+	{"println(values...)", "Prints every value on its own line.", internal_println},
+	{"print(values...)", "Prints the values without separators.", internal_print},
+	{"input(prompt?: string): string", "Prints the prompt and reads a line of input, returns null at the end of input.", internal_input},
+	{"implements(value, trait: trait): bool", "Reports whether the value is an instance of a struct implementing the trait.", internal_implements},
+	{"map(arr: array, fn: func): array", "Returns a new array with fn applied to every element.", internal_map},
+	{"filter(arr: array, fn: func): array", "Returns the elements for which fn returns a true value.", internal_filter},
+	{"reduce(arr: array, fn: func, initial)", "Folds the array into a single value by calling fn(accumulator, element).", internal_reduce},
+	{"sort(arr: array, cmp?: func): array", "Returns a sorted copy of the array of numbers or strings, cmp(a, b) returns true when a goes before b.", internal_sort},
+	{"help(value)", "Prints the signature and documentation of a function, struct or trait.", internal_help},
+	{"assert(condition, message?: string)", "Fails with the message unless the condition is true.", internal_assert},
+	{"assert_eq(actual, expected, message?: string)", "Fails with a diff of the values unless they are equal, collections and structs are compared by content.", internal_assert_eq},
+	{"assert_error(fn: func, expected?: string): string", "Calls fn and fails unless it returns an error containing expected, returns the message of the error.", internal_assert_error},
+}
+
+func internal_len(in types.Interpreter, args ...types.Object) types.Object {
 	switch arg := args[0].(type) {
 	case *types.String:
 		return &types.Int{Value: int64(len(arg.Value))}
+	case *types.Array:
+		return &types.Int{Value: int64(len(arg.Elements))}
 	default:
-		return newError("argument to `len` not supported")
+		return &types.Int{Value: int64(arg.(*types.Map).Len())}
 	}
 }
 
-func internal_println(args ...types.Object) types.Object {
+func internal_println(in types.Interpreter, args ...types.Object) types.Object {
 	for _, a := range args {
-		fmt.Println(a)
+		fmt.Fprintln(in.Out(), a)
 	}
 
 	return types.NULL
 }
 
-func internal_print(args ...types.Object) types.Object {
+func internal_print(in types.Interpreter, args ...types.Object) types.Object {
 	for _, a := range args {
-		fmt.Print(a)
+		fmt.Fprint(in.Out(), a)
 	}
 
 	return types.NULL
 }
 
-func internal_input(args ...types.Object) types.Object {
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	return &types.String{Value: scanner.Text()}
+func internal_input(in types.Interpreter, args ...types.Object) types.Object {
+	if len(args) > 0 {
+		fmt.Fprint(in.Out(), args[0])
+	}
+
+	line, err := in.In().ReadString('\n')
+	if err != nil && line == "" {
+		return types.NULL
+	}
+	return &types.String{Value: strings.TrimRight(line, "\r\n")}
+}
+
+func internal_implements(in types.Interpreter, args ...types.Object) types.Object {
+	trait := args[1].(*types.Trait)
+	instance, ok := args[0].(*types.Instance)
+	return getBoolType(ok && instance.Type.Implements(trait))
+}
+
+func internal_map(in types.Interpreter, args ...types.Object) types.Object {
+	arr := args[0].(*types.Array)
+
+	result := &types.Array{Elements: make([]types.Object, 0, len(arr.Elements))}
+	for _, el := range arr.Elements {
+		mapped := in.Call(args[1], el)
+		if isError(mapped) {
+			return mapped
+		}
+		result.Elements = append(result.Elements, mapped)
+	}
+
+	return result
+}
+
+func internal_filter(in types.Interpreter, args ...types.Object) types.Object {
+	arr := args[0].(*types.Array)
+
+	result := &types.Array{Elements: []types.Object{}}
+	for _, el := range arr.Elements {
+		keep := in.Call(args[1], el)
+		if isError(keep) {
+			return keep
+		}
+		if isConditionTrue(keep) {
+			result.Elements = append(result.Elements, el)
+		}
+	}
+
+	return result
+}
+
+func internal_reduce(in types.Interpreter, args ...types.Object) types.Object {
+	arr := args[0].(*types.Array)
+
+	acc := args[2]
+	for _, el := range arr.Elements {
+		acc = in.Call(args[1], acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+
+	return acc
+}
+
+func internal_sort(in types.Interpreter, args ...types.Object) types.Object {
+	elements := append([]types.Object{}, args[0].(*types.Array).Elements...)
+
+	less := defaultLess
+	if len(args) == 2 {
+		less = func(a, b types.Object) types.Object { return in.Call(args[1], a, b) }
+	}
+
+	var sortErr types.Object
+	sort.SliceStable(elements, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		result := less(elements[i], elements[j])
+		if isError(result) {
+			sortErr = result
+			return false
+		}
+		return isConditionTrue(result)
+	})
+
+	if sortErr != nil {
+		return sortErr
+	}
+
+	return &types.Array{Elements: elements}
+}
+
+// defaultLess orders numbers by value and strings byte-wise.
+func defaultLess(a, b types.Object) types.Object {
+	if a, ok := a.(*types.String); ok {
+		if b, ok := b.(*types.String); ok {
+			return getBoolType(a.Value < b.Value)
+		}
+	}
+	return evalInfixOperation("<", a, b)
+}
+
+func internal_help(in types.Interpreter, args ...types.Object) types.Object {
+	fmt.Fprintln(in.Out(), describe(args[0]))
+	return types.NULL
+}
+
+func describe(o types.Object) string {
+	switch o := o.(type) {
+	case *types.InternalCall:
+		if o.Signature == nil {
+			return "builtin without signature"
+		}
+		if o.Signature.Doc == "" {
+			return o.Signature.String()
+		}
+		return o.Signature.String() + "\n    " + o.Signature.Doc
+	case *types.Function:
+		params := make([]string, len(o.Parameters))
+		for i, p := range o.Parameters {
+			params[i] = p.Value
+			if p.Type != nil {
+				params[i] += ": " + p.Type.Name
+			}
+		}
+		return fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	case *types.StructType:
+		methods := make([]string, 0, len(o.Methods))
+		for name, m := range o.Methods {
+			methods = append(methods, name+strings.TrimPrefix(describe(m), "func"))
+		}
+		sort.Strings(methods)
+		return fmt.Sprintf("struct %s { %s }", o.Name, strings.Join(append(append([]string{}, o.Fields...), methods...), ", "))
+	case *types.Trait:
+		return fmt.Sprintf("trait %s { %s }", o.Name, strings.Join(o.Methods, ", "))
+	case *types.Module:
+		names := make([]string, 0, len(o.Exports))
+		for name := range o.Exports {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Sprintf("module %s { %s }", o.Name, strings.Join(names, ", "))
+	default:
+		return types.TypeName(o)
+	}
+}
+
+// checkArguments validates the arguments against the signature of a builtin.
+func checkArguments(sig *types.Signature, args []types.Object) types.Object {
+	min, max := sig.Arity()
+	if len(args) < min || max >= 0 && len(args) > max {
+		var want string
+		switch {
+		case max < 0:
+			want = fmt.Sprintf("at least %d", min)
+		case min == max:
+			want = fmt.Sprint(min)
+		default:
+			want = fmt.Sprintf("%d to %d", min, max)
+		}
+		return newError("wrong number of arguments to `%s`. got=%d, want=%s", sig.Name, len(args), want)
+	}
+
+	for i, arg := range args {
+		param := sig.Params[len(sig.Params)-1]
+		if i < len(sig.Params) {
+			param = sig.Params[i]
+		}
+		if !param.Accepts(arg) {
+			return newError("argument %d (%s) to `%s` must be %s, got %s",
+				i+1, param.Name, sig.Name, strings.Join(param.Types, " or "), types.TypeName(arg))
+		}
+	}
+
+	return nil
 }
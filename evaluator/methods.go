@@ -0,0 +1,213 @@
+package evaluator
+
+import (
+	"Simply/types"
+	"math"
+	"sort"
+	"strings"
+)
+
+type builtinMethod func(receiver types.Object, args ...types.Object) types.Object
+
+type methodDefinition struct {
+	spec string
+	doc  string
+	fn   builtinMethod
+}
+
+type method struct {
+	sig *types.Signature
+	fn  builtinMethod
+}
+
+var stringMethods = newMethodTable("string", []methodDefinition{
+	{"len(): int", "Returns the number of bytes.", func(r types.Object, args ...types.Object) types.Object {
+		return internal_len(nil, r)
+	}},
+	{"upper(): string", "Returns the string in upper case.", func(r types.Object, args ...types.Object) types.Object {
+		return &types.String{Value: strings.ToUpper(r.(*types.String).Value)}
+	}},
+	{"lower(): string", "Returns the string in lower case.", func(r types.Object, args ...types.Object) types.Object {
+		return &types.String{Value: strings.ToLower(r.(*types.String).Value)}
+	}},
+	{"trim(): string", "Removes leading and trailing white space.", func(r types.Object, args ...types.Object) types.Object {
+		return &types.String{Value: strings.TrimSpace(r.(*types.String).Value)}
+	}},
+	{"contains(sub: string): bool", "Reports whether sub is within the string.", func(r types.Object, args ...types.Object) types.Object {
+		return getBoolType(strings.Contains(r.(*types.String).Value, args[0].(*types.String).Value))
+	}},
+	{"index(sub: string): int", "Returns the index of the first sub, or -1.", func(r types.Object, args ...types.Object) types.Object {
+		return &types.Int{Value: int64(strings.Index(r.(*types.String).Value, args[0].(*types.String).Value))}
+	}},
+	{"split(sep: string): array", "Splits the string around each sep.", func(r types.Object, args ...types.Object) types.Object {
+		result := &types.Array{}
+		for _, part := range strings.Split(r.(*types.String).Value, args[0].(*types.String).Value) {
+			result.Elements = append(result.Elements, &types.String{Value: part})
+		}
+		return result
+	}},
+	{"replace(old: string, new: string): string", "Replaces every old with new.", func(r types.Object, args ...types.Object) types.Object {
+		return &types.String{Value: strings.ReplaceAll(r.(*types.String).Value, args[0].(*types.String).Value, args[1].(*types.String).Value)}
+	}},
+})
+
+var arrayMethods = newMethodTable("array", []methodDefinition{
+	{"len(): int", "Returns the number of elements.", func(r types.Object, args ...types.Object) types.Object {
+		return internal_len(nil, r)
+	}},
+	{"push(values...): array", "Appends the values and returns the array.", func(r types.Object, args ...types.Object) types.Object {
+		arr := r.(*types.Array)
+		arr.Elements = append(arr.Elements, args...)
+		return arr
+	}},
+	{"pop()", "Removes and returns the last element.", func(r types.Object, args ...types.Object) types.Object {
+		arr := r.(*types.Array)
+		if len(arr.Elements) == 0 {
+			return newError("pop from empty array")
+		}
+		last := arr.Elements[len(arr.Elements)-1]
+		arr.Elements = arr.Elements[:len(arr.Elements)-1]
+		return last
+	}},
+	{"contains(value): bool", "Reports whether an element equals value.", func(r types.Object, args ...types.Object) types.Object {
+		return getBoolType(indexOf(r.(*types.Array), args[0]) >= 0)
+	}},
+	{"index(value): int", "Returns the index of the first element equal to value, or -1.", func(r types.Object, args ...types.Object) types.Object {
+		return &types.Int{Value: int64(indexOf(r.(*types.Array), args[0]))}
+	}},
+	{"join(sep: string): string", "Concatenates the elements with sep between them.", func(r types.Object, args ...types.Object) types.Object {
+		parts := make([]string, len(r.(*types.Array).Elements))
+		for i, el := range r.(*types.Array).Elements {
+			parts[i] = el.String()
+		}
+		return &types.String{Value: strings.Join(parts, args[0].(*types.String).Value)}
+	}},
+})
+
+var mapMethods = newMethodTable("map", []methodDefinition{
+	{"len(): int", "Returns the number of pairs.", func(r types.Object, args ...types.Object) types.Object {
+		return internal_len(nil, r)
+	}},
+	{"keys(): array", "Returns the keys in insertion order.", func(r types.Object, args ...types.Object) types.Object {
+		result := &types.Array{}
+		for _, p := range r.(*types.Map).Pairs() {
+			result.Elements = append(result.Elements, p.Key)
+		}
+		return result
+	}},
+	{"values(): array", "Returns the values in insertion order.", func(r types.Object, args ...types.Object) types.Object {
+		result := &types.Array{}
+		for _, p := range r.(*types.Map).Pairs() {
+			result.Elements = append(result.Elements, p.Value)
+		}
+		return result
+	}},
+	{"has(key): bool", "Reports whether the key is present.", func(r types.Object, args ...types.Object) types.Object {
+		_, ok := r.(*types.Map).Get(args[0])
+		return getBoolType(ok)
+	}},
+	{"delete(key): bool", "Removes the key and reports whether it was present.", func(r types.Object, args ...types.Object) types.Object {
+		return getBoolType(r.(*types.Map).Delete(args[0]))
+	}},
+})
+
+func newMethodTable(typeName string, definitions []methodDefinition) map[string]*method {
+	table := map[string]*method{}
+	for _, d := range definitions {
+		sig := mustParseSignature(d.spec, d.doc)
+		table[sig.Name] = &method{sig: sig, fn: d.fn}
+		sig.Name = typeName + "." + sig.Name
+	}
+	return table
+}
+
+func methodTable(receiver types.Object) map[string]*method {
+	switch receiver.(type) {
+	case *types.String:
+		return stringMethods
+	case *types.Array:
+		return arrayMethods
+	case *types.Map:
+		return mapMethods
+	}
+	return nil
+}
+
+// MethodSignatures returns the signatures of the methods of a built-in type, sorted by name.
+func MethodSignatures(receiver types.Object) []*types.Signature {
+	var sigs []*types.Signature
+	for _, m := range methodTable(receiver) {
+		sigs = append(sigs, m.sig)
+	}
+	sort.Slice(sigs, func(i, j int) bool { return sigs[i].Name < sigs[j].Name })
+	return sigs
+}
+
+// getBuiltinMethod binds the named method of a built-in type to the receiver.
+func getBuiltinMethod(receiver types.Object, name string) types.Object {
+	m, ok := methodTable(receiver)[name]
+	if !ok {
+		return newError("no such method: %s.%s", types.TypeName(receiver), name)
+	}
+
+	return &types.InternalCall{Signature: m.sig, Fn: func(in types.Interpreter, args ...types.Object) types.Object {
+		return m.fn(receiver, args...)
+	}}
+}
+
+func indexOf(arr *types.Array, o types.Object) int {
+	for i, el := range arr.Elements {
+		if eq, ok := evalInfixOperation("==", el, o).(*types.Bool); ok && eq.Value {
+			return i
+		}
+	}
+	return -1
+}
+
+// newBuiltinModules creates the built-in modules, every registry gets its own copy.
+func newBuiltinModules() map[string]*types.Module {
+	return map[string]*types.Module{
+		"math": newNamespace("math", map[string]types.Object{
+			"pi":    &types.Float{Value: math.Pi},
+			"e":     &types.Float{Value: math.E},
+			"sqrt":  mathFunc("sqrt", "Returns the square root of x.", math.Sqrt),
+			"floor": mathFunc("floor", "Rounds x down.", math.Floor),
+			"ceil":  mathFunc("ceil", "Rounds x up.", math.Ceil),
+			"abs":   mathFunc("abs", "Returns the absolute value of x.", math.Abs),
+			"pow": &types.InternalCall{
+				Signature: mustParseSignature("math.pow(x: int|float, y: int|float): float", "Returns x to the power of y."),
+				Fn: func(in types.Interpreter, args ...types.Object) types.Object {
+					return &types.Float{Value: math.Pow(toFloat(args[0]), toFloat(args[1]))}
+				},
+			},
+		}),
+	}
+}
+
+// newNamespace groups built-in values in a module so they are accessed like math.sqrt.
+func newNamespace(name string, members map[string]types.Object) *types.Module {
+	m := &types.Module{Name: name, Ctx: types.NewContext(nil), Exports: map[string]bool{}}
+	for k, v := range members {
+		m.Ctx.Set(k, v)
+		m.Exports[k] = true
+	}
+	return m
+}
+
+func mathFunc(name, doc string, fn func(float64) float64) *types.InternalCall {
+	return &types.InternalCall{
+		Signature: mustParseSignature("math."+name+"(x: int|float): float", doc),
+		Fn: func(in types.Interpreter, args ...types.Object) types.Object {
+			return &types.Float{Value: fn(toFloat(args[0]))}
+		},
+	}
+}
+
+func mustParseSignature(spec, doc string) *types.Signature {
+	sig, err := types.ParseSignature(spec)
+	if err != nil {
+		panic(err)
+	}
+	sig.Doc = doc
+	return sig
+}
@@ -0,0 +1,169 @@
+package evaluator
+
+import (
+	"Simply/ast"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/types"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type moduleLoader struct {
+	cache map[string]*types.Module
+	stack []*types.Module //Modules being evaluated, innermost last
+}
+
+func newModuleLoader() *moduleLoader {
+	return &moduleLoader{cache: map[string]*types.Module{}}
+}
+
+func (l *moduleLoader) current() *types.Module {
+	if len(l.stack) == 0 {
+		return nil
+	}
+	return l.stack[len(l.stack)-1]
+}
+
+// EvalModule evaluates the program as the module loaded from path, so that its
+// imports are resolved relative to it and its exports are recorded.
+func (e *Evaluator) EvalModule(program *ast.Program, path string, ctx *types.Context) types.Object {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return newError("invalid module path %s: %s", path, err)
+	}
+
+	module := &types.Module{Name: moduleName(path), Path: abs, Ctx: ctx, Exports: map[string]bool{}}
+
+	if e.coverage != nil {
+		e.coverage.Module(abs, program)
+	}
+
+	e.modules.stack = append(e.modules.stack, module)
+	defer func() { e.modules.stack = e.modules.stack[:len(e.modules.stack)-1] }()
+	result := e.Eval(program, ctx)
+
+	if !isError(result) {
+		e.modules.cache[abs] = module
+	}
+
+	return result
+}
+
+func moduleName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement, ctx *types.Context) types.Object {
+	path, ok := e.resolveImport(node.Path.Value)
+	if !ok {
+		return newError("module not found: %s", node.Path.Value)
+	}
+
+	module, err := e.loadModule(path)
+	if err != nil {
+		return err
+	}
+
+	name := moduleName(path)
+	if node.Alias != nil {
+		name = node.Alias.Value
+	}
+	ctx.Set(name, module)
+
+	return types.NULL
+}
+
+// resolveImport looks for the file next to the importing module first and then in the search path.
+func (e *Evaluator) resolveImport(path string) (string, bool) {
+	if filepath.IsAbs(path) {
+		return path, fileExists(path)
+	}
+
+	dir := "."
+	if current := e.modules.current(); current != nil {
+		dir = filepath.Dir(current.Path)
+	}
+
+	for _, d := range append([]string{dir}, e.SearchPath...) {
+		candidate := filepath.Join(d, path)
+		if fileExists(candidate) {
+			abs, err := filepath.Abs(candidate)
+			return abs, err == nil
+		}
+	}
+
+	return "", false
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func (e *Evaluator) loadModule(path string) (*types.Module, types.Object) {
+	if module, ok := e.modules.cache[path]; ok {
+		return module, nil
+	}
+
+	for i, m := range e.modules.stack {
+		if m.Path == path {
+			var cycle []string
+			for _, v := range e.modules.stack[i:] {
+				cycle = append(cycle, filepath.Base(v.Path))
+			}
+			cycle = append(cycle, filepath.Base(path))
+			return nil, newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newError("failed to read module %s: %s", path, err)
+	}
+
+	p := parser.NewParser(lexer.NewTokenizer(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		return nil, newError("failed to parse module %s: %s", path, strings.Join(p.Errors, "; "))
+	}
+
+	result := e.EvalModule(program, path, types.NewContext(nil))
+	if isError(result) {
+		return nil, result
+	}
+
+	return e.modules.cache[path], nil
+}
+
+func (e *Evaluator) evalExportStatement(node *ast.ExportStatement, ctx *types.Context) types.Object {
+	module := e.modules.current()
+	if module == nil || module.Ctx != ctx {
+		return newError("export is only allowed at the top level of a module")
+	}
+
+	result := e.Eval(node.Statement, ctx)
+	if isError(result) {
+		return result
+	}
+
+	module.Exports[declaredName(node.Statement)] = true
+
+	return types.NULL
+}
+
+func declaredName(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.DeclarativeStatement:
+		return n.Name.Value
+	case *ast.FunctionStatement:
+		return n.Name.Value
+	case *ast.StructStatement:
+		return n.Name.Value
+	case *ast.TraitStatement:
+		return n.Name.Value
+	}
+	return ""
+}
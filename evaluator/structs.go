@@ -0,0 +1,196 @@
+package evaluator
+
+import (
+	"Simply/ast"
+	"Simply/types"
+)
+
+const (
+	receiverName    = "self"
+	constructorName = "init"
+)
+
+func (e *Evaluator) evalStructStatement(node *ast.StructStatement, ctx *types.Context) types.Object {
+	st := &types.StructType{Name: node.Name.Value, Methods: map[string]*types.Function{}}
+
+	for _, f := range node.Fields {
+		if st.HasField(f.Value) {
+			return newError("duplicate field %s in struct %s", f.Value, st.Name)
+		}
+		st.Fields = append(st.Fields, f.Value)
+	}
+
+	for _, m := range node.Methods {
+		if _, ok := st.Methods[m.Name.Value]; ok || st.HasField(m.Name.Value) {
+			return newError("duplicate member %s in struct %s", m.Name.Value, st.Name)
+		}
+		st.Methods[m.Name.Value] = e.newFunction(st.Name+"."+m.Name.Value, m.Function, ctx)
+	}
+
+	for _, t := range node.Traits {
+		obj, ok := ctx.Get(t.Value)
+		if !ok {
+			return newError("identifier not found: " + t.Value)
+		}
+		trait, ok := obj.(*types.Trait)
+		if !ok {
+			return newError("%s is not a trait", t.Value)
+		}
+		if err := checkTraitImplementation(st, trait); err != nil {
+			return err
+		}
+		st.Traits = append(st.Traits, trait)
+	}
+
+	ctx.Set(st.Name, st)
+
+	return types.NULL
+}
+
+func (e *Evaluator) newInstance(st *types.StructType, args []types.Object, pos ast.Position) types.Object {
+	instance := &types.Instance{Type: st, Fields: make(map[string]types.Object, len(st.Fields))}
+	for _, f := range st.Fields {
+		instance.Fields[f] = types.NULL
+	}
+
+	if init, ok := st.Methods[constructorName]; ok {
+		result := e.executeFunction(bindMethod(instance, init), args, pos)
+		if isError(result) {
+			return result
+		}
+		return instance
+	}
+
+	if len(args) > len(st.Fields) {
+		return newError("too many arguments for %s. got=%d, want=%d", st.Name, len(args), len(st.Fields))
+	}
+
+	for idx, arg := range args {
+		instance.Fields[st.Fields[idx]] = arg
+	}
+
+	return instance
+}
+
+// bindMethod returns a copy of the method whose context has the receiver bound to self.
+func bindMethod(receiver types.Object, method *types.Function) *types.Function {
+	ctx := types.NewContext(method.Ctx)
+	ctx.Set(receiverName, receiver)
+
+	return &types.Function{Name: method.Name, File: method.File, Parameters: method.Parameters, Body: method.Body, Ctx: ctx}
+}
+
+func (e *Evaluator) evalMemberExpression(node *ast.MemberExpression, ctx *types.Context) types.Object {
+	obj := e.Eval(node.Object, ctx)
+	if isError(obj) {
+		return obj
+	}
+
+	return e.getMember(obj, node.Member.Value)
+}
+
+func (e *Evaluator) getMember(obj types.Object, name string) types.Object {
+	switch obj := obj.(type) {
+	case *types.Instance:
+		if val, ok := obj.Fields[name]; ok {
+			return val
+		}
+		if method, ok := obj.Type.Methods[name]; ok {
+			return bindMethod(obj, method)
+		}
+		return newError("%s has no member %s", obj.Type.Name, name)
+	case *types.Trait:
+		if _, ok := obj.Arity[name]; ok {
+			return &types.TraitMethod{Trait: obj, Name: name}
+		}
+		return newError("trait %s has no method %s", obj.Name, name)
+	case *types.Module:
+		if val, ok := obj.Ctx.Get(name); ok && obj.Exports[name] {
+			return val
+		}
+		return newError("module %s does not export %s", obj.Name, name)
+	case *types.String, *types.Array, *types.Map:
+		return getBuiltinMethod(obj, name)
+	default:
+		return newError("cannot access member %s of %s", name, obj.String())
+	}
+}
+
+func (e *Evaluator) evalAssignmentExpression(node *ast.AssignmentExpression, ctx *types.Context) types.Object {
+	val := e.Eval(node.Value, ctx)
+	if isError(val) {
+		return val
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if !ctx.Assign(target.Value, val) {
+			return newError("identifier not found: " + target.Value)
+		}
+	case *ast.IndexExpression:
+		return e.evalIndexAssignment(target, val, ctx)
+	case *ast.MemberExpression:
+		obj := e.Eval(target.Object, ctx)
+		if isError(obj) {
+			return obj
+		}
+
+		instance, ok := obj.(*types.Instance)
+		if !ok {
+			return newError("cannot assign member %s of %s", target.Member.Value, obj.String())
+		}
+		if !instance.Type.HasField(target.Member.Value) {
+			return newError("%s has no field %s", instance.Type.Name, target.Member.Value)
+		}
+		instance.Fields[target.Member.Value] = val
+	default:
+		return newError("invalid assignment target")
+	}
+
+	return val
+}
+
+func (e *Evaluator) evalTraitStatement(node *ast.TraitStatement, ctx *types.Context) types.Object {
+	trait := &types.Trait{Name: node.Name.Value, Arity: map[string]int{}}
+
+	for _, m := range node.Methods {
+		if _, ok := trait.Arity[m.Name.Value]; ok {
+			return newError("duplicate method %s in trait %s", m.Name.Value, trait.Name)
+		}
+		trait.Methods = append(trait.Methods, m.Name.Value)
+		trait.Arity[m.Name.Value] = len(m.Parameters)
+	}
+
+	ctx.Set(trait.Name, trait)
+
+	return types.NULL
+}
+
+func checkTraitImplementation(st *types.StructType, trait *types.Trait) types.Object {
+	for _, name := range trait.Methods {
+		method, ok := st.Methods[name]
+		if !ok {
+			return newError("%s does not implement %s: missing method %s", st.Name, trait.Name, name)
+		}
+		if len(method.Parameters) != trait.Arity[name] {
+			return newError("%s does not implement %s: method %s takes %d parameters, want %d",
+				st.Name, trait.Name, name, len(method.Parameters), trait.Arity[name])
+		}
+	}
+
+	return nil
+}
+
+// callTraitMethod dispatches Trait.method(receiver, args...) to the receiver's implementation.
+func (e *Evaluator) callTraitMethod(tm *types.TraitMethod, args []types.Object, pos ast.Position) types.Object {
+	if len(args) == 0 {
+		return newError("%s called without a receiver", tm.String())
+	}
+
+	instance, ok := args[0].(*types.Instance)
+	if !ok || !instance.Type.Implements(tm.Trait) {
+		return newError("%s does not implement %s", args[0].String(), tm.Trait.Name)
+	}
+
+	return e.executeFunction(bindMethod(instance, instance.Type.Methods[tm.Name]), args[1:], pos)
+}
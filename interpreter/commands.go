@@ -0,0 +1,126 @@
+package interpreter
+
+import (
+	"Simply/checker"
+	"Simply/types"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// commandPrefix starts the REPL meta-commands such as :help.
+const commandPrefix = ":"
+
+type replCommand struct {
+	name string
+	args string
+	help string
+	run  func(r *repl, arg string)
+}
+
+var replCommands []replCommand
+
+func init() {
+	//Assigned in init as :help lists the commands itself
+	replCommands = []replCommand{
+		{"help", "", "show this help", (*repl).help},
+		{"env", "", "list the bindings of the session", (*repl).env},
+		{"reset", "", "discard all bindings and loaded modules", func(r *repl, _ string) { r.reset() }},
+		{"load", "file", "run a script in the session", (*repl).load},
+		{"ast", "expr", "print the syntax tree of expr", (*repl).ast},
+		{"tokens", "expr", "print the tokens of expr", func(r *repl, arg string) { writeTokens(r.out, arg) }},
+		{"time", "expr", "evaluate expr and print how long it took", (*repl).time},
+		{"type", "expr", "print the type of expr without evaluating it", (*repl).typeOf},
+	}
+}
+
+func (r *repl) runCommand(input string) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(input, commandPrefix), " ")
+	arg = strings.TrimSpace(arg)
+
+	for _, c := range replCommands {
+		if c.name != name {
+			continue
+		}
+		if c.args != "" && arg == "" {
+			fmt.Fprintf(r.out, "usage: %s%s %s\n", commandPrefix, c.name, c.args)
+			return
+		}
+		c.run(r, arg)
+		return
+	}
+
+	fmt.Fprintf(r.out, "unknown command %s%s, see %shelp\n", commandPrefix, name, commandPrefix)
+}
+
+func (r *repl) help(string) {
+	for _, c := range replCommands {
+		usage := commandPrefix + c.name
+		if c.args != "" {
+			usage += " " + c.args
+		}
+		fmt.Fprintf(r.out, "  %-14s %s\n", usage, c.help)
+	}
+}
+
+func (r *repl) env(string) {
+	for _, name := range r.ctx.Names() {
+		v, _ := r.ctx.Get(name)
+		if v == nil {
+			v = types.NULL
+		}
+		fmt.Fprintf(r.out, "%s: %s = %s\n", name, types.TypeName(v), types.Inspect(v))
+	}
+}
+
+func (r *repl) load(path string) {
+	source, err := readSource(path, Streams{In: r.in, Out: r.out, Err: r.out})
+	if err != nil {
+		return
+	}
+
+	program, err := parseInput(r.out, path, source)
+	if err != nil {
+		return
+	}
+
+	if evalError, ok := r.eval.EvalModule(program, path, r.ctx).(*types.Error); ok {
+		logEvalErrors(r.out, path, evalError)
+	}
+}
+
+func (r *repl) ast(source string) {
+	program, err := parseInput(r.out, "", source)
+	if err != nil {
+		return
+	}
+
+	dumpNode(r.out, reflect.ValueOf(program), "")
+}
+
+func (r *repl) time(source string) {
+	start := time.Now()
+	r.evalInput(source)
+	fmt.Fprintf(r.out, "took %s\n", time.Since(start))
+}
+
+func (r *repl) typeOf(source string) {
+	program, err := parseInput(r.out, "", source)
+	if err != nil {
+		return
+	}
+
+	globals := map[string]types.Object{}
+	for _, name := range r.ctx.Names() {
+		globals[name], _ = r.ctx.Get(name)
+	}
+
+	t, errs := checker.Infer(program, globals)
+	for _, e := range errs {
+		fmt.Fprintln(r.out, e.Error())
+	}
+	if len(errs) == 0 {
+		fmt.Fprintln(r.out, t.String())
+	}
+}
@@ -0,0 +1,63 @@
+package interpreter
+
+import (
+	"Simply/lexer"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// complete returns the names that can complete the word before the cursor and
+// where that word starts. Keywords, builtins and session bindings are offered,
+// meta-commands at the start of the line.
+func (r *repl) complete(before []rune) ([]string, int) {
+	start := len(before)
+	for start > 0 && isNameRune(before[start-1]) {
+		start--
+	}
+	word := string(before[start:])
+
+	var names []string
+	if strings.TrimSpace(string(before[:start])) == commandPrefix {
+		for _, c := range replCommands {
+			names = append(names, c.name)
+		}
+	} else {
+		if word == "" {
+			return nil, start
+		}
+		names = append(names, lexer.Keywords()...)
+		names = append(names, r.eval.Builtins.Names()...)
+		names = append(names, r.ctx.Names()...)
+	}
+
+	seen := map[string]bool{}
+	var candidates []string
+	for _, name := range names {
+		if strings.HasPrefix(name, word) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+
+	return candidates, start
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,82 @@
+package interpreter
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	r := &repl{in: bufio.NewReader(strings.NewReader("")), out: io.Discard}
+	r.reset()
+	r.evalInput("let reduced = 1; let total = 2;")
+
+	tests := []struct {
+		before     string
+		candidates []string
+		start      int
+	}{
+		{"re", []string{"reduce", "reduced", "return"}, 0},
+		{"let x = to", []string{"total"}, 8},
+		{"print(le", []string{"len", "let"}, 6},
+		{":ty", []string{"type"}, 1},
+		{"x + ", nil, 4},
+		{"zzz", nil, 0},
+	}
+
+	for _, tt := range tests {
+		candidates, start := r.complete([]rune(tt.before))
+		if !reflect.DeepEqual(candidates, tt.candidates) || start != tt.start {
+			t.Errorf("%q: expected %v at %d, got %v at %d", tt.before, tt.candidates, tt.start, candidates, start)
+		}
+	}
+}
+
+func TestLineEditorCompletion(t *testing.T) {
+	complete := func(before []rune) ([]string, int) {
+		return []string{"reduce", "reduced"}, 0
+	}
+
+	tests := []struct {
+		keys     string
+		expected string
+		output   string
+	}{
+		{"r\t\r", "reduce", ""},
+		{"reduce\t\r", "reduce", "\nreduce  reduced\n"},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		l := newLineEditor(bufio.NewReader(strings.NewReader(tt.keys)), &out, &history{})
+		l.complete = complete
+		line, err := l.readLine(">>>")
+		if err != nil || line != tt.expected {
+			t.Errorf("%q: expected %q, got %q %v", tt.keys, tt.expected, line, err)
+		}
+		if !strings.Contains(out.String(), tt.output) {
+			t.Errorf("%q: expected output to contain %q, got %q", tt.keys, tt.output, out.String())
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;", colorKeyword + "let" + colorReset + " x = " + colorNumber + "5" + colorReset + ";"},
+		{`print("a b", 1.5)`, `print(` + colorString + `"a b"` + colorReset + ", " + colorNumber + "1.5" + colorReset + ")"},
+		{`"open`, colorString + `"open` + colorReset},
+		{"if (true) {", colorKeyword + "if" + colorReset + " (" + colorKeyword + "true" + colorReset + ") {"},
+		{"héllo", "héllo"},
+	}
+
+	for _, tt := range tests {
+		if got := highlight(tt.input); got != tt.expected {
+			t.Errorf("highlight(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
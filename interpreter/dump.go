@@ -0,0 +1,58 @@
+package interpreter
+
+import (
+	"Simply/ast"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+var positionType = reflect.TypeOf(ast.Position{})
+
+// dumpNode writes a node as its type and position followed by its fields, one per line.
+func dumpNode(out io.Writer, v reflect.Value, indent string) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			fmt.Fprintln(out, "nil")
+			return
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		fmt.Fprintf(out, "%#v\n", v.Interface())
+		return
+	}
+
+	if pos := v.FieldByName("Position"); pos.IsValid() && pos.Type() == positionType {
+		fmt.Fprintf(out, "%s %s\n", v.Type().Name(), pos.Interface().(ast.Position).String())
+	} else {
+		fmt.Fprintln(out, v.Type().Name())
+	}
+
+	indent += "  "
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type == positionType {
+			continue
+		}
+
+		fmt.Fprintf(out, "%s%s:", indent, field.Name)
+		value := v.Field(i)
+		if value.Kind() != reflect.Slice {
+			fmt.Fprint(out, " ")
+			dumpNode(out, value, indent)
+			continue
+		}
+
+		if value.Len() == 0 {
+			fmt.Fprintln(out, " []")
+			continue
+		}
+		fmt.Fprintln(out)
+		for j := 0; j < value.Len(); j++ {
+			fmt.Fprintf(out, "%s  - ", indent)
+			dumpNode(out, value.Index(j), indent+"  ")
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package interpreter
+
+import (
+	"Simply/printer"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FormatMode selects what FormatFile does with the formatted source.
+type FormatMode int
+
+const (
+	FormatPrint FormatMode = iota //Write the formatted source to Out
+	FormatWrite                   //Replace the file with the formatted source
+	FormatCheck                   //Only report files that are not formatted
+)
+
+// FormatFile formats the script at path, "-" reads it from In. In check mode it
+// reports whether the file was already formatted, otherwise whether it parsed.
+func FormatFile(path string, mode FormatMode, s Streams) bool {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(s.In)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(s.Err, "Error reading file:", err)
+		return false
+	}
+
+	source := string(content)
+	shebang := ""
+	if strings.HasPrefix(source, "#!") {
+		shebang, source, _ = strings.Cut(source, "\n")
+		shebang += "\n"
+	}
+
+	formatted, err := printer.Format(source)
+	if err != nil {
+		fmt.Fprintf(s.Err, "%s: %s\n", path, err)
+		return false
+	}
+	formatted = shebang + formatted
+
+	switch mode {
+	case FormatCheck:
+		if formatted != string(content) {
+			fmt.Fprintln(s.Out, path)
+			return false
+		}
+	case FormatWrite:
+		if path == "-" {
+			fmt.Fprint(s.Out, formatted)
+		} else if formatted != string(content) {
+			if err := writeFile(path, formatted); err != nil {
+				fmt.Fprintln(s.Err, "Error writing file:", err)
+				return false
+			}
+		}
+	default:
+		fmt.Fprint(s.Out, formatted)
+	}
+
+	return true
+}
+
+// writeFile replaces the content of an existing file, keeping its permissions.
+func writeFile(path, content string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), info.Mode().Perm())
+}
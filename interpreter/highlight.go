@@ -0,0 +1,63 @@
+package interpreter
+
+import (
+	"Simply/lexer"
+	"strings"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorKeyword = "\x1b[35m"
+	colorString  = "\x1b[32m"
+	colorNumber  = "\x1b[33m"
+)
+
+// highlight colours the keywords, strings and numbers of a single line of input.
+// Text the tokenizer skips, such as spaces, is copied unchanged.
+func highlight(line string) string {
+	var sb strings.Builder
+	last := 0
+
+	t := lexer.NewTokenizer(line)
+	for tok := t.NextToken(); tok.Type != lexer.EOF; tok = t.NextToken() {
+		start := tok.Col - 1
+		end := tokenEnd(line, start, tok)
+
+		color := ""
+		switch {
+		case tok.Type == lexer.STRING:
+			color = colorString
+		case tok.Type == lexer.INT || tok.Type == lexer.FLOAT:
+			color = colorNumber
+		case lexer.GetType(tok.Literal) != lexer.IDENTIFIER:
+			color = colorKeyword
+		}
+
+		sb.WriteString(line[last:start])
+		if color == "" {
+			sb.WriteString(line[start:end])
+		} else {
+			sb.WriteString(color + line[start:end] + colorReset)
+		}
+		last = end
+	}
+	sb.WriteString(line[last:])
+
+	return sb.String()
+}
+
+// tokenEnd returns the offset after the token, string literals include their quotes.
+func tokenEnd(line string, start int, tok lexer.Token) int {
+	switch tok.Type {
+	case lexer.ILLEGAL:
+		return start + 1 //A single byte, its literal may be longer once converted to a rune
+	case lexer.STRING:
+		end := start + 1 + len(tok.Literal)
+		if end < len(line) {
+			end++ //Closing quote
+		}
+		return min(end, len(line))
+	}
+
+	return min(start+len(tok.Literal), len(line))
+}
@@ -0,0 +1,73 @@
+package interpreter
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+const maxHistory = 1000
+
+// history holds the lines entered in the REPL, the newest last, and appends them
+// to its file so they are available in the next session.
+type history struct {
+	entries []string
+	path    string
+	lines   int //Lines in the file, which is compacted to the entries at twice maxHistory
+}
+
+// loadHistory reads the history file at path, an empty path keeps history in memory only.
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.entries = append(h.entries, scanner.Text())
+	}
+	h.lines = len(h.entries)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+
+	return h
+}
+
+// add records the line unless it is blank or repeats the previous entry.
+func (h *history) add(line string) {
+	if strings.TrimSpace(line) == "" || len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+
+	if h.path == "" {
+		return
+	}
+	//History is a convenience, failing to save it is not worth interrupting the session
+	if h.lines >= 2*maxHistory {
+		if os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600) == nil {
+			h.lines = len(h.entries)
+		}
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err == nil {
+		h.lines++
+	}
+}
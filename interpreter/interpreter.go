@@ -2,97 +2,474 @@ package interpreter
 
 import (
 	"Simply/ast"
+	"Simply/checker"
+	"Simply/coverage"
+	"Simply/debugger"
 	"Simply/evaluator"
 	"Simply/lexer"
+	"Simply/lint"
 	"Simply/parser"
+	"Simply/profiler"
+	"Simply/tracer"
 	"Simply/types"
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 )
 
-const promtd = ">>>"
+// searchPathEnv lists extra directories, separated like PATH, searched for imports.
+const searchPathEnv = "SIMPLY_PATH"
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	globalCtx := types.NewContext(nil)
-	for {
-		fmt.Fprint(out, promtd)
+func newEvaluator() *evaluator.Evaluator {
+	e := evaluator.New()
+	e.SearchPath = filepath.SplitList(os.Getenv(searchPathEnv))
+	return e
+}
+
+// Streams are the standard streams of a command, diagnostics are written to Err.
+type Streams struct {
+	In       io.Reader
+	Out, Err io.Writer
+}
+
+// ProcessFile runs the script at path, "-" reads it from In. The script sees args
+// in the args builtin. It reports whether the script ran without errors.
+func ProcessFile(path string, args []string, s Streams) bool {
+	scriptText, err := readSource(path, s)
+	if err != nil {
+		return false
+	}
+
+	return runSource(path, scriptText, args, false, s)
+}
+
+// ProfileFile runs the script at path like ProcessFile while measuring where it
+// spends its time. The top functions and lines are written to Err and a pprof
+// profile to profilePath.
+func ProfileFile(path string, args []string, profilePath string, top int, s Streams) bool {
+	scriptText, err := readSource(path, s)
+	if err != nil {
+		return false
+	}
+
+	program, err := parseInput(s.Err, path, scriptText)
+	if err != nil {
+		return false
+	}
+
+	p := profiler.New()
+	ok := runProgram(path, program, args, false, p, s)
+	p.Stop()
+
+	if err := p.WriteTable(s.Err, top); err != nil {
+		fmt.Fprintln(s.Err, "Error writing profile:", err)
+		return false
+	}
+
+	if err := createFile(profilePath, p.WritePprof); err != nil {
+		fmt.Fprintln(s.Err, "Error writing profile:", err)
+		return false
+	}
+
+	return ok
+}
+
+// TraceFile runs the script at path like ProcessFile while writing its statements,
+// calls and returns to Err. When functions are given only those are traced.
+func TraceFile(path string, args []string, functions []string, s Streams) bool {
+	scriptText, err := readSource(path, s)
+	if err != nil {
+		return false
+	}
+
+	program, err := parseInput(s.Err, path, scriptText)
+	if err != nil {
+		return false
+	}
+
+	return runProgram(path, program, args, false, tracer.New(s.Err, functions...), s)
+}
+
+// CoverageReports are the files coverage reports are written to, empty ones are skipped.
+type CoverageReports struct {
+	LCOV string
+	HTML string
+}
+
+// CoverFile runs the script at path like ProcessFile while recording which of
+// its statements and branches run. A summary is written to Err and the other
+// reports to their files.
+func CoverFile(path string, args []string, reports CoverageReports, s Streams) bool {
+	scriptText, err := readSource(path, s)
+	if err != nil {
+		return false
+	}
+
+	program, err := parseInput(s.Err, path, scriptText)
+	if err != nil {
+		return false
+	}
+
+	c := coverage.New()
+	ok := runProgram(path, program, args, false, c, s)
+	return writeCoverage(c, reports, s) && ok
+}
+
+func writeCoverage(c *coverage.Coverage, reports CoverageReports, s Streams) bool {
+	if err := c.WriteSummary(s.Err); err != nil {
+		fmt.Fprintln(s.Err, "Error writing coverage:", err)
+		return false
+	}
 
-		if ok := scanner.Scan(); !ok {
+	for _, report := range []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{reports.LCOV, c.WriteLCOV},
+		{reports.HTML, c.WriteHTML},
+	} {
+		if report.path == "" {
 			continue
 		}
+		if err := createFile(report.path, report.write); err != nil {
+			fmt.Fprintln(s.Err, "Error writing coverage:", err)
+			return false
+		}
+	}
+	return true
+}
+
+// createFile creates the file at path with the content written by write.
+func createFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// EvalString runs the source and prints the value of its last statement unless it is null.
+func EvalString(source string, args []string, s Streams) bool {
+	return runSource("-", source, args, true, s)
+}
+
+// ProcessAST runs the syntax tree written by PrintAST in JSON, "-" reads it from In.
+func ProcessAST(path string, args []string, s Streams) bool {
+	data, err := readSource(path, s)
+	if err != nil {
+		return false
+	}
+
+	node, err := ast.FromJSON([]byte(data))
+	if err != nil {
+		fmt.Fprintf(s.Err, "%s: invalid syntax tree: %v\n", path, err)
+		return false
+	}
+	program, ok := node.(*ast.Program)
+	if !ok {
+		fmt.Fprintf(s.Err, "%s: invalid syntax tree: expected Program, got %T\n", path, node)
+		return false
+	}
+
+	return runProgram(path, program, args, false, nil, s)
+}
+
+func runSource(path, source string, args []string, printResult bool, s Streams) bool {
+	program, err := parseInput(s.Err, path, source)
+	if err != nil {
+		return false
+	}
+
+	return runProgram(path, program, args, printResult, nil, s)
+}
+
+// runProgram evaluates the program, hook observes the evaluation when it is not nil.
+func runProgram(path string, program *ast.Program, args []string, printResult bool, hook evaluator.Hook, s Streams) bool {
+	ctx := types.NewContext(nil)
+	e := newEvaluator()
+	e.SetIO(s.In, s.Out)
+	e.Builtins.Register("args", stringArray(args))
+	if hook != nil {
+		e.SetHook(hook)
+	}
+
+	var evalResult types.Object
+	if path == "-" {
+		evalResult = e.Eval(program, ctx)
+	} else {
+		evalResult = e.EvalModule(program, path, ctx)
+	}
+
+	if evalError, isError := evalResult.(*types.Error); isError {
+		logEvalErrors(s.Err, path, evalError)
+		return false
+	}
+
+	if printResult && evalResult != nil && evalResult != types.NULL {
+		fmt.Fprintln(s.Out, evalResult.String())
+	}
+
+	return true
+}
+
+func stringArray(values []string) *types.Array {
+	arr := &types.Array{Elements: make([]types.Object, len(values))}
+	for i, v := range values {
+		arr.Elements[i] = &types.String{Value: v}
+	}
+	return arr
+}
 
-		program, err := parseInput(out, scanner.Text())
+// DebugFile runs the script at path under the command line debugger, which reads
+// its commands from In like the script does.
+func DebugFile(path string, args []string, s Streams) bool {
+	scriptText, err := readSource(path, s)
+	if err != nil {
+		return false
+	}
 
+	program, err := parseInput(s.Err, path, scriptText)
+	if err != nil {
+		return false
+	}
+
+	in := bufio.NewReader(s.In)
+	e := newEvaluator()
+	e.SetIO(in, s.Out)
+	e.Builtins.Register("args", stringArray(args))
+
+	result, finished := debugger.NewCLI(in, s.Out).Run(e, program, path, types.NewContext(nil))
+	if evalError, isError := result.(*types.Error); isError && finished {
+		logEvalErrors(s.Err, path, evalError)
+		return false
+	}
+
+	return true
+}
+
+// ServeDAP runs a debug adapter on In and Out, the scripts the client launches
+// get no input and their output is sent to the client.
+func ServeDAP(s Streams) bool {
+	err := debugger.ServeDAP(s.In, s.Out, func(path string, args []string, out io.Writer) (*evaluator.Evaluator, *ast.Program, error) {
+		content, err := os.ReadFile(path)
 		if err != nil {
-			fmt.Println(out, err)
-			continue
+			return nil, nil, err
 		}
 
-		evalResult := evaluator.Eval(program, globalCtx)
-
-		if evalResult != nil {
-			fmt.Fprintln(out, evalResult.String())
+		var parseErrors strings.Builder
+		program, err := parseInput(&parseErrors, path, stripShebang(string(content)))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", path, strings.TrimSpace(parseErrors.String()))
 		}
 
+		e := newEvaluator()
+		e.SetIO(strings.NewReader(""), out)
+		e.Builtins.Register("args", stringArray(args))
+		return e, program, nil
+	})
+	if err != nil {
+		fmt.Fprintln(s.Err, "debug:", err)
+		return false
 	}
+	return true
 }
 
-func ProcessFile(path string, out io.Writer) {
-	scriptText, err := readFile(path)
+// CheckFile type checks the script without evaluating it and reports whether it passed.
+func CheckFile(path string, s Streams) bool {
+	scriptText, err := readSource(path, s)
 	if err != nil {
-		return
+		return false
 	}
 
-	program, err := parseInput(out, scriptText)
+	program, err := parseInput(s.Err, path, scriptText)
 	if err != nil {
-		fmt.Println(out, err)
-		return
+		return false
 	}
 
-	ctx := types.NewContext(nil)
+	errs := checker.Check(program)
+	for _, e := range errs {
+		fmt.Fprintf(s.Err, "%s:%s\n", path, e.Error())
+	}
+
+	return len(errs) == 0
+}
+
+// LintFile reports the issues the linter finds in the script. The configuration
+// is read from configPath, or found next to the script when it is empty.
+func LintFile(path, configPath string, s Streams) bool {
+	var config *lint.Config
+	var err error
+	switch {
+	case configPath != "":
+		config, err = lint.LoadConfig(configPath)
+	case path == "-":
+		config, err = lint.FindConfig(".")
+	default:
+		config, err = lint.FindConfig(filepath.Dir(path))
+	}
+	if err != nil {
+		fmt.Fprintln(s.Err, "Error reading lint configuration:", err)
+		return false
+	}
+
+	scriptText, err := readSource(path, s)
+	if err != nil {
+		return false
+	}
+
+	program, err := parseInput(s.Err, path, scriptText)
+	if err != nil {
+		return false
+	}
+
+	diagnostics := lint.Lint(program, config)
+	for _, d := range diagnostics {
+		fmt.Fprintf(s.Err, "%s:%s\n", path, d.Error())
+	}
+
+	return len(diagnostics) == 0
+}
 
-	evalResult := evaluator.Eval(program, ctx)
+// PrintTokens writes the tokens of the script, one per line with their position,
+// or as a JSON array of tokens when asJSON is set.
+func PrintTokens(path string, asJSON bool, s Streams) bool {
+	scriptText, err := readSource(path, s)
+	if err != nil {
+		return false
+	}
 
-	evalError, isError := evalResult.(*types.Error)
+	if asJSON {
+		return writeTokensJSON(s, scriptText)
+	}
+	writeTokens(s.Out, scriptText)
+	return true
+}
 
-	if isError {
-		logEvalErrors(evalError)
+func writeTokens(out io.Writer, source string) {
+	t := lexer.NewTokenizer(source)
+	for {
+		tok := t.NextToken()
+		fmt.Fprintf(out, "%d:%d\t%s\t%q\n", tok.Row, tok.Col, tok.Type, tok.Literal)
+		if tok.Type == lexer.EOF {
+			return
+		}
 	}
 }
 
-func readFile(path string) (string, error) {
-	content, err := os.ReadFile(path)
+// writeTokensJSON writes the tokens as a JSON array with one token per line.
+func writeTokensJSON(s Streams, source string) bool {
+	t := lexer.NewTokenizer(source)
+	fmt.Fprintln(s.Out, "[")
+	for {
+		tok := t.NextToken()
+		data, err := json.Marshal(tok)
+		if err != nil {
+			fmt.Fprintln(s.Err, "Error encoding tokens:", err)
+			return false
+		}
+
+		if tok.Type == lexer.EOF {
+			fmt.Fprintf(s.Out, "  %s\n]\n", data)
+			return true
+		}
+		fmt.Fprintf(s.Out, "  %s,\n", data)
+	}
+}
+
+// PrintAST writes the syntax tree of the script, as JSON when asJSON is set.
+func PrintAST(path string, asJSON bool, s Streams) bool {
+	scriptText, err := readSource(path, s)
 	if err != nil {
-		fmt.Println("Error reading file:", err)
+		return false
+	}
+
+	program, err := parseInput(s.Err, path, scriptText)
+	if err != nil {
+		return false
+	}
+
+	if !asJSON {
+		dumpNode(s.Out, reflect.ValueOf(program), "")
+		return true
+	}
+
+	data, err := ast.ToJSON(program)
+	if err != nil {
+		fmt.Fprintln(s.Err, "Error encoding syntax tree:", err)
+		return false
+	}
+	fmt.Fprintf(s.Out, "%s\n", data)
+	return true
+}
+
+// readSource reads the script at path, or In for "-", and blanks a leading shebang line.
+func readSource(path string, s Streams) (string, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(s.In)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(s.Err, "Error reading file:", err)
 		return "", err
 	}
 
-	return string(content), err
+	return stripShebang(string(content)), nil
 }
 
-func parseInput(out io.Writer, input string) (*ast.Program, error) {
+// stripShebang keeps the newline so positions still match the file
+func stripShebang(source string) string {
+	if !strings.HasPrefix(source, "#!") {
+		return source
+	}
+	if idx := strings.IndexByte(source, '\n'); idx >= 0 {
+		return source[idx:]
+	}
+	return ""
+}
+
+// parseInput parses the source read from path, the errors are written to out.
+func parseInput(out io.Writer, path, input string) (*ast.Program, error) {
 	t := lexer.NewTokenizer(input)
 	p := parser.NewParser(t)
 	parseResult := p.ParseProgram()
 
 	if len(p.Errors) > 0 {
-		logParseErrors(out, p)
+		logParseErrors(out, path, p)
 		return nil, errors.New("failed to parse")
 	}
 
 	return parseResult, nil
 }
 
-func logParseErrors(out io.Writer, p *parser.Parser) {
-	for _, e := range p.Errors {
-		fmt.Fprintln(out, e)
+// logParseErrors writes the errors as path:row:col: message, or row:col: message
+// without a path, like for the lines entered in the REPL.
+func logParseErrors(out io.Writer, path string, p *parser.Parser) {
+	for i, e := range p.Errors {
+		pos := p.ErrorPositions[i].String()
+		if path != "" {
+			pos = path + ":" + pos
+		}
+		fmt.Fprintf(out, "%s: %s\n", pos, e)
 	}
 }
 
-func logEvalErrors(e *types.Error) {
-	fmt.Println(e.String())
+func logEvalErrors(out io.Writer, path string, e *types.Error) {
+	if e.Position.Row == 0 {
+		fmt.Fprintf(out, "%s: %s\n", path, e.String())
+		return
+	}
+	fmt.Fprintf(out, "%s:%s: %s\n", path, e.Position.String(), e.String())
 }
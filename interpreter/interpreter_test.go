@@ -0,0 +1,333 @@
+package interpreter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEvalString(t *testing.T) {
+	tests := []struct {
+		input    string
+		args     []string
+		ok       bool
+		expected string
+	}{
+		{"1 + 2", nil, true, "3\n"},
+		{`println("hi");`, nil, true, "hi\n"},
+		{"len(args)", []string{"a", "b"}, true, "2\n"},
+		{"let = 1", nil, false, "-:1:5: Invalid token: expected IDENTIFIER, got =\n-:1:5: Missing prefix parser for =\n"},
+		{"1 + true", nil, false, "-:1:1: Unknown inflix operation 1 + true\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		ok := EvalString(tt.input, tt.args, Streams{In: strings.NewReader(""), Out: &out, Err: &out})
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t", tt.input, tt.ok, ok)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s: expected output %q, got %q", tt.input, tt.expected, out.String())
+		}
+	}
+}
+
+func TestProcessFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sy")
+	script := "#!/usr/bin/env simply\nprintln(args[0]);\nlet x = y;\n"
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	ok := ProcessFile(path, []string{"first"}, Streams{In: strings.NewReader(""), Out: &out, Err: &errOut})
+	if ok {
+		t.Error("expected the script to fail")
+	}
+	if out.String() != "first\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if expected := path + ":3:9: identifier not found: y\n"; errOut.String() != expected {
+		t.Errorf("expected error %q, got %q", expected, errOut.String())
+	}
+
+	out.Reset()
+	if !ProcessFile("-", nil, Streams{In: strings.NewReader(`println("stdin");`), Out: &out, Err: &out}) {
+		t.Errorf("expected script from stdin to run, got %q", out.String())
+	}
+	if out.String() != "stdin\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sy")
+	if err := os.WriteFile(path, []byte("#!/usr/bin/env simply\nlet x=1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s := Streams{In: strings.NewReader(""), Out: &out, Err: &out}
+	if FormatFile(path, FormatCheck, s) || out.String() != path+"\n" {
+		t.Errorf("expected check to report the file, got %q", out.String())
+	}
+
+	if !FormatFile(path, FormatWrite, s) {
+		t.Fatal("expected the file to be formatted")
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "#!/usr/bin/env simply\nlet x = 1;\n" {
+		t.Errorf("unexpected formatted file %q", content)
+	}
+
+	out.Reset()
+	if !FormatFile(path, FormatCheck, s) || out.String() != "" {
+		t.Errorf("expected a formatted file to pass the check, got %q", out.String())
+	}
+
+	out.Reset()
+	if FormatFile("-", FormatPrint, Streams{In: strings.NewReader("let = 1"), Out: &out, Err: &out}) {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.sy")
+	if err := os.WriteFile(path, []byte("let x = 1;\nprintln(x + len(args));\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	s := Streams{In: strings.NewReader(""), Out: &out, Err: &errOut}
+	if !PrintTokens(path, true, s) {
+		t.Fatalf("expected tokens to be printed, got %q", errOut.String())
+	}
+	if lines := strings.Split(out.String(), "\n"); lines[1] != `  {"type":"LET","literal":"let","row":1,"col":1},` ||
+		lines[len(lines)-3] != `  {"type":"EOF","literal":"","row":3,"col":1}` {
+		t.Errorf("unexpected tokens %q", out.String())
+	}
+
+	out.Reset()
+	if !PrintAST(path, true, s) {
+		t.Fatalf("expected the syntax tree to be printed, got %q", errOut.String())
+	}
+	treePath := filepath.Join(dir, "script.json")
+	if err := os.WriteFile(treePath, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if !ProcessAST(treePath, []string{"a"}, s) || out.String() != "2\n" {
+		t.Errorf("expected the syntax tree to run, got %q %q", out.String(), errOut.String())
+	}
+
+	errOut.Reset()
+	s.In = strings.NewReader(`{"kind": "IntLiteral", "value": 1}`)
+	if ProcessAST("-", nil, s) || errOut.String() != "-: invalid syntax tree: expected Program, got *ast.IntLiteral\n" {
+		t.Errorf("expected a program to be required, got %q", errOut.String())
+	}
+}
+
+func TestLintFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.sy")
+	if err := os.WriteFile(path, []byte("func f(a) { 1 }\nf(1, 2);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s := Streams{In: strings.NewReader(""), Out: &out, Err: &out}
+	if LintFile(path, "", s) {
+		t.Error("expected lint issues")
+	}
+	expected := path + ":1:8: parameter a is never used (unused-parameter)\n" +
+		path + ":2:1: wrong number of arguments in call to f: got 2, want 1 (arity)\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".simplylint.json"), []byte(`{"disable": ["unused-parameter", "arity"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if !LintFile(path, "", s) {
+		t.Errorf("expected the project configuration to disable the rules, got %q", out.String())
+	}
+}
+
+func TestDebugFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sy")
+	script := "#!/usr/bin/env simply\nlet name = input();\nprintln(name + args[0]);\nlet x = y;\n"
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	//The script reads its input between the commands
+	var out, errOut bytes.Buffer
+	ok := DebugFile(path, []string{"!"}, Streams{In: strings.NewReader("n\nworld\np name\nc\n"), Out: &out, Err: &errOut})
+	if ok {
+		t.Error("expected the script to fail")
+	}
+
+	expected := "entry at script.sy:2\n=>   2\tlet name = input();\n(debug) " +
+		"step at script.sy:3\n=>   3\tprintln(name + args[0]);\n(debug) \"world\"\n(debug) world!\n"
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+	if expected := path + ":4:9: identifier not found: y\n"; errOut.String() != expected {
+		t.Errorf("expected error %q, got %q", expected, errOut.String())
+	}
+
+	out.Reset()
+	errOut.Reset()
+	if !DebugFile(path, nil, Streams{In: strings.NewReader("q\n"), Out: &out, Err: &errOut}) || errOut.Len() != 0 {
+		t.Errorf("expected quitting to succeed, got %q", errOut.String())
+	}
+}
+
+func TestProfileFile(t *testing.T) {
+	dir := t.TempDir()
+	path, profilePath := filepath.Join(dir, "script.sy"), filepath.Join(dir, "out.pprof")
+	script := "func double(x) {\n  x + x\n}\nprintln(double(args[0]));\n"
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if !ProfileFile(path, []string{"21"}, profilePath, 5, Streams{In: strings.NewReader(""), Out: &out, Err: &errOut}) {
+		t.Fatalf("expected the script to succeed, got %q", errOut.String())
+	}
+	if out.String() != "2121\n" {
+		t.Errorf("expected the output of the script, got %q", out.String())
+	}
+	for _, expected := range []string{"Total: ", "calls  function", "count  line", "script.sy:2 double"} {
+		if !strings.Contains(errOut.String(), expected) {
+			t.Errorf("expected the table to contain %q, got %q", expected, errOut.String())
+		}
+	}
+	if info, err := os.Stat(profilePath); err != nil || info.Size() == 0 {
+		t.Errorf("expected a profile to be written, got %v", err)
+	}
+
+	//The table is still written when the script fails
+	errOut.Reset()
+	if ProfileFile(path, nil, profilePath, 5, Streams{In: strings.NewReader(""), Out: &out, Err: &errOut}) {
+		t.Error("expected the script to fail")
+	}
+	if !strings.Contains(errOut.String(), "Total: ") {
+		t.Errorf("expected the table, got %q", errOut.String())
+	}
+}
+
+func TestCoverFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.sy")
+	lcov, html := filepath.Join(dir, "out.lcov"), filepath.Join(dir, "out.html")
+	script := "if (args[0] == \"a\") {\n  println(\"a\");\n} else {\n  println(\"b\");\n}\n"
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	reports := CoverageReports{LCOV: lcov, HTML: html}
+	if !CoverFile(path, []string{"a"}, reports, Streams{In: strings.NewReader(""), Out: &out, Err: &errOut}) {
+		t.Fatalf("expected the script to succeed, got %q", errOut.String())
+	}
+	if out.String() != "a\n" {
+		t.Errorf("expected the output of the script, got %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "2/3   66.7%") || !strings.Contains(errOut.String(), "1/2   50.0%") {
+		t.Errorf("expected a summary, got %q", errOut.String())
+	}
+	if content, err := os.ReadFile(lcov); err != nil || !strings.Contains(string(content), "SF:"+path+"\n") {
+		t.Errorf("expected an LCOV report, got %q and %v", content, err)
+	}
+	if content, err := os.ReadFile(html); err != nil || !strings.Contains(string(content), `<tr class="uncovered"`) {
+		t.Errorf("expected an HTML report, got %v", err)
+	}
+
+	//The summary is still written when the script fails
+	errOut.Reset()
+	if CoverFile(path, nil, CoverageReports{}, Streams{In: strings.NewReader(""), Out: &out, Err: &errOut}) {
+		t.Error("expected the script to fail")
+	}
+	if !strings.Contains(errOut.String(), "1/3   33.3%       0/2    0.0%") {
+		t.Errorf("expected a summary, got %q", errOut.String())
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"lib.syn":      "export func double(n) {\n  if (n > 0) { n * 2 } else { 0 }\n}\n",
+		"lib_test.syn": "import \"lib.syn\";\nfunc test_double() { assert_eq(lib.double(2), 4) }\nfunc test_wrong() { assert_eq(lib.double(1), 3) }\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out, errOut bytes.Buffer
+	s := Streams{In: strings.NewReader(""), Out: &out, Err: &errOut}
+	if RunTests([]string{dir}, TestOptions{}, s) {
+		t.Error("expected a test to fail")
+	}
+	file := filepath.Join(dir, "lib_test.syn")
+	for _, expected := range []string{"--- FAIL: " + file + ": test_wrong", file + ":3:21: assert_eq failed", "FAIL  1 of 2 tests failed in 1 file"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected the report to contain %q, got %q", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	lcov := filepath.Join(dir, "out.lcov")
+	opts := TestOptions{Run: "double$", Format: "tap", Cover: true, Coverage: CoverageReports{LCOV: lcov}}
+	if !RunTests([]string{dir}, opts, s) {
+		t.Errorf("expected the selected test to pass, got %q", errOut.String())
+	}
+	if !strings.HasPrefix(out.String(), "TAP version 13\n1..1\nok 1 - ") {
+		t.Errorf("unexpected TAP report %q", out.String())
+	}
+	//Only the code under test is covered
+	if !strings.Contains(errOut.String(), "lib.syn") || strings.Contains(errOut.String(), "lib_test.syn") {
+		t.Errorf("expected the coverage of lib.syn, got %q", errOut.String())
+	}
+	if content, err := os.ReadFile(lcov); err != nil || !strings.Contains(string(content), "BRDA:2,0,0,1\nBRDA:2,0,1,0\n") {
+		t.Errorf("expected an LCOV report, got %q and %v", content, err)
+	}
+
+	errOut.Reset()
+	if RunTests([]string{dir}, TestOptions{Run: "("}, s) || !strings.Contains(errOut.String(), "Invalid test filter") {
+		t.Errorf("expected an invalid filter to fail, got %q", errOut.String())
+	}
+}
+
+func TestTraceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sy")
+	script := "func inc(n) { n + 1 }\nprintln(inc(1));\n"
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if !TraceFile(path, nil, nil, Streams{In: strings.NewReader(""), Out: &out, Err: &errOut}) {
+		t.Fatalf("expected the script to succeed, got %q", errOut.String())
+	}
+	expected := "script.sy:1:1: func inc(n) { n + 1 }\n" +
+		"script.sy:2:1: println(inc(1))\n" +
+		"script.sy:2:1: call inc(1)\n" +
+		"  script.sy:1:15: n + 1\n" +
+		"script.sy:2:1: return inc = 2\n"
+	if out.String() != "2\n" || errOut.String() != expected {
+		t.Errorf("expected output 2 and the trace %q, got %q and %q", expected, out.String(), errOut.String())
+	}
+
+	errOut.Reset()
+	TraceFile(path, nil, []string{"other"}, Streams{In: strings.NewReader(""), Out: &out, Err: &errOut})
+	if errOut.Len() != 0 {
+		t.Errorf("expected nothing traced, got %q", errOut.String())
+	}
+}
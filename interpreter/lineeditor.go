@@ -0,0 +1,245 @@
+package interpreter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errInterrupted is returned when the line is abandoned with Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// lineEditor reads a line from a terminal in raw mode, echoing and redrawing it
+// as it is edited. It supports the arrow keys, Home/End, Delete and the usual
+// Emacs bindings, Up and Down walk through the history and Tab completes words.
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history *history
+
+	//Optional, complete returns the candidates for the word ending before the cursor
+	//and the index it starts at, highlight decorates the line when it is drawn
+	complete  func(before []rune) ([]string, int)
+	highlight func(line string) string
+
+	prompt string
+	line   []rune
+	pos    int
+}
+
+func newLineEditor(in *bufio.Reader, out io.Writer, h *history) *lineEditor {
+	return &lineEditor{in: in, out: out, history: h}
+}
+
+func (l *lineEditor) readLine(prompt string) (string, error) {
+	l.prompt, l.line, l.pos = prompt, nil, 0
+	l.refresh()
+
+	//index into the history, the line being written is kept while browsing it
+	index := len(l.history.entries)
+	var pending []rune
+
+	for {
+		r, _, err := l.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, '\n':
+			line := string(l.line)
+			fmt.Fprint(l.out, "\n")
+			l.history.add(line)
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(l.out, "^C\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(l.line) == 0 {
+				return "", io.EOF
+			}
+			l.deleteAt(l.pos)
+		case keyBackspace, keyDelete:
+			if l.pos > 0 {
+				l.pos--
+				l.deleteAt(l.pos)
+			}
+		case keyCtrlA:
+			l.pos = 0
+		case keyCtrlE:
+			l.pos = len(l.line)
+		case keyCtrlB:
+			l.moveBy(-1)
+		case keyCtrlF:
+			l.moveBy(1)
+		case keyCtrlK:
+			l.line = l.line[:l.pos]
+		case keyCtrlU:
+			l.line = l.line[l.pos:]
+			l.pos = 0
+		case keyTab:
+			l.completeWord()
+		case keyCtrlL:
+			fmt.Fprint(l.out, "\x1b[H\x1b[2J")
+		case keyCtrlP:
+			index, pending = l.browse(index-1, index, pending)
+		case keyCtrlN:
+			index, pending = l.browse(index+1, index, pending)
+		case keyEscape:
+			switch l.readEscape() {
+			case 'A':
+				index, pending = l.browse(index-1, index, pending)
+			case 'B':
+				index, pending = l.browse(index+1, index, pending)
+			case 'C':
+				l.moveBy(1)
+			case 'D':
+				l.moveBy(-1)
+			case 'H':
+				l.pos = 0
+			case 'F':
+				l.pos = len(l.line)
+			case '3':
+				l.deleteAt(l.pos)
+			}
+		default:
+			if r < ' ' {
+				continue
+			}
+			l.insert(r)
+		}
+
+		l.refresh()
+	}
+}
+
+// readEscape reads the rest of an escape sequence such as "[A" and returns the
+// key it identifies: A-D for the arrows, H and F for Home and End and 3 for Delete.
+func (l *lineEditor) readEscape() rune {
+	next, _, err := l.in.ReadRune()
+	if err != nil || next != '[' && next != 'O' {
+		return 0
+	}
+
+	var params strings.Builder
+	for {
+		r, _, err := l.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if r >= '0' && r <= '9' || r == ';' {
+			params.WriteRune(r)
+			continue
+		}
+
+		switch {
+		case r == '~' && (params.String() == "1" || params.String() == "7"):
+			return 'H'
+		case r == '~' && (params.String() == "4" || params.String() == "8"):
+			return 'F'
+		case r == '~' && params.String() == "3":
+			return '3'
+		case r == '~':
+			return 0
+		}
+		return r
+	}
+}
+
+// browse replaces the line with the history entry at index, the line past the
+// newest entry is the one that was being written.
+func (l *lineEditor) browse(index, current int, pending []rune) (int, []rune) {
+	if index < 0 || index > len(l.history.entries) {
+		return current, pending
+	}
+	if current == len(l.history.entries) {
+		pending = l.line
+	}
+
+	if index == len(l.history.entries) {
+		l.line = pending
+	} else {
+		l.line = []rune(l.history.entries[index])
+	}
+	l.pos = len(l.line)
+
+	return index, pending
+}
+
+// completeWord extends the word before the cursor by what all candidates share,
+// or lists the candidates when there is nothing to add.
+func (l *lineEditor) completeWord() {
+	if l.complete == nil {
+		return
+	}
+
+	candidates, start := l.complete(l.line[:l.pos])
+	word := string(l.line[start:l.pos])
+	prefix := commonPrefix(candidates)
+
+	if len(prefix) > len(word) {
+		for _, r := range prefix[len(word):] {
+			l.insert(r)
+		}
+		return
+	}
+
+	if len(candidates) > 1 {
+		fmt.Fprintf(l.out, "\n%s\n", strings.Join(candidates, "  "))
+	}
+}
+
+func (l *lineEditor) insert(r rune) {
+	l.line = append(l.line, 0)
+	copy(l.line[l.pos+1:], l.line[l.pos:])
+	l.line[l.pos] = r
+	l.pos++
+}
+
+func (l *lineEditor) deleteAt(pos int) {
+	if pos < len(l.line) {
+		l.line = append(l.line[:pos], l.line[pos+1:]...)
+	}
+}
+
+func (l *lineEditor) moveBy(n int) {
+	l.pos = min(max(l.pos+n, 0), len(l.line))
+}
+
+// refresh redraws the prompt and the line and puts the cursor back in place.
+func (l *lineEditor) refresh() {
+	var sb strings.Builder
+	sb.WriteString("\r")
+	sb.WriteString(l.prompt)
+	if l.highlight != nil {
+		sb.WriteString(l.highlight(string(l.line)))
+	} else {
+		sb.WriteString(string(l.line))
+	}
+	sb.WriteString("\x1b[K")
+	if back := len(l.line) - l.pos; back > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dD", back)
+	}
+	fmt.Fprint(l.out, sb.String())
+}
@@ -0,0 +1,175 @@
+package interpreter
+
+import (
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/types"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	promtd             = ">>>"
+	continuationPrompt = "..."
+)
+
+// historyEnv names the file the REPL keeps its history in, set it empty to disable history.
+const historyEnv = "SIMPLY_HISTORY"
+
+// noColorEnv turns off syntax highlighting when set, see https://no-color.org.
+const noColorEnv = "NO_COLOR"
+
+// lineReader reads one line of input without its line ending.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+type repl struct {
+	in    *bufio.Reader
+	out   io.Writer
+	lines lineReader
+	ctx   *types.Context
+	eval  *evaluator.Evaluator
+}
+
+// Start runs the interactive shell until the input ends. On a terminal lines are
+// edited in place and kept in a history, otherwise they are read as they come.
+func Start(in io.Reader, out io.Writer) {
+	//input() shares the reader so lines buffered by one are not lost to the other
+	reader := bufio.NewReader(in)
+
+	r := &repl{in: reader, out: out}
+	r.reset()
+	r.lines = &plainReader{reader: reader, out: out}
+
+	if f, ok := in.(*os.File); ok && isTerminal(int(f.Fd())) {
+		editor := newLineEditor(reader, out, loadHistory(historyPath()))
+		editor.complete = r.complete
+		if _, ok := os.LookupEnv(noColorEnv); !ok {
+			editor.highlight = highlight
+		}
+		r.lines = &terminalReader{fd: int(f.Fd()), editor: editor}
+	}
+
+	for {
+		source, err := r.readInput()
+		if err != nil {
+			fmt.Fprintln(out)
+			return
+		}
+
+		r.evalInput(source)
+	}
+}
+
+// readInput reads lines until the brackets they open are closed.
+func (r *repl) readInput() (string, error) {
+	var sb strings.Builder
+	prompt := promtd
+
+	for {
+		line, err := r.lines.readLine(prompt)
+		if errors.Is(err, errInterrupted) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		if !isIncomplete(sb.String()) {
+			return sb.String(), nil
+		}
+		prompt = continuationPrompt
+	}
+}
+
+// reset discards the session bindings and loaded modules.
+func (r *repl) reset() {
+	r.ctx = types.NewContext(nil)
+	r.eval = newEvaluator()
+	r.eval.SetIO(r.in, r.out)
+}
+
+func (r *repl) evalInput(source string) {
+	if strings.HasPrefix(strings.TrimSpace(source), commandPrefix) {
+		r.runCommand(strings.TrimSpace(source))
+		return
+	}
+
+	program, err := parseInput(r.out, "", source)
+	if err != nil {
+		fmt.Fprintln(r.out, err)
+		return
+	}
+
+	evalResult := r.eval.Eval(program, r.ctx)
+
+	if evalResult != nil && evalResult != types.NULL {
+		fmt.Fprintln(r.out, evalResult.String())
+	}
+}
+
+// isIncomplete reports whether the source has more opening than closing brackets.
+func isIncomplete(source string) bool {
+	depth := 0
+	t := lexer.NewTokenizer(source)
+	for tok := t.NextToken(); tok.Type != lexer.EOF; tok = t.NextToken() {
+		switch tok.Type {
+		case lexer.LPAREN, lexer.LBRACE, lexer.LBRACKET:
+			depth++
+		case lexer.RPAREN, lexer.RBRACE, lexer.RBRACKET:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+func historyPath() string {
+	if path, ok := os.LookupEnv(historyEnv); ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".simply_history")
+}
+
+// plainReader reads lines from input that is not a terminal.
+type plainReader struct {
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+func (p *plainReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+
+	line, err := p.reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// terminalReader switches the terminal to raw mode while a line is edited.
+type terminalReader struct {
+	fd     int
+	editor *lineEditor
+}
+
+func (t *terminalReader) readLine(prompt string) (string, error) {
+	restore, err := makeRaw(t.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	return t.editor.readLine(prompt)
+}
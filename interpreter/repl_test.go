@@ -0,0 +1,196 @@
+package interpreter
+
+import (
+	"Simply/types"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	input := "let add = func(a, b) {\n  a + b\n};\nadd(1,\n 2)\n[1,\n2]\n"
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := ">>>......>>>...3\n>>>...[1, 2]\n>>>\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 1;", false},
+		{"if (x) {", true},
+		{"func(a,", true},
+		{"[1, 2", true},
+		{"}", false},
+		{`"unterminated`, false},
+	}
+
+	for _, tt := range tests {
+		if got := isIncomplete(tt.input); got != tt.expected {
+			t.Errorf("isIncomplete(%q) = %t, want %t", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	tests := []struct {
+		keys     string
+		history  []string
+		expected string
+	}{
+		{"abc\r", nil, "abc"},
+		{"abd\x7fc\r", nil, "abc"},
+		{"bc\x1b[Da\x1b[C\x1b[Cd\r", nil, "bacd"},
+		{"bc\x01a\x05d\r", nil, "abcd"},
+		{"abcd\x1b[D\x1b[D\x0b\r", nil, "ab"},
+		{"abcd\x1b[D\x1b[D\x15\r", nil, "cd"},
+		{"ab\x1b[H\x1b[3~\r", nil, "b"},
+		{"\x1b[A\r", []string{"first", "second"}, "second"},
+		{"\x1b[A\x1b[A\r", []string{"first", "second"}, "first"},
+		{"new\x1b[A\x1b[B\r", []string{"first"}, "new"},
+		{"\x10!\r", []string{"first"}, "first!"},
+		{"héllo\x1b[D\x7f\r", nil, "hélo"},
+	}
+
+	for _, tt := range tests {
+		h := &history{entries: append([]string(nil), tt.history...)}
+		l := newLineEditor(bufio.NewReader(strings.NewReader(tt.keys)), io.Discard, h)
+		line, err := l.readLine(">>>")
+		if err != nil {
+			t.Errorf("%q: %s", tt.keys, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.keys, tt.expected, line)
+		}
+		if h.entries[len(h.entries)-1] != tt.expected {
+			t.Errorf("%q: expected the line to be added to the history", tt.keys)
+		}
+	}
+
+	l := newLineEditor(bufio.NewReader(strings.NewReader("ab\x03\x04")), io.Discard, &history{})
+	if _, err := l.readLine(">>>"); !errors.Is(err, errInterrupted) {
+		t.Errorf("expected Ctrl-C to interrupt, got %v", err)
+	}
+	if _, err := l.readLine(">>>"); err != io.EOF {
+		t.Errorf("expected Ctrl-D on an empty line to end input, got %v", err)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h := loadHistory(path)
+	h.add("let x = 1;")
+	h.add("let x = 1;")
+	h.add("  ")
+	h.add("x")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "let x = 1;\nx\n" {
+		t.Errorf("unexpected history file %q", content)
+	}
+
+	if got := loadHistory(path).entries; len(got) != 2 || got[1] != "x" {
+		t.Errorf("expected history to be loaded, got %q", got)
+	}
+}
+
+func TestHistoryFileLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	var lines []string
+	for i := 0; i < maxHistory; i++ {
+		lines = append(lines, fmt.Sprint(i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() []string {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	}
+
+	//Lines are appended until the file holds twice the entries kept, then it
+	//is compacted once and appended to again
+	h := loadHistory(path)
+	rewrites, size := 0, maxHistory
+	for i := 0; i < 2*maxHistory; i++ {
+		h.add(fmt.Sprint("new ", i))
+		got := read()
+		if got[len(got)-1] != fmt.Sprint("new ", i) {
+			t.Fatalf("expected the file to end with the new line, got %q", got[len(got)-1])
+		}
+		if len(got) != size+1 {
+			rewrites++
+			if len(got) != maxHistory {
+				t.Errorf("expected the file to be compacted to %d lines, got %d", maxHistory, len(got))
+			}
+		}
+		size = len(got)
+	}
+	if rewrites != 1 || size != 2*maxHistory-1 {
+		t.Errorf("expected 1 rewrite and %d lines, got %d rewrites and %d lines", 2*maxHistory-1, rewrites, size)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.syn")
+	if err := os.WriteFile(path, []byte("let x = 5;\nlet add = func(a, b) { a + b };\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let y = \"a\";\n:env\n", `y: string = "a"`},
+		{"func f() { let x = 1 };\nlet y = f();\n:env\n", "y: null = null\n"},
+		{":load " + path + "\n:env\n", "add: func = func(a, b)\nx: int = 5\n"},
+		{":load missing.syn\n", "Error reading file"},
+		{"let y = 1;\n:reset\n:env\ny\n", ">>>>>>>>>identifier not found: y"},
+		{"let y = 1;\n:type y + 0.5\n", "float"},
+		{`:type "a" - 1` + "\n", "1:1: invalid operation: string - int"},
+		{":tokens 1 + 2\n", "1:3\t+\t\"+\"\n"},
+		{":ast x\n", "Expression: Identifier 1:1"},
+		{":time 1 + 1\n", "2\ntook "},
+		{":help\n", ":load file     run a script in the session"},
+		{":type\n", "usage: :type expr"},
+		{":nope\n", "unknown command :nope, see :help"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+		if !strings.Contains(out.String(), tt.expected) {
+			t.Errorf("%q: expected output to contain %q, got %q", tt.input, tt.expected, out.String())
+		}
+	}
+
+	//Bindings set from Go may hold no value
+	var out bytes.Buffer
+	r := &repl{out: &out, ctx: types.NewContext(nil)}
+	r.ctx.Set("z", nil)
+	r.env("")
+	if out.String() != "z: null = null\n" {
+		t.Errorf("unexpected environment %q", out.String())
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package interpreter
+
+import "errors"
+
+//Line editing needs raw terminal mode, elsewhere the REPL reads plain lines
+
+func isTerminal(fd int) bool { return false }
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
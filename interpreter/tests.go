@@ -0,0 +1,75 @@
+package interpreter
+
+import (
+	"Simply/coverage"
+	"Simply/evaluator"
+	"Simply/testrunner"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TestOptions select the tests run by RunTests and how they are reported.
+type TestOptions struct {
+	Run      string //Regular expression matching the names of the tests to run, empty runs all
+	Format   string //text, tap or junit
+	Verbose  bool   //List the passing tests in the text format
+	Cover    bool   //Record which statements of the code under test ran
+	Coverage CoverageReports
+}
+
+// RunTests runs the tests found in paths, the current directory when there are
+// none, and writes the report to Out. It reports whether every test passed.
+func RunTests(paths []string, opts TestOptions, s Streams) bool {
+	var filter *regexp.Regexp
+	if opts.Run != "" {
+		var err error
+		if filter, err = regexp.Compile(opts.Run); err != nil {
+			fmt.Fprintln(s.Err, "Invalid test filter:", err)
+			return false
+		}
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := testrunner.Find(paths)
+	if err != nil {
+		fmt.Fprintln(s.Err, "Error finding tests:", err)
+		return false
+	}
+
+	var c *coverage.Coverage
+	if opts.Cover {
+		c = coverage.New()
+		c.Ignore = func(path string) bool { return strings.HasSuffix(path, testrunner.FileSuffix) }
+	}
+	results := testrunner.Run(files, testrunner.Options{Run: filter, NewEvaluator: func() *evaluator.Evaluator {
+		e := newEvaluator()
+		if c != nil {
+			e.SetHook(c)
+		}
+		return e
+	}})
+
+	switch opts.Format {
+	case "", "text":
+		err = testrunner.WriteText(s.Out, results, opts.Verbose)
+	case "tap":
+		err = testrunner.WriteTAP(s.Out, results)
+	case "junit":
+		err = testrunner.WriteJUnit(s.Out, results)
+	default:
+		err = fmt.Errorf("unknown format %s", opts.Format)
+	}
+	if err != nil {
+		fmt.Fprintln(s.Err, "Error writing test report:", err)
+		return false
+	}
+
+	ok := testrunner.Summarize(results).Failures == 0
+	if c != nil {
+		ok = writeCoverage(c, opts.Coverage, s) && ok
+	}
+	return ok
+}
@@ -1,18 +1,23 @@
 package lexer
 
+import "sort"
+
 type TokenType string
 type Token struct {
-	Type     TokenType
-	Literal  string
-	Row, Col int
+	Type    TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Row     int       `json:"row"`
+	Col     int       `json:"col"`
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 
+	COMMENT    = "COMMENT"
 	IDENTIFIER = "IDENTIFIER"
 	INT        = "INT"
+	FLOAT      = "FLOAT"
 	STRING     = "STRING"
 
 	// Operators
@@ -29,11 +34,15 @@ const (
 
 	// Delimiters
 	COMMA     = ","
+	DOT       = "."
 	SEMICOLON = ";"
+	COLON     = ":"
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
 	RBRACE    = "}"
+	LBRACKET  = "["
+	RBRACKET  = "]"
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -43,6 +52,12 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	STRUCT   = "STRUCT"
+	TRAIT    = "TRAIT"
+	IMPL     = "IMPL"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
 )
 
 var keywords = map[string]TokenType{
@@ -53,6 +68,22 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"struct": STRUCT,
+	"trait":  TRAIT,
+	"impl":   IMPL,
+	"import": IMPORT,
+	"export": EXPORT,
+	"as":     AS,
+}
+
+// Keywords returns the reserved words in sorted order.
+func Keywords() []string {
+	result := make([]string, 0, len(keywords))
+	for k := range keywords {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
 }
 
 func GetType(identifier string) TokenType {
@@ -4,20 +4,30 @@ type Tokenizer struct {
 	input      string
 	currentPos int
 	ch         byte
-	//row, col   int TODO implement row/col trackin for debuging
+	row, col   int
+
+	Comments []Token //Line comments skipped so far, in source order
 }
 
 func NewTokenizer(input string) *Tokenizer {
-	t := &Tokenizer{input: input}
+	t := &Tokenizer{input: input, row: 1}
 	t.readChar()
 	return t
 }
 
 func (t *Tokenizer) NextToken() Token {
-	var tok Token
-
 	t.skipToNextCh()
 
+	row, col := t.row, t.col
+	tok := t.readToken()
+	tok.Row, tok.Col = row, col
+
+	return tok
+}
+
+func (t *Tokenizer) readToken() Token {
+	var tok Token
+
 	switch t.ch {
 	//String
 	case '"':
@@ -59,16 +69,24 @@ func (t *Tokenizer) NextToken() Token {
 	//Delimiters
 	case ';':
 		tok = Token{Type: SEMICOLON, Literal: string(t.ch)}
+	case ':':
+		tok = Token{Type: COLON, Literal: string(t.ch)}
 	case '(':
 		tok = Token{Type: LPAREN, Literal: string(t.ch)}
 	case ')':
 		tok = Token{Type: RPAREN, Literal: string(t.ch)}
 	case ',':
 		tok = Token{Type: COMMA, Literal: string(t.ch)}
+	case '.':
+		tok = Token{Type: DOT, Literal: string(t.ch)}
 	case '{':
 		tok = Token{Type: LBRACE, Literal: string(t.ch)}
 	case '}':
 		tok = Token{Type: RBRACE, Literal: string(t.ch)}
+	case '[':
+		tok = Token{Type: LBRACKET, Literal: string(t.ch)}
+	case ']':
+		tok = Token{Type: RBRACKET, Literal: string(t.ch)}
 	//End of file
 	case 0:
 		tok = Token{Type: EOF, Literal: ""}
@@ -78,9 +96,7 @@ func (t *Tokenizer) NextToken() Token {
 			tok.Type = GetType(tok.Literal)
 			return tok
 		} else if isDigit(t.ch) {
-			tok.Type = INT
-			tok.Literal = t.readValue(isDigit)
-			return tok
+			return t.readNumber()
 		} else {
 			tok = Token{Type: ILLEGAL, Literal: string(t.ch)}
 		}
@@ -91,12 +107,28 @@ func (t *Tokenizer) NextToken() Token {
 }
 
 func (t *Tokenizer) skipToNextCh() {
-	for t.ch == ' ' || t.ch == '\t' || t.ch == '\n' || t.ch == '\r' {
-		t.readChar()
+	for {
+		for t.ch == ' ' || t.ch == '\t' || t.ch == '\n' || t.ch == '\r' {
+			t.readChar()
+		}
+
+		if t.ch != '/' || t.peekChar() != '/' {
+			return
+		}
+
+		comment := Token{Type: COMMENT, Row: t.row, Col: t.col}
+		comment.Literal = t.readValue(isComment)
+		t.Comments = append(t.Comments, comment)
 	}
 }
 
 func (t *Tokenizer) readChar() {
+	if t.ch == '\n' {
+		t.row++
+		t.col = 0
+	}
+	t.col++
+
 	if t.currentPos >= len(t.input) {
 		t.ch = 0
 	} else {
@@ -123,6 +155,20 @@ func (t *Tokenizer) readValue(fn func(byte) bool) string {
 	return t.input[startPos : t.currentPos-1]
 }
 
+func (t *Tokenizer) readNumber() Token {
+	startPos := t.currentPos - 1
+	tokenType := TokenType(INT)
+
+	t.readValue(isDigit)
+	if t.ch == '.' && isDigit(t.peekChar()) {
+		tokenType = FLOAT
+		t.readChar()
+		t.readValue(isDigit)
+	}
+
+	return Token{Type: tokenType, Literal: t.input[startPos : t.currentPos-1]}
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
@@ -131,6 +177,10 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isComment(ch byte) bool {
+	return ch != '\n' && ch != 0
+}
+
 func isString(ch byte) bool {
-	return ch != '"'
+	return ch != '"' && ch != 0
 }
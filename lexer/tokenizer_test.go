@@ -0,0 +1,56 @@
+package lexer
+
+import "testing"
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x: int = 5\n  foo(\"a b\").bar\n"
+	expected := []Token{
+		{LET, "let", 1, 1},
+		{IDENTIFIER, "x", 1, 5},
+		{COLON, ":", 1, 6},
+		{IDENTIFIER, "int", 1, 8},
+		{ASSIGN, "=", 1, 12},
+		{INT, "5", 1, 14},
+		{IDENTIFIER, "foo", 2, 3},
+		{LPAREN, "(", 2, 6},
+		{STRING, "a b", 2, 7},
+		{RPAREN, ")", 2, 12},
+		{DOT, ".", 2, 13},
+		{IDENTIFIER, "bar", 2, 14},
+		{EOF, "", 3, 1},
+	}
+
+	tokenizer := NewTokenizer(input)
+	for i, want := range expected {
+		got := tokenizer.NextToken()
+		if got != want {
+			t.Fatalf("token %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// header\nlet x = 4 / 2 // half\n//"
+	expectedTokens := []TokenType{LET, IDENTIFIER, ASSIGN, INT, SLASH, INT, EOF}
+
+	tokenizer := NewTokenizer(input)
+	for i, want := range expectedTokens {
+		if got := tokenizer.NextToken(); got.Type != want {
+			t.Fatalf("token %d: expected %s, got %+v", i, want, got)
+		}
+	}
+
+	expectedComments := []Token{
+		{COMMENT, "// header", 1, 1},
+		{COMMENT, "// half", 2, 15},
+		{COMMENT, "//", 3, 1},
+	}
+	if len(tokenizer.Comments) != len(expectedComments) {
+		t.Fatalf("expected %d comments, got %+v", len(expectedComments), tokenizer.Comments)
+	}
+	for i, want := range expectedComments {
+		if tokenizer.Comments[i] != want {
+			t.Errorf("comment %d: expected %+v, got %+v", i, want, tokenizer.Comments[i])
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package lint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ConfigFile is the name of the project configuration, looked up in the
+// directory of a script and its parents.
+const ConfigFile = ".simplylint.json"
+
+// Config selects the rules of a project, for example:
+//
+//	{"disable": ["shadow"], "globals": ["request"]}
+type Config struct {
+	Disable []string `json:"disable"` //IDs of the rules that are not reported
+	Globals []string `json:"globals"` //Names the host defines for its scripts
+}
+
+func (c *Config) disabled(rule string) bool {
+	for _, d := range c.Disable {
+		if d == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseConfig decodes a configuration and checks that the rules it names exist.
+func ParseConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+
+	for _, id := range config.Disable {
+		if !isRule(id) {
+			return nil, fmt.Errorf("unknown rule %q", id)
+		}
+	}
+	return config, nil
+}
+
+func isRule(id string) bool {
+	for _, r := range Rules {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadConfig reads the configuration at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// FindConfig loads the closest ConfigFile in dir or its parents. Without one
+// every rule is enabled.
+func FindConfig(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		config, err := LoadConfig(filepath.Join(dir, ConfigFile))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return config, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &Config{}, nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,453 @@
+// Package lint reports code that runs but is likely a mistake.
+package lint
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/types"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Rule is a check of the linter, its ID is used in diagnostics and configuration.
+type Rule struct {
+	ID          string
+	Description string
+}
+
+var Rules = []Rule{
+	{"unused-variable", "local variables and functions that are never used"},
+	{"unused-parameter", "function parameters that are never used"},
+	{"shadow", "declarations that hide a binding of an enclosing scope"},
+	{"unreachable", "statements that follow a return"},
+	{"undefined", "calls to identifiers that are not declared"},
+	{"arity", "calls with the wrong number of arguments"},
+	{"constant-comparison", "comparisons that always have the same result"},
+}
+
+type Diagnostic struct {
+	Position ast.Position
+	Rule     string
+	Message  string
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Position.String(), d.Message, d.Rule)
+}
+
+type bindingKind int
+
+const (
+	builtinBinding bindingKind = iota
+	globalBinding              //Declared at the top level, may be used by the host
+	localBinding
+	parameterBinding
+)
+
+// arity is the number of arguments a function accepts, max is -1 for variadic functions.
+type arity struct {
+	min, max int
+}
+
+type binding struct {
+	name  string
+	pos   ast.Position
+	kind  bindingKind
+	used  bool
+	arity *arity              //Nil when unknown
+	trait *ast.TraitStatement //Set for traits
+}
+
+type scope struct {
+	vars   map[string]*binding
+	order  []*binding //Declaration order, for stable reports
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: map[string]*binding{}, parent: parent}
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	for c := s; c != nil; c = c.parent {
+		if b, ok := c.vars[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+var builtinArity = builtinArities()
+
+func builtinArities() map[string]*arity {
+	result := map[string]*arity{}
+
+	b := evaluator.DefaultBuiltins()
+	for _, name := range b.Names() {
+		obj, _ := b.Lookup(name)
+		result[name] = nil
+		if call, ok := obj.(*types.InternalCall); ok && call.Signature != nil {
+			min, max := call.Signature.Arity()
+			result[name] = &arity{min: min, max: max}
+		}
+	}
+
+	return result
+}
+
+type linter struct {
+	config      *Config
+	diagnostics []Diagnostic
+	reassigned  map[string]bool //Names that are assigned to, their arity is unknown
+}
+
+// Lint reports the issues found in the program by the rules enabled in config,
+// nil enables every rule.
+func Lint(program *ast.Program, config *Config) []Diagnostic {
+	if config == nil {
+		config = &Config{}
+	}
+	l := &linter{config: config, reassigned: map[string]bool{}}
+
+	ast.Inspect(program, func(n ast.Node) bool {
+		if a, ok := n.(*ast.AssignmentExpression); ok {
+			if id, ok := a.Target.(*ast.Identifier); ok {
+				l.reassigned[id.Value] = true
+			}
+		}
+		return true
+	})
+
+	builtins := newScope(nil)
+	for name, a := range builtinArity {
+		builtins.vars[name] = &binding{name: name, kind: builtinBinding, arity: a}
+	}
+	for _, name := range config.Globals {
+		builtins.vars[name] = &binding{name: name, kind: builtinBinding}
+	}
+
+	l.lintStatements(program.Statements, newScope(builtins), globalBinding)
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i].Position, l.diagnostics[j].Position
+		return a.Row < b.Row || a.Row == b.Row && a.Col < b.Col
+	})
+	return l.diagnostics
+}
+
+func (l *linter) report(rule string, pos ast.Position, format string, args ...interface{}) {
+	if l.config.disabled(rule) {
+		return
+	}
+	l.diagnostics = append(l.diagnostics, Diagnostic{Position: pos, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+// declare binds name in s, reporting the bindings of enclosing scopes it hides.
+// A name declared again in the same scope keeps its first binding.
+func (l *linter) declare(s *scope, id *ast.Identifier, kind bindingKind) *binding {
+	if b, ok := s.vars[id.Value]; ok {
+		return b
+	}
+
+	if outer, ok := s.parent.lookup(id.Value); ok && id.Value != "self" {
+		if outer.kind == builtinBinding {
+			l.report("shadow", id.Pos(), "%s shadows the builtin %s", id.Value, id.Value)
+		} else {
+			l.report("shadow", id.Pos(), "%s shadows the declaration at %s", id.Value, outer.pos.String())
+		}
+	}
+
+	b := &binding{name: id.Value, pos: id.Pos(), kind: kind}
+	s.vars[id.Value] = b
+	s.order = append(s.order, b)
+	return b
+}
+
+// lintStatements hoists the declarations of a block, as the checker does, then
+// lints its statements in order.
+func (l *linter) lintStatements(statements []ast.Node, s *scope, kind bindingKind) {
+	l.hoist(statements, s, kind)
+
+	terminated := false
+	for _, n := range statements {
+		if terminated {
+			l.report("unreachable", n.Pos(), "unreachable code")
+			terminated = false
+		}
+		l.lintStatement(n, s)
+		if terminates(n) {
+			terminated = true
+		}
+	}
+}
+
+func (l *linter) hoist(statements []ast.Node, s *scope, kind bindingKind) {
+	for _, n := range statements {
+		if export, ok := n.(*ast.ExportStatement); ok {
+			n = export.Statement
+		}
+
+		switch n := n.(type) {
+		case *ast.DeclarativeStatement:
+			b := l.declare(s, &n.Name, kind)
+			if fl, ok := n.Value.(*ast.FunctionLiteral); ok && b.arity == nil {
+				b.arity = &arity{min: len(fl.Parameters), max: len(fl.Parameters)}
+			}
+		case *ast.FunctionStatement:
+			b := l.declare(s, &n.Name, kind)
+			b.arity = &arity{min: len(n.Function.Parameters), max: len(n.Function.Parameters)}
+		case *ast.StructStatement:
+			b := l.declare(s, &n.Name, globalBinding)
+			b.arity = &arity{min: 0, max: len(n.Fields)}
+			for _, m := range n.Methods {
+				if m.Name.Value == "init" {
+					b.arity = &arity{min: len(m.Function.Parameters), max: len(m.Function.Parameters)}
+				}
+			}
+		case *ast.TraitStatement:
+			l.declare(s, &n.Name, globalBinding).trait = n
+		}
+	}
+}
+
+// terminates reports whether control never continues after the statement.
+func terminates(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExpressionStatement:
+		c, ok := n.Expression.(*ast.ConditionalExpression)
+		return ok && c.False != nil && blockTerminates(c.True) && blockTerminates(c.False)
+	}
+	return false
+}
+
+func blockTerminates(b *ast.CodeBlock) bool {
+	for _, n := range b.Statements {
+		if terminates(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *linter) lintStatement(n ast.Node, s *scope) {
+	switch n := n.(type) {
+	case *ast.DeclarativeStatement:
+		l.lintExpression(n.Value, s)
+	case *ast.FunctionStatement:
+		l.lintFunction(n.Function, s, nil)
+	case *ast.StructStatement:
+		l.lintStruct(n, s)
+	case *ast.ImportStatement:
+		name := strings.TrimSuffix(filepath.Base(n.Path.Value), filepath.Ext(n.Path.Value))
+		if n.Alias != nil {
+			name = n.Alias.Value
+		}
+		l.declare(s, &ast.Identifier{Position: n.Position, Value: name}, globalBinding).used = true
+	case *ast.ExportStatement:
+		l.lintStatement(n.Statement, s)
+	case *ast.ReturnStatement:
+		l.lintExpression(n.Value, s)
+	case *ast.ExpressionStatement:
+		l.lintExpression(n.Expression, s)
+	case *ast.TraitStatement:
+	default:
+		l.lintExpression(n, s)
+	}
+}
+
+func (l *linter) lintStruct(n *ast.StructStatement, s *scope) {
+	//Methods required by a trait must keep its parameters
+	required := map[string]bool{}
+	for _, t := range n.Traits {
+		b, ok := s.lookup(t.Value)
+		if !ok {
+			continue
+		}
+		b.used = true
+		if b.trait != nil {
+			for _, m := range b.trait.Methods {
+				required[m.Name.Value] = true
+			}
+		}
+	}
+
+	for _, m := range n.Methods {
+		methodScope := newScope(s)
+		methodScope.vars["self"] = &binding{name: "self", kind: localBinding, used: true}
+
+		var exempt map[string]bool
+		if required[m.Name.Value] {
+			exempt = map[string]bool{}
+			for _, p := range m.Function.Parameters {
+				exempt[p.Value] = true
+			}
+		}
+		l.lintFunction(m.Function, methodScope, exempt)
+	}
+}
+
+// lintFunction lints the body of a function in its own scope, parameters in
+// exempt are not reported when unused.
+func (l *linter) lintFunction(fl *ast.FunctionLiteral, s *scope, exempt map[string]bool) {
+	body := newScope(s)
+	for _, p := range fl.Parameters {
+		l.declare(body, p, parameterBinding)
+	}
+
+	l.lintStatements(fl.Body.Statements, body, localBinding)
+
+	for _, b := range body.order {
+		if b.used || strings.HasPrefix(b.name, "_") {
+			continue
+		}
+		switch {
+		case b.kind == parameterBinding && !exempt[b.name]:
+			l.report("unused-parameter", b.pos, "parameter %s is never used", b.name)
+		case b.kind == localBinding:
+			l.report("unused-variable", b.pos, "%s is declared but never used", b.name)
+		}
+	}
+}
+
+func (l *linter) lintExpression(n ast.Node, s *scope) {
+	ast.Inspect(n, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			if b, ok := s.lookup(node.Value); ok {
+				b.used = true
+			}
+		case *ast.FunctionLiteral:
+			l.lintFunction(node, s, nil)
+			return false
+		case *ast.ConditionalExpression:
+			//Blocks share the scope of the enclosing code
+			l.lintExpression(node.Condition, s)
+			l.lintStatements(node.True.Statements, s, localBindingOf(s))
+			if node.False != nil {
+				l.lintStatements(node.False.Statements, s, localBindingOf(s))
+			}
+			return false
+		case *ast.CallExpression:
+			l.lintCall(node, s)
+			return false
+		case *ast.MemberExpression:
+			//The member is not a reference to a binding
+			l.lintExpression(node.Object, s)
+			return false
+		case *ast.AssignmentExpression:
+			//Assigning to a variable does not use it
+			if _, ok := node.Target.(*ast.Identifier); !ok {
+				l.lintExpression(node.Target, s)
+			}
+			l.lintExpression(node.Value, s)
+			return false
+		case *ast.InfixExpression:
+			l.lintComparison(node)
+		}
+		return true
+	})
+}
+
+// localBindingOf is the kind of the bindings declared in s.
+func localBindingOf(s *scope) bindingKind {
+	if s.parent != nil && s.parent.parent == nil {
+		return globalBinding
+	}
+	return localBinding
+}
+
+func (l *linter) lintCall(n *ast.CallExpression, s *scope) {
+	for _, a := range n.Arguments {
+		//Callbacks take the parameters their caller passes, used or not
+		if fl, ok := a.(*ast.FunctionLiteral); ok {
+			exempt := map[string]bool{}
+			for _, p := range fl.Parameters {
+				exempt[p.Value] = true
+			}
+			l.lintFunction(fl, s, exempt)
+			continue
+		}
+		l.lintExpression(a, s)
+	}
+
+	id, ok := n.Function.(*ast.Identifier)
+	if !ok {
+		l.lintExpression(n.Function, s)
+		return
+	}
+
+	b, ok := s.lookup(id.Value)
+	if !ok {
+		l.report("undefined", id.Pos(), "call to undefined function %s", id.Value)
+		return
+	}
+	b.used = true
+
+	if b.arity == nil || l.reassigned[id.Value] && b.kind != builtinBinding {
+		return
+	}
+	got := len(n.Arguments)
+	switch a := b.arity; {
+	case a.max == -1 && got < a.min:
+		l.report("arity", n.Pos(), "wrong number of arguments in call to %s: got %d, want at least %d", id.Value, got, a.min)
+	case a.max != -1 && (got < a.min || got > a.max):
+		want := fmt.Sprint(a.max)
+		if a.min != a.max {
+			want = fmt.Sprintf("%d to %d", a.min, a.max)
+		}
+		l.report("arity", n.Pos(), "wrong number of arguments in call to %s: got %d, want %s", id.Value, got, want)
+	}
+}
+
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, ">": true}
+
+// lintComparison reports comparisons of literals and of an expression with itself.
+func (l *linter) lintComparison(n *ast.InfixExpression) {
+	if !comparisons[n.Operator] {
+		return
+	}
+
+	if isLiteral(n.Left) && isLiteral(n.Right) {
+		result, ok := evaluator.New().Eval(n, types.NewContext(nil)).(*types.Bool)
+		if ok {
+			l.report("constant-comparison", n.Pos(), "comparison %s is always %t", n.String(), result.Value)
+		}
+		return
+	}
+
+	if isPure(n.Left) && n.Left.String() == n.Right.String() {
+		result := n.Operator == "=="
+		l.report("constant-comparison", n.Pos(), "comparison %s is always %t", n.String(), result)
+	}
+}
+
+func isLiteral(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.IntLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BoolLiteral:
+		return true
+	case *ast.PrefixExpression:
+		return isLiteral(n.Expression)
+	}
+	return false
+}
+
+// isPure reports whether evaluating the expression twice gives the same value.
+func isPure(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.MemberExpression:
+		return isPure(n.Object)
+	case *ast.IndexExpression:
+		return isPure(n.Left) && isPure(n.Index)
+	case *ast.PrefixExpression:
+		return isPure(n.Expression)
+	case *ast.InfixExpression:
+		return isPure(n.Left) && isPure(n.Right)
+	}
+	return isLiteral(n)
+}
@@ -0,0 +1,125 @@
+package lint
+
+import (
+	"Simply/lexer"
+	"Simply/parser"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; func add(a, b) { a + b }; add(x, 2);", nil},
+		{"func f() { let y = 1; 2 }; f();", []string{"1:16: y is declared but never used (unused-variable)"}},
+		{"func f() { func g() { 1 }; let _tmp = 1; 2 }; f();", []string{"1:17: g is declared but never used (unused-variable)"}},
+		{"func f() { let y = 1; y = 2; 3 }; f();", []string{"1:16: y is declared but never used (unused-variable)"}},
+		{"let unused = 1;", nil},
+		{"func f(a, b) { a }; f(1, 2);", []string{"1:11: parameter b is never used (unused-parameter)"}},
+		{"func f(a, _b) { a }; f(1, 2); map([1], func(x, i) { 1 });", nil},
+		{"trait T { f(a) }; struct S impl T { func f(a) { 1 } func g(b) { self } };", []string{
+			"1:60: parameter b is never used (unused-parameter)",
+		}},
+		{"let x = 1; func f(x) { x }; f(x);", []string{"1:19: x shadows the declaration at 1:5 (shadow)"}},
+		{"func f() { let len = 1; len }; f();", []string{"1:16: len shadows the builtin len (shadow)"}},
+		{"let f = func(n) { if (n) { let m = n; m } else { 0 } }; f(1);", nil},
+		{"func f() { return 1; let x = 2; x }; f();", []string{"1:22: unreachable code (unreachable)"}},
+		{"func f(n) { if (n) { return 1 } else { return 2 }; 3 }; f(1);", []string{"1:52: unreachable code (unreachable)"}},
+		{"func f(n) { if (n) { return 1 }; 3 }; f(1);", nil},
+		{"missing(1); let g = func() { other() };", []string{
+			"1:1: call to undefined function missing (undefined)",
+			"1:30: call to undefined function other (undefined)",
+		}},
+		{"func f() { g() }; func g() { 1 }; f();", nil},
+		{`import "lib/geometry.simply"; import "x.simply" as y; geometry.area(4); y.f();`, nil},
+		{"func add(a, b) { a + b }; add(1); add(1, 2, 3);", []string{
+			"1:27: wrong number of arguments in call to add: got 1, want 2 (arity)",
+			"1:35: wrong number of arguments in call to add: got 3, want 2 (arity)",
+		}},
+		{"len(); sort([1], 1, 2); println();", []string{
+			"1:1: wrong number of arguments in call to len: got 0, want 1 (arity)",
+			"1:8: wrong number of arguments in call to sort: got 3, want 1 to 2 (arity)",
+		}},
+		{"struct P { x, y }; P(1, 2, 3); struct Q { v func init() { 1 } }; Q(1);", []string{
+			"1:20: wrong number of arguments in call to P: got 3, want 0 to 2 (arity)",
+			"1:66: wrong number of arguments in call to Q: got 1, want 0 (arity)",
+		}},
+		{"let f = func(a) { a }; f = func(a, b) { a + b }; f(1, 2);", nil},
+		{`1 < 2; "a" == "b"; -1 != 1; let x = 1; x == x; x.y != x.y; x == 1;`, []string{
+			"1:1: comparison 1 < 2 is always true (constant-comparison)",
+			"1:8: comparison \"a\" == \"b\" is always false (constant-comparison)",
+			"1:20: comparison (-1) != 1 is always true (constant-comparison)",
+			"1:40: comparison x == x is always true (constant-comparison)",
+			"1:48: comparison x.y != x.y is always false (constant-comparison)",
+		}},
+		{"f(x) == f(x); 1 == true;", []string{"1:1: call to undefined function f (undefined)", "1:9: call to undefined function f (undefined)"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewTokenizer(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%s: parse errors %v", tt.input, p.Errors)
+		}
+
+		var got []string
+		for _, d := range Lint(program, nil) {
+			got = append(got, d.Error())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s:\nexpected %q\ngot      %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestLintConfig(t *testing.T) {
+	source := "func f(a) { missing(); request(); let len = 1; 1 }; f(1);"
+	program := parser.NewParser(lexer.NewTokenizer(source)).ParseProgram()
+
+	config := &Config{Disable: []string{"unused-parameter", "unused-variable"}, Globals: []string{"request"}}
+	var got []string
+	for _, d := range Lint(program, config) {
+		got = append(got, d.Error())
+	}
+
+	expected := []string{
+		"1:13: call to undefined function missing (undefined)",
+		"1:39: len shadows the builtin len (shadow)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := FindConfig(nested)
+	if err != nil || len(config.Disable) != 0 {
+		t.Errorf("expected the default configuration, got %v %v", config, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, ConfigFile), []byte(`{"disable": ["shadow"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = FindConfig(nested)
+	if err != nil || !reflect.DeepEqual(config.Disable, []string{"shadow"}) {
+		t.Errorf("expected the configuration of the parent directory, got %v %v", config, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(nested, ConfigFile), []byte(`{"disable": ["typo"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindConfig(nested); err == nil || !strings.HasSuffix(err.Error(), `unknown rule "typo"`) {
+		t.Errorf("expected an unknown rule error, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package lsp
+
+import (
+	"Simply/ast"
+	"Simply/checker"
+	"Simply/lexer"
+	"Simply/lint"
+	"Simply/parser"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []string
+
+	//Of the last text without syntax errors, kept while the text is being edited
+	program     *ast.Program
+	index       *index
+	parsedLines []string
+}
+
+// update replaces the text of the document and returns its diagnostics.
+func (d *document) update(text string, version int) []Diagnostic {
+	d.text, d.version = text, version
+	d.lines = strings.Split(text, "\n")
+
+	p := parser.NewParser(lexer.NewTokenizer(text))
+	program := p.ParseProgram()
+
+	diagnostics := []Diagnostic{}
+	if len(p.Errors) > 0 {
+		for i, msg := range p.Errors {
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    toRange(d.lines, p.ErrorPositions[i], 1),
+				Severity: SeverityError,
+				Source:   "simply",
+				Message:  msg,
+			})
+		}
+		return diagnostics
+	}
+
+	d.program, d.index, d.parsedLines = program, newIndex(program), d.lines
+
+	for _, e := range checker.Check(program) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    toRange(d.lines, e.Position, 1),
+			Severity: SeverityError,
+			Source:   "simply check",
+			Message:  e.Message,
+		})
+	}
+
+	config, err := lint.FindConfig(filepath.Dir(uriToPath(d.uri)))
+	if err != nil {
+		config = nil
+	}
+	for _, l := range lint.Lint(program, config) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    toRange(d.lines, l.Position, 1),
+			Severity: SeverityWarning,
+			Code:     l.Rule,
+			Source:   "simply lint",
+			Message:  l.Message,
+		})
+	}
+
+	return diagnostics
+}
+
+// uriToPath returns the file path of a file URI, or the URI itself for other schemes.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// toPosition converts a source position, whose column counts bytes from 1, to
+// a protocol position.
+func toPosition(lines []string, p ast.Position) Position {
+	line, col := p.Row-1, p.Col-1
+	if line < 0 || line >= len(lines) || col < 0 {
+		return Position{Line: max(line, 0), Character: max(col, 0)}
+	}
+
+	text := lines[line]
+	if col > len(text) {
+		col = len(text)
+	}
+	return Position{Line: line, Character: utf16Len(text[:col])}
+}
+
+// fromPosition converts a protocol position to a source position.
+func fromPosition(lines []string, p Position) ast.Position {
+	if p.Line < 0 || p.Line >= len(lines) {
+		return ast.Position{Row: p.Line + 1, Col: p.Character + 1}
+	}
+
+	text := lines[p.Line]
+	units, col := 0, 0
+	for col < len(text) && units < p.Character {
+		r, size := utf8.DecodeRuneInString(text[col:])
+		units += utf16Units(r)
+		col += size
+	}
+	return ast.Position{Row: p.Line + 1, Col: col + 1}
+}
+
+// toRange returns the range of length bytes starting at p.
+func toRange(lines []string, p ast.Position, length int) Range {
+	return Range{Start: toPosition(lines, p), End: toPosition(lines, ast.Position{Row: p.Row, Col: p.Col + length})}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Units(r)
+	}
+	return n
+}
+
+// utf16Units is the number of UTF-16 code units that encode r.
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,363 @@
+package lsp
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/printer"
+	"Simply/types"
+	"sort"
+	"strings"
+)
+
+var (
+	builtins = evaluator.DefaultBuiltins()
+	//Methods of the built-in types, the type of a receiver is not known
+	builtinMethods = append(append(evaluator.MethodSignatures(&types.String{}),
+		evaluator.MethodSignatures(&types.Array{})...),
+		evaluator.MethodSignatures(types.NewMap())...)
+)
+
+// occurrenceAt returns the identifier under the cursor of a parsed document.
+func (s *Server) occurrenceAt(params TextDocumentPositionParams) (*document, occurrence, bool) {
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok || d.index == nil {
+		return nil, occurrence{}, false
+	}
+
+	o, ok := d.index.at(fromPosition(d.parsedLines, params.Position))
+	return d, o, ok
+}
+
+func (s *Server) hover(params TextDocumentPositionParams) (any, error) {
+	d, o, ok := s.occurrenceAt(params)
+	if !ok {
+		return nil, nil
+	}
+
+	text := describe(o)
+	if text == "" {
+		return nil, nil
+	}
+	r := toRange(d.parsedLines, o.pos, len(o.name))
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text}, Range: &r}, nil
+}
+
+// describe returns the declaration of the identifier, and the documentation of builtins, as markdown.
+func describe(o occurrence) string {
+	if o.sym != nil {
+		return code(declaration(o.sym))
+	}
+
+	if o.member {
+		var docs []string
+		for _, sig := range builtinMethods {
+			if sig.Name[strings.LastIndex(sig.Name, ".")+1:] == o.name {
+				docs = append(docs, code(sig.String())+sig.Doc)
+			}
+		}
+		return strings.Join(docs, "\n\n---\n\n")
+	}
+
+	obj, ok := builtins.Lookup(o.name)
+	if !ok {
+		return ""
+	}
+	if call, ok := obj.(*types.InternalCall); ok && call.Signature != nil {
+		return code(call.Signature.String()) + call.Signature.Doc
+	}
+	return code(o.name + ": " + types.TypeName(obj))
+}
+
+func code(s string) string {
+	return "```simply\n" + s + "\n```\n"
+}
+
+// declaration renders how the symbol was declared.
+func declaration(sym *symbol) string {
+	switch decl := sym.decl.(type) {
+	case *ast.DeclarativeStatement:
+		if fl, ok := decl.Value.(*ast.FunctionLiteral); ok {
+			return "func " + sym.name + signature(fl)
+		}
+		return "let " + typed(&decl.Name)
+	case *ast.FunctionStatement:
+		if sym.parent != nil {
+			return "func " + sym.parent.name + "." + sym.name + signature(decl.Function)
+		}
+		return "func " + sym.name + signature(decl.Function)
+	case *ast.FunctionLiteral:
+		for _, p := range decl.Parameters {
+			if p.Value == sym.name {
+				return "parameter " + typed(p)
+			}
+		}
+	case *ast.StructStatement:
+		if sym.kind == selfSymbol {
+			return "self: " + decl.Name.Value
+		}
+		fields := make([]string, len(decl.Fields))
+		for i, f := range decl.Fields {
+			fields[i] = typed(f)
+		}
+		return "struct " + decl.Name.Value + " { " + strings.Join(fields, ", ") + " }"
+	case *ast.Identifier:
+		return "field " + sym.parent.name + "." + typed(decl)
+	case *ast.TraitStatement:
+		return decl.String()
+	case *ast.TraitMethod:
+		return "func " + sym.parent.name + "." + decl.String()
+	case *ast.ImportStatement:
+		return decl.String()
+	}
+	return sym.name
+}
+
+func typed(id *ast.Identifier) string {
+	if id.Type != nil {
+		return id.Value + ": " + id.Type.Name
+	}
+	return id.Value
+}
+
+func signature(fl *ast.FunctionLiteral) string {
+	params := make([]string, len(fl.Parameters))
+	for i, p := range fl.Parameters {
+		params[i] = typed(p)
+	}
+
+	result := "(" + strings.Join(params, ", ") + ")"
+	if fl.ReturnType != nil {
+		result += ": " + fl.ReturnType.Name
+	}
+	return result
+}
+
+func (s *Server) definition(params TextDocumentPositionParams) (any, error) {
+	d, o, ok := s.occurrenceAt(params)
+	if !ok || o.sym == nil {
+		return nil, nil
+	}
+
+	return Location{URI: d.uri, Range: toRange(d.parsedLines, o.sym.pos, len(o.sym.name))}, nil
+}
+
+func (s *Server) references(params ReferenceParams) (any, error) {
+	d, o, ok := s.occurrenceAt(params.TextDocumentPositionParams)
+	if !ok || o.sym == nil {
+		return nil, nil
+	}
+
+	locations := []Location{}
+	seen := map[ast.Position]bool{}
+	for _, p := range o.sym.refs {
+		if seen[p] || p == o.sym.pos && !params.Context.IncludeDeclaration {
+			continue
+		}
+		seen[p] = true
+		locations = append(locations, Location{URI: d.uri, Range: toRange(d.parsedLines, p, len(o.sym.name))})
+	}
+	return locations, nil
+}
+
+func (s *Server) documentSymbol(params DocumentSymbolParams) (any, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil || d.program == nil {
+		return nil, err
+	}
+
+	symbols := []DocumentSymbol{}
+	for _, n := range d.program.Statements {
+		if export, ok := n.(*ast.ExportStatement); ok {
+			n = export.Statement
+		}
+		if sym, ok := d.symbolOf(n); ok {
+			symbols = append(symbols, sym)
+		}
+	}
+	return symbols, nil
+}
+
+// symbolOf describes a declaration for the outline of the document.
+func (d *document) symbolOf(n ast.Node) (DocumentSymbol, bool) {
+	//Nodes only record where they start, declarations end with their name or closing brace
+	newSymbol := func(name *ast.Identifier, kind SymbolKind, start ast.Position, end *ast.Position) DocumentSymbol {
+		selection := toRange(d.parsedLines, name.Pos(), len(name.Value))
+		r := Range{Start: toPosition(d.parsedLines, start), End: selection.End}
+		if end != nil {
+			r.End = toPosition(d.parsedLines, ast.Position{Row: end.Row, Col: end.Col + 1})
+		}
+		return DocumentSymbol{Name: name.Value, Kind: kind, Range: r, SelectionRange: selection}
+	}
+
+	switch n := n.(type) {
+	case *ast.DeclarativeStatement:
+		if fl, ok := n.Value.(*ast.FunctionLiteral); ok {
+			sym := newSymbol(&n.Name, SymbolFunction, n.Pos(), &fl.Body.Rbrace)
+			sym.Detail = "func" + signature(fl)
+			return sym, true
+		}
+		return newSymbol(&n.Name, SymbolVariable, n.Pos(), nil), true
+	case *ast.FunctionStatement:
+		sym := newSymbol(&n.Name, SymbolFunction, n.Pos(), &n.Function.Body.Rbrace)
+		sym.Detail = "func" + signature(n.Function)
+		return sym, true
+	case *ast.StructStatement:
+		sym := newSymbol(&n.Name, SymbolStruct, n.Pos(), &n.Rbrace)
+		for _, f := range n.Fields {
+			sym.Children = append(sym.Children, newSymbol(f, SymbolField, f.Pos(), nil))
+		}
+		for _, m := range n.Methods {
+			method := newSymbol(&m.Name, SymbolMethod, m.Pos(), &m.Function.Body.Rbrace)
+			method.Detail = "func" + signature(m.Function)
+			sym.Children = append(sym.Children, method)
+		}
+		return sym, true
+	case *ast.TraitStatement:
+		sym := newSymbol(&n.Name, SymbolInterface, n.Pos(), &n.Rbrace)
+		for _, m := range n.Methods {
+			sym.Children = append(sym.Children, newSymbol(&m.Name, SymbolMethod, m.Pos(), nil))
+		}
+		return sym, true
+	case *ast.ImportStatement:
+		name := &ast.Identifier{Position: n.Path.Pos(), Value: n.Path.String()}
+		if n.Alias != nil {
+			name = n.Alias
+		}
+		return newSymbol(name, SymbolModule, n.Pos(), nil), true
+	}
+	return DocumentSymbol{}, false
+}
+
+var completionKinds = map[symbolKind]CompletionItemKind{
+	variableSymbol:  CompletionVariable,
+	parameterSymbol: CompletionVariable,
+	functionSymbol:  CompletionFunction,
+	structSymbol:    CompletionStruct,
+	traitSymbol:     CompletionStruct,
+	fieldSymbol:     CompletionField,
+	methodSymbol:    CompletionMethod,
+	moduleSymbol:    CompletionModule,
+	selfSymbol:      CompletionVariable,
+}
+
+func (s *Server) completion(params TextDocumentPositionParams) (any, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	//The current text may not parse, the index of the last one that did is used
+	p := fromPosition(d.lines, params.Position)
+	var visible []*symbol
+	if d.index != nil {
+		visible = d.index.visible(p)
+	}
+
+	items := []CompletionItem{}
+	if object, ok := memberObject(d.lines, p); ok {
+		return append(items, memberCompletions(object, visible)...), nil
+	}
+
+	for _, sym := range visible {
+		items = append(items, CompletionItem{Label: sym.name, Kind: completionKinds[sym.kind], Detail: declaration(sym)})
+	}
+	for _, name := range builtins.Names() {
+		item := CompletionItem{Label: name, Kind: CompletionFunction}
+		if obj, _ := builtins.Lookup(name); obj != nil {
+			if call, ok := obj.(*types.InternalCall); ok && call.Signature != nil {
+				item.Detail = call.Signature.String()
+			}
+		}
+		items = append(items, item)
+	}
+	for _, k := range lexer.Keywords() {
+		items = append(items, CompletionItem{Label: k, Kind: CompletionKeyword})
+	}
+
+	sortCompletions(items)
+	return items, nil
+}
+
+// memberObject returns the identifier before the dot when the cursor follows one.
+func memberObject(lines []string, p ast.Position) (string, bool) {
+	if p.Row < 1 || p.Row > len(lines) {
+		return "", false
+	}
+	line := lines[p.Row-1]
+	end := min(p.Col-1, len(line))
+
+	i := end
+	for i > 0 && isNameByte(line[i-1]) {
+		i--
+	}
+	if i == 0 || line[i-1] != '.' {
+		return "", false
+	}
+
+	j := i - 1
+	for j > 0 && isNameByte(line[j-1]) {
+		j--
+	}
+	return line[j : i-1], true
+}
+
+func isNameByte(b byte) bool {
+	return b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+// memberCompletions lists the members of self or a trait, and the methods of the
+// built-in types for other values.
+func memberCompletions(object string, visible []*symbol) []CompletionItem {
+	var items []CompletionItem
+	for _, sym := range visible {
+		if sym.name != object {
+			continue
+		}
+		owner := sym
+		if sym.kind == selfSymbol {
+			owner = sym.parent
+		}
+		if owner.kind == structSymbol || owner.kind == traitSymbol {
+			for _, m := range owner.members {
+				items = append(items, CompletionItem{Label: m.name, Kind: completionKinds[m.kind], Detail: declaration(m)})
+			}
+			return items
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, sig := range builtinMethods {
+		name := sig.Name[strings.LastIndex(sig.Name, ".")+1:]
+		if !seen[name] {
+			seen[name] = true
+			items = append(items, CompletionItem{Label: name, Kind: CompletionMethod, Detail: sig.String()})
+		}
+	}
+	sortCompletions(items)
+	return items
+}
+
+func sortCompletions(items []CompletionItem) {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+}
+
+func (s *Server) formatting(params DocumentFormattingParams) (any, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := printer.Format(d.text)
+	if err != nil {
+		//Syntax errors are already reported as diagnostics
+		return nil, nil
+	}
+	if formatted == d.text {
+		return []TextEdit{}, nil
+	}
+
+	last := len(d.lines) - 1
+	end := Position{Line: last, Character: utf16Len(d.lines[last])}
+	return []TextEdit{{Range: Range{End: end}, NewText: formatted}}, nil
+}
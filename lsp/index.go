@@ -0,0 +1,303 @@
+package lsp
+
+import (
+	"Simply/ast"
+	"path/filepath"
+	"strings"
+)
+
+type symbolKind int
+
+const (
+	variableSymbol symbolKind = iota
+	parameterSymbol
+	functionSymbol
+	structSymbol
+	traitSymbol
+	fieldSymbol
+	methodSymbol
+	moduleSymbol
+	selfSymbol
+)
+
+// symbol is a name declared in a document.
+type symbol struct {
+	name    string
+	kind    symbolKind
+	pos     ast.Position //Of the name in the declaration
+	decl    ast.Node     //Declaring node, a FunctionLiteral for parameters
+	refs    []ast.Position
+	parent  *symbol   //Struct or trait of a member, struct of self
+	members []*symbol //Fields and methods of a struct, methods of a trait
+}
+
+func (s *symbol) member(name string) *symbol {
+	for _, m := range s.members {
+		if m.name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// occurrence is an identifier of the document, sym is nil for builtins, undefined
+// names and members of values whose type is not known.
+type occurrence struct {
+	pos    ast.Position
+	name   string
+	sym    *symbol
+	member bool //Follows a dot
+}
+
+func (o occurrence) contains(p ast.Position) bool {
+	return o.pos.Row == p.Row && o.pos.Col <= p.Col && p.Col <= o.pos.Col+len(o.name)
+}
+
+type scope struct {
+	vars       map[string]*symbol
+	parent     *scope
+	start, end ast.Position //Zero for the top level
+}
+
+func (s *scope) lookup(name string) *symbol {
+	for c := s; c != nil; c = c.parent {
+		if sym, ok := c.vars[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+func (s *scope) contains(p ast.Position) bool {
+	if s.start.Row == 0 {
+		return true
+	}
+	return before(s.start, p) && before(p, s.end)
+}
+
+func before(a, b ast.Position) bool {
+	return a.Row < b.Row || a.Row == b.Row && a.Col <= b.Col
+}
+
+// index resolves the identifiers of a program to the symbols they refer to.
+type index struct {
+	occurrences []occurrence
+	scopes      []*scope
+}
+
+func newIndex(program *ast.Program) *index {
+	x := &index{}
+	x.statements(program.Statements, x.newScope(nil, ast.Position{}, ast.Position{}))
+	return x
+}
+
+func (x *index) newScope(parent *scope, start, end ast.Position) *scope {
+	s := &scope{vars: map[string]*symbol{}, parent: parent, start: start, end: end}
+	x.scopes = append(x.scopes, s)
+	return s
+}
+
+// at returns the identifier at p.
+func (x *index) at(p ast.Position) (occurrence, bool) {
+	for _, o := range x.occurrences {
+		if o.contains(p) {
+			return o, true
+		}
+	}
+	return occurrence{}, false
+}
+
+// visible returns the symbols in scope at p, inner declarations first.
+func (x *index) visible(p ast.Position) []*symbol {
+	var innermost *scope
+	for _, s := range x.scopes {
+		if s.contains(p) && (innermost == nil || before(innermost.start, s.start)) {
+			innermost = s
+		}
+	}
+
+	var result []*symbol
+	seen := map[string]bool{}
+	for s := innermost; s != nil; s = s.parent {
+		for name, sym := range s.vars {
+			if !seen[name] {
+				seen[name] = true
+				result = append(result, sym)
+			}
+		}
+	}
+	return result
+}
+
+func (x *index) use(id *ast.Identifier, sym *symbol) {
+	x.occurrences = append(x.occurrences, occurrence{pos: id.Pos(), name: id.Value, sym: sym})
+	if sym != nil {
+		sym.refs = append(sym.refs, id.Pos())
+	}
+}
+
+func (x *index) declare(s *scope, id *ast.Identifier, kind symbolKind, decl ast.Node) *symbol {
+	if sym, ok := s.vars[id.Value]; ok {
+		return sym
+	}
+	sym := &symbol{name: id.Value, kind: kind, pos: id.Pos(), decl: decl}
+	s.vars[id.Value] = sym
+	x.use(id, sym)
+	return sym
+}
+
+// statements declares the bindings of a block ahead of its statements, as the checker does.
+func (x *index) statements(statements []ast.Node, s *scope) {
+	for _, n := range statements {
+		if export, ok := n.(*ast.ExportStatement); ok {
+			n = export.Statement
+		}
+
+		switch n := n.(type) {
+		case *ast.DeclarativeStatement:
+			kind := variableSymbol
+			if _, ok := n.Value.(*ast.FunctionLiteral); ok {
+				kind = functionSymbol
+			}
+			x.declare(s, &n.Name, kind, n)
+		case *ast.FunctionStatement:
+			x.declare(s, &n.Name, functionSymbol, n)
+		case *ast.StructStatement:
+			st := x.declare(s, &n.Name, structSymbol, n)
+			for _, f := range n.Fields {
+				x.member(st, f, fieldSymbol, f)
+			}
+			for _, m := range n.Methods {
+				x.member(st, &m.Name, methodSymbol, m)
+			}
+		case *ast.TraitStatement:
+			t := x.declare(s, &n.Name, traitSymbol, n)
+			for _, m := range n.Methods {
+				x.member(t, &m.Name, methodSymbol, m)
+			}
+		case *ast.ImportStatement:
+			name := strings.TrimSuffix(filepath.Base(n.Path.Value), filepath.Ext(n.Path.Value))
+			id := &ast.Identifier{Position: n.Path.Pos(), Value: name}
+			if n.Alias != nil {
+				id = n.Alias
+			}
+			sym := &symbol{name: id.Value, kind: moduleSymbol, pos: id.Pos(), decl: n}
+			s.vars[id.Value] = sym
+			if n.Alias != nil {
+				x.use(id, sym)
+			}
+		}
+	}
+
+	for _, n := range statements {
+		x.statement(n, s)
+	}
+}
+
+func (x *index) member(parent *symbol, id *ast.Identifier, kind symbolKind, decl ast.Node) {
+	sym := &symbol{name: id.Value, kind: kind, pos: id.Pos(), decl: decl, parent: parent}
+	parent.members = append(parent.members, sym)
+	x.use(id, sym)
+}
+
+func (x *index) statement(n ast.Node, s *scope) {
+	switch n := n.(type) {
+	case *ast.DeclarativeStatement:
+		x.annotation(n.Name.Type, s)
+		x.expression(n.Value, s)
+	case *ast.FunctionStatement:
+		x.function(n.Function, s, nil)
+	case *ast.StructStatement:
+		st := s.lookup(n.Name.Value)
+		for _, t := range n.Traits {
+			x.use(t, s.lookup(t.Value))
+		}
+		for _, f := range n.Fields {
+			x.annotation(f.Type, s)
+		}
+		for _, m := range n.Methods {
+			self := &symbol{name: "self", kind: selfSymbol, pos: n.Name.Pos(), decl: n, parent: st}
+			x.function(m.Function, s, self)
+		}
+	case *ast.ExportStatement:
+		x.statement(n.Statement, s)
+	case *ast.ReturnStatement:
+		x.expression(n.Value, s)
+	case *ast.ExpressionStatement:
+		x.expression(n.Expression, s)
+	case *ast.ImportStatement, *ast.TraitStatement:
+	default:
+		x.expression(n, s)
+	}
+}
+
+func (x *index) annotation(t *ast.TypeAnnotation, s *scope) {
+	if t == nil {
+		return
+	}
+	if sym := s.lookup(t.Name); sym != nil && (sym.kind == structSymbol || sym.kind == traitSymbol) {
+		x.use(&ast.Identifier{Position: t.Position, Value: t.Name}, sym)
+	}
+}
+
+// function indexes a function body in its own scope, self is set for methods.
+func (x *index) function(fl *ast.FunctionLiteral, s *scope, self *symbol) {
+	body := x.newScope(s, fl.Pos(), fl.Body.Rbrace)
+	if self != nil {
+		body.vars["self"] = self
+	}
+	for _, p := range fl.Parameters {
+		x.declare(body, p, parameterSymbol, fl)
+		x.annotation(p.Type, s)
+	}
+	x.annotation(fl.ReturnType, s)
+
+	x.statements(fl.Body.Statements, body)
+}
+
+func (x *index) expression(n ast.Node, s *scope) {
+	ast.Inspect(n, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			x.use(node, s.lookup(node.Value))
+		case *ast.FunctionLiteral:
+			x.function(node, s, nil)
+			return false
+		case *ast.ConditionalExpression:
+			//Blocks share the scope of the enclosing code
+			x.expression(node.Condition, s)
+			x.statements(node.True.Statements, s)
+			if node.False != nil {
+				x.statements(node.False.Statements, s)
+			}
+			return false
+		case *ast.MemberExpression:
+			x.expression(node.Object, s)
+			var object *symbol
+			if id, ok := node.Object.(*ast.Identifier); ok {
+				object = s.lookup(id.Value)
+			}
+			x.memberUse(node.Member, object)
+			return false
+		}
+		return true
+	})
+}
+
+// memberUse resolves the member of self or of a trait, object is nil when unknown.
+func (x *index) memberUse(member *ast.Identifier, object *symbol) {
+	var owner *symbol
+	if object != nil && object.kind == selfSymbol {
+		owner = object.parent
+	} else if object != nil && object.kind == traitSymbol {
+		owner = object
+	}
+
+	if owner != nil {
+		if m := owner.member(member.Value); m != nil {
+			x.use(member, m)
+			return
+		}
+	}
+	x.occurrences = append(x.occurrences, occurrence{pos: member.Pos(), name: member.Value, member: true})
+}
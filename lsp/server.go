@@ -0,0 +1,198 @@
+// Package lsp implements a Language Server Protocol server for Simply scripts.
+package lsp
+
+import (
+	"Simply/wire"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrExitWithoutShutdown is returned by Serve when the client exits without
+// asking the server to shut down first.
+var ErrExitWithoutShutdown = errors.New("exit without shutdown")
+
+type Server struct {
+	conn     *wire.Conn
+	docs     map[string]*document
+	shutdown bool
+}
+
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+// handle decodes the parameters of a method before calling fn.
+func handle[P any](fn func(s *Server, params P) (any, error)) handler {
+	return func(s *Server, raw json.RawMessage) (any, error) {
+		var params P
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return nil, &ResponseError{Code: codeInvalidParams, Message: err.Error()}
+			}
+		}
+		return fn(s, params)
+	}
+}
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"initialize":                  handle((*Server).initialize),
+		"initialized":                 handle((*Server).ignore),
+		"shutdown":                    handle((*Server).stop),
+		"textDocument/didOpen":        handle((*Server).didOpen),
+		"textDocument/didChange":      handle((*Server).didChange),
+		"textDocument/didClose":       handle((*Server).didClose),
+		"textDocument/hover":          handle((*Server).hover),
+		"textDocument/definition":     handle((*Server).definition),
+		"textDocument/references":     handle((*Server).references),
+		"textDocument/documentSymbol": handle((*Server).documentSymbol),
+		"textDocument/completion":     handle((*Server).completion),
+		"textDocument/formatting":     handle((*Server).formatting),
+	}
+}
+
+// Serve answers the requests read from in until the client exits.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &Server{conn: wire.NewConn(in, out), docs: map[string]*document{}}
+
+	for {
+		content, err := s.conn.Read()
+		if err == io.EOF {
+			return ErrExitWithoutShutdown
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(content, &req); err != nil {
+			if err := s.reply(nil, nil, &ResponseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		result, err := s.dispatch(req)
+		if req.ID == nil {
+			//Notifications have no response
+			continue
+		}
+		if err := s.reply(req.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch calls the handler of a method. A handler panicking, on source no
+// analysis expected, fails the request instead of the server.
+func (s *Server) dispatch(req request) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &ResponseError{Code: codeInternalError, Message: fmt.Sprintf("%s: %v", req.Method, r)}
+		}
+	}()
+
+	if s.shutdown {
+		return nil, &ResponseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	h, ok := handlers[req.Method]
+	if !ok {
+		return nil, &ResponseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+	return h(s, req.Params)
+}
+
+func (s *Server) reply(id *json.RawMessage, result any, err error) error {
+	if err == nil {
+		return s.conn.Write(response{JSONRPC: "2.0", ID: id, Result: result})
+	}
+
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		respErr = &ResponseError{Code: codeInternalError, Message: err.Error()}
+	}
+	return s.conn.Write(errorResponse{JSONRPC: "2.0", ID: id, Error: respErr})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return s.conn.Write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	result := InitializeResult{Capabilities: ServerCapabilities{
+		TextDocumentSync:           1, //Full
+		HoverProvider:              true,
+		DefinitionProvider:         true,
+		ReferencesProvider:         true,
+		DocumentSymbolProvider:     true,
+		DocumentFormattingProvider: true,
+		CompletionProvider:         &CompletionOptions{TriggerCharacters: []string{"."}},
+	}}
+	result.ServerInfo.Name = "simply"
+	result.ServerInfo.Version = "0.1"
+	return result, nil
+}
+
+func (s *Server) ignore(params json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (s *Server) stop(params json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params DidOpenTextDocumentParams) (any, error) {
+	d := &document{uri: params.TextDocument.URI}
+	s.docs[d.uri] = d
+	return nil, s.publish(d, d.update(params.TextDocument.Text, params.TextDocument.Version))
+}
+
+func (s *Server) didChange(params DidChangeTextDocumentParams) (any, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.ContentChanges) == 0 {
+		return nil, nil
+	}
+
+	//With full sync the last change holds the whole text
+	text := params.ContentChanges[len(params.ContentChanges)-1].Text
+	return nil, s.publish(d, d.update(text, params.TextDocument.Version))
+}
+
+func (s *Server) didClose(params DidCloseTextDocumentParams) (any, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.docs, d.uri)
+	return nil, s.publish(d, []Diagnostic{})
+}
+
+func (s *Server) publish(d *document, diagnostics []Diagnostic) error {
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: diagnostics,
+	})
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &ResponseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %s is not open", uri)}
+	}
+	return d, nil
+}
@@ -0,0 +1,427 @@
+package lsp
+
+import (
+	"Simply/ast"
+	"Simply/printer"
+	"Simply/wire"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// session records the messages of a client, then runs a server over them.
+type session struct {
+	in bytes.Buffer
+	id int
+}
+
+func (c *session) send(msg map[string]any) {
+	msg["jsonrpc"] = "2.0"
+	data, _ := json.Marshal(msg)
+	fmt.Fprintf(&c.in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (c *session) request(method string, params any) int {
+	c.id++
+	c.send(map[string]any{"id": c.id, "method": method, "params": params})
+	return c.id
+}
+
+func (c *session) notify(method string, params any) {
+	c.send(map[string]any{"method": method, "params": params})
+}
+
+type output struct {
+	responses     map[int]map[string]json.RawMessage
+	notifications []map[string]json.RawMessage
+}
+
+// result decodes the result of request id into v.
+func (o output) result(t *testing.T, id int, v any) {
+	t.Helper()
+
+	r, ok := o.responses[id]
+	if !ok {
+		t.Fatalf("no response to request %d", id)
+	}
+	if r["error"] != nil {
+		t.Fatalf("request %d failed: %s", id, r["error"])
+	}
+	if err := json.Unmarshal(r["result"], v); err != nil {
+		t.Fatalf("request %d: %v in %s", id, err, r["result"])
+	}
+}
+
+func (c *session) run(t *testing.T) (output, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	err := Serve(&c.in, &out)
+
+	o := output{responses: map[int]map[string]json.RawMessage{}}
+	reader := wire.NewConn(&out, nil)
+	for {
+		content, readErr := reader.Read()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+
+		var msg map[string]json.RawMessage
+		if err := json.Unmarshal(content, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if id, ok := msg["id"]; ok {
+			var n int
+			json.Unmarshal(id, &n)
+			o.responses[n] = msg
+		} else {
+			o.notifications = append(o.notifications, msg)
+		}
+	}
+	return o, err
+}
+
+func (c *session) open(uri, text string) {
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "simply", "version": 1, "text": text},
+	})
+}
+
+func (c *session) exit() {
+	c.request("shutdown", nil)
+	c.notify("exit", nil)
+}
+
+func at(uri string, line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	c := &session{}
+	initialize := c.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	c.notify("initialized", map[string]any{})
+	unknown := c.request("workspace/unknown", nil)
+	c.notify("$/cancelRequest", map[string]any{"id": 1})
+	c.exit()
+
+	out, err := c.run(t)
+	if err != nil {
+		t.Fatalf("expected a clean exit, got %v", err)
+	}
+
+	var result InitializeResult
+	out.result(t, initialize, &result)
+	if !result.Capabilities.HoverProvider || result.Capabilities.TextDocumentSync != 1 || result.ServerInfo.Name != "simply" {
+		t.Errorf("unexpected capabilities %+v", result)
+	}
+
+	var respErr ResponseError
+	json.Unmarshal(out.responses[unknown]["error"], &respErr)
+	if respErr.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %+v", respErr)
+	}
+
+	c = &session{}
+	c.notify("exit", nil)
+	if _, err := c.run(t); !errors.Is(err, ErrExitWithoutShutdown) {
+		t.Errorf("expected an exit without shutdown error, got %v", err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := &session{}
+	c.open("file:///a.syn", "let x = ;")
+	c.open("file:///b.syn", "func f(a) { 1 }\nf(\"s\" + 1);")
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": "file:///a.syn", "version": 2},
+		"contentChanges": []map[string]any{{"text": "let x = 1;"}},
+	})
+	c.notify("textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": "file:///b.syn"}})
+	c.exit()
+
+	out, err := c.run(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var published []PublishDiagnosticsParams
+	for _, n := range out.notifications {
+		var params PublishDiagnosticsParams
+		json.Unmarshal(n["params"], &params)
+		published = append(published, params)
+	}
+	if len(published) != 4 {
+		t.Fatalf("expected 4 notifications, got %d", len(published))
+	}
+
+	syntax := published[0].Diagnostics
+	if len(syntax) == 0 || syntax[0].Severity != SeverityError || syntax[0].Source != "simply" ||
+		syntax[0].Range.Start != (Position{Line: 0, Character: 8}) {
+		t.Errorf("unexpected syntax diagnostics %+v", syntax)
+	}
+
+	expected := []Diagnostic{
+		{Range: Range{Start: Position{0, 7}, End: Position{0, 8}}, Severity: SeverityWarning, Code: "unused-parameter",
+			Source: "simply lint", Message: "parameter a is never used"},
+		{Range: Range{Start: Position{1, 2}, End: Position{1, 3}}, Severity: SeverityError,
+			Source: "simply check", Message: "invalid operation: string + int"},
+	}
+	got := published[1].Diagnostics
+	if len(got) == 2 && got[0].Source == "simply check" {
+		got[0], got[1] = got[1], got[0]
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	if published[2].Version != 2 || len(published[2].Diagnostics) != 0 {
+		t.Errorf("expected the change to clear the diagnostics, got %+v", published[2])
+	}
+	if published[3].URI != "file:///b.syn" || len(published[3].Diagnostics) != 0 {
+		t.Errorf("expected closing to clear the diagnostics, got %+v", published[3])
+	}
+}
+
+func TestHalfTypedDocument(t *testing.T) {
+	//Every prefix of the source, as typed, gets diagnostics
+	text := "struct P { x func m() { self.x } }\nlet p = P(1);\np.m(p.x).(1);\na. + 1;"
+	c := &session{}
+	c.open("file:///a.syn", "")
+	for i := 1; i <= len(text); i++ {
+		c.notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": "file:///a.syn", "version": i + 1},
+			"contentChanges": []map[string]any{{"text": text[:i]}},
+		})
+	}
+	hover := c.request("textDocument/hover", at("file:///a.syn", 2, 2))
+	c.exit()
+
+	out, err := c.run(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.notifications) != len(text)+1 {
+		t.Errorf("expected diagnostics for every change, got %d notifications", len(out.notifications))
+	}
+	if r, ok := out.responses[hover]; !ok || r["error"] != nil {
+		t.Errorf("expected hover to answer, got %s", r["error"])
+	}
+}
+
+func TestHandlerPanic(t *testing.T) {
+	handlers["test/panic"] = func(s *Server, params json.RawMessage) (any, error) { panic("boom") }
+	defer delete(handlers, "test/panic")
+
+	c := &session{}
+	failed := c.request("test/panic", nil)
+	c.notify("test/panic", nil)
+	initialize := c.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	c.exit()
+
+	out, err := c.run(t)
+	if err != nil {
+		t.Fatalf("expected the server to keep running, got %v", err)
+	}
+	var respErr ResponseError
+	json.Unmarshal(out.responses[failed]["error"], &respErr)
+	if respErr.Code != codeInternalError || respErr.Message != "test/panic: boom" {
+		t.Errorf("expected an internal error, got %+v", respErr)
+	}
+	var result InitializeResult
+	out.result(t, initialize, &result)
+}
+
+const script = `struct Point { x: int, y func norm() { self.x * self.x } }
+func add(a: int, b): int { a + b }
+let total = add(1, 2);
+println(total, len("é"));`
+
+// atText returns the position of the nth occurrence of sub on a line of script.
+func atText(uri string, line int, sub string, nth int) map[string]any {
+	text := strings.Split(script, "\n")[line]
+	col := -1
+	for i := 0; i <= nth; i++ {
+		col += 1 + strings.Index(text[col+1:], sub)
+	}
+	return at(uri, line, col)
+}
+
+func TestNavigation(t *testing.T) {
+	const uri = "file:///nav.syn"
+	c := &session{}
+	c.open(uri, script)
+
+	hoverAdd := c.request("textDocument/hover", at(uri, 2, 13))
+	hoverLen := c.request("textDocument/hover", atText(uri, 3, "len", 0))
+	hoverField := c.request("textDocument/hover", atText(uri, 0, "x", 2))
+	hoverParam := c.request("textDocument/hover", atText(uri, 1, "a", 3))
+	hoverNothing := c.request("textDocument/hover", at(uri, 1, 25))
+	definition := c.request("textDocument/definition", atText(uri, 3, "total", 0))
+	refs := c.request("textDocument/references", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": 2, "character": 12},
+		"context":      map[string]any{"includeDeclaration": true},
+	})
+	symbols := c.request("textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	c.exit()
+
+	out, err := c.run(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hovers := []struct {
+		id       int
+		expected string
+	}{
+		{hoverAdd, "```simply\nfunc add(a: int, b): int\n```\n"},
+		{hoverField, "```simply\nfield Point.x: int\n```\n"},
+		{hoverParam, "```simply\nparameter a: int\n```\n"},
+	}
+	for _, h := range hovers {
+		var hover Hover
+		out.result(t, h.id, &hover)
+		if hover.Contents.Value != h.expected {
+			t.Errorf("expected hover %q, got %q", h.expected, hover.Contents.Value)
+		}
+	}
+
+	var hover Hover
+	out.result(t, hoverLen, &hover)
+	if !strings.HasPrefix(hover.Contents.Value, "```simply\nlen(") {
+		t.Errorf("expected the signature of len, got %q", hover.Contents.Value)
+	}
+	if string(out.responses[hoverNothing]["result"]) != "null" {
+		t.Errorf("expected no hover, got %s", out.responses[hoverNothing]["result"])
+	}
+
+	var location Location
+	out.result(t, definition, &location)
+	if location.URI != uri || location.Range != (Range{Start: Position{2, 4}, End: Position{2, 9}}) {
+		t.Errorf("unexpected definition %+v", location)
+	}
+
+	var locations []Location
+	out.result(t, refs, &locations)
+	if len(locations) != 2 || locations[0].Range.Start != (Position{1, 5}) || locations[1].Range.Start != (Position{2, 12}) {
+		t.Errorf("unexpected references %+v", locations)
+	}
+
+	var outline []DocumentSymbol
+	out.result(t, symbols, &outline)
+	var names []string
+	for _, s := range outline {
+		names = append(names, fmt.Sprintf("%s:%d", s.Name, s.Kind))
+		for _, child := range s.Children {
+			names = append(names, fmt.Sprintf("  %s:%d", child.Name, child.Kind))
+		}
+	}
+	expected := []string{"Point:23", "  x:8", "  y:8", "  norm:6", "add:12", "total:13"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected symbols %v, got %v", expected, names)
+	}
+	if outline[0].Range.End != (Position{0, 58}) {
+		t.Errorf("expected the struct to end after its brace, got %+v", outline[0].Range)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := &session{}
+	c.open("file:///c.syn", "let value = 1;\nva")
+	c.open("file:///m.syn", "let s = \"a\";\ns.")
+	c.open("file:///s.syn", "struct P { x func f() { self.x } }")
+	names := c.request("textDocument/completion", at("file:///c.syn", 1, 2))
+	methods := c.request("textDocument/completion", at("file:///m.syn", 1, 2))
+	members := c.request("textDocument/completion", at("file:///s.syn", 0, 29))
+	c.exit()
+
+	out, err := c.run(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := func(id int) map[string]CompletionItemKind {
+		var items []CompletionItem
+		out.result(t, id, &items)
+		result := map[string]CompletionItemKind{}
+		for _, item := range items {
+			result[item.Label] = item.Kind
+		}
+		return result
+	}
+
+	got := labels(names)
+	if got["value"] != CompletionVariable || got["len"] != CompletionFunction || got["let"] != CompletionKeyword {
+		t.Errorf("expected variables, builtins and keywords, got %v", got)
+	}
+	if got := labels(methods); got["split"] != CompletionMethod || got["len"] != CompletionMethod || got["let"] != 0 {
+		t.Errorf("expected the methods of built-in types, got %v", got)
+	}
+	if got := labels(members); !reflect.DeepEqual(got, map[string]CompletionItemKind{"x": CompletionField, "f": CompletionMethod}) {
+		t.Errorf("expected the members of P, got %v", got)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := &session{}
+	c.open("file:///f.syn", "let x=1\nlet y = [1,\n2]")
+	c.open("file:///g.syn", "let = 1")
+	edits := c.request("textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": "file:///f.syn"}})
+	invalid := c.request("textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": "file:///g.syn"}})
+	closed := c.request("textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": "file:///none.syn"}})
+	c.exit()
+
+	out, err := c.run(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, _ := printer.Format("let x=1\nlet y = [1,\n2]")
+	var got []TextEdit
+	out.result(t, edits, &got)
+	expected := []TextEdit{{Range: Range{End: Position{2, 2}}, NewText: formatted}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	if string(out.responses[invalid]["result"]) != "null" {
+		t.Errorf("expected no edits for invalid source, got %s", out.responses[invalid]["result"])
+	}
+	if out.responses[closed]["error"] == nil {
+		t.Error("expected formatting a document that is not open to fail")
+	}
+}
+
+func TestPositions(t *testing.T) {
+	lines := []string{`let s = "é😀x";`}
+
+	tests := []struct {
+		col      int
+		expected int
+	}{
+		{1, 0},
+		{12, 10}, //After é, two bytes but one unit
+		{16, 12}, //After 😀, four bytes but two units
+	}
+
+	for _, tt := range tests {
+		p := toPosition(lines, ast.Position{Row: 1, Col: tt.col})
+		if p.Character != tt.expected {
+			t.Errorf("col %d: expected character %d, got %d", tt.col, tt.expected, p.Character)
+		}
+		if back := fromPosition(lines, p); back.Col != tt.col {
+			t.Errorf("character %d: expected col %d, got %d", p.Character, tt.col, back.Col)
+		}
+	}
+}
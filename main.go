@@ -2,16 +2,386 @@ package main
 
 import (
 	"Simply/interpreter"
+	"Simply/lint"
+	"Simply/lsp"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
+const (
+	exitOK    = 0
+	exitError = 1 //Parse, check or runtime error
+	exitUsage = 2
+)
+
+const usage = `Usage:
+  simply [file [args...]]       run a script, or start the REPL without a file
+  simply -e 'expr' [args...]    evaluate an expression
+  simply [run flags] [file|-] [args...]
+                                run a script with the flags of run, like -trace
+  simply <command> [arguments]
+
+Commands:
+  run [-e 'expr'] [file|-] [args...]    run a script, "-" or no file reads it from stdin
+  run -ast <file|-> [args...]           run a syntax tree written by ast -json
+  run -profile <out> [-top n] <file|-> [args...]
+                                        run a script and report the functions and lines
+                                        it spends the most time in, out gets a pprof profile
+  run -cover [-coverprofile out] [-coverhtml out] <file> [args...]
+                                        run a script and report the statements and branches
+                                        that ran, as LCOV and annotated HTML with the flags
+  run -trace [-tracefunc f,g] <file|-> [args...]
+                                        run a script and log its statements, calls and
+                                        returns to stderr, only those of f and g if given
+  repl                                  start the interactive shell
+  check <file>                          type check a script
+  lint [-config file] <files...>        report likely mistakes in scripts
+  lsp                                   start a language server on stdin and stdout
+  debug <file> [args...]                step through a script with breakpoints
+  debug -dap                            start a debug adapter on stdin and stdout
+  fmt [-w|-check] [files...]            format scripts, "-" or no file reads stdin
+  ast [-json] <file>                    print the syntax tree of a script
+  tokens [-json] <file>                 print the tokens of a script
+  test [-run regexp] [-format text|tap|junit] [-v] [paths...]
+                                        run the test_ functions of the *_test.syn files in
+                                        paths, the current directory by default, -cover
+                                        and its report flags work as with run
+`
+
+type command func(args []string, s interpreter.Streams) int
+
+var commands = map[string]command{
+	"run":    runCommand,
+	"repl":   replCommand,
+	"check":  fileCommand("check", interpreter.CheckFile),
+	"ast":    jsonCommand("ast", interpreter.PrintAST),
+	"tokens": jsonCommand("tokens", interpreter.PrintTokens),
+	"lint":   lintCommand,
+	"lsp":    lspCommand,
+	"debug":  debugCommand,
+	"fmt":    fmtCommand,
+	"test":   testCommand,
+}
+
 func main() {
+	os.Exit(run(os.Args[1:], interpreter.Streams{In: os.Stdin, Out: os.Stdout, Err: os.Stderr}))
+}
+
+func run(args []string, s interpreter.Streams) int {
+	if len(args) == 0 {
+		return replCommand(nil, s)
+	}
+
+	if cmd, ok := commands[args[0]]; ok {
+		return cmd(args[1:], s)
+	}
+
+	switch arg := args[0]; {
+	case arg == "-h" || arg == "-help" || arg == "--help" || arg == "help":
+		fmt.Fprint(s.Out, usage)
+		return exitOK
+	case arg == "-" || isRunFlag(arg):
+		return runCommand(args, s)
+	case strings.HasPrefix(arg, "-"):
+		fmt.Fprintf(s.Err, "unknown flag %s\n%s", arg, usage)
+		return exitUsage
+	}
+
+	return runCommand(args, s)
+}
+
+// runFlags are the flags of run, which can be given without the command too.
+type runFlags struct {
+	expr       *string
+	syntaxTree *bool
+	profile    *string
+	top        *int
+	cover      coverFlags
+	trace      *bool
+	traceFunc  *string
+}
+
+func newRunFlags(out io.Writer) (*flag.FlagSet, runFlags) {
+	flags := newFlagSet("run", out)
+	return flags, runFlags{
+		expr:       flags.String("e", "", "evaluate the expression and print its value"),
+		syntaxTree: flags.Bool("ast", false, "the script is a syntax tree in JSON"),
+		profile:    flags.String("profile", "", "write a pprof profile of the script to this file"),
+		top:        flags.Int("top", 10, "number of functions and lines reported with -profile"),
+		cover:      addCoverFlags(flags),
+		trace:      flags.Bool("trace", false, "log the statements, calls and returns of the script"),
+		traceFunc:  flags.String("tracefunc", "", "only trace these functions, separated by commas, implies -trace"),
+	}
+}
+
+// isRunFlag reports whether arg, like -trace or --top=5, is a flag of run.
+func isRunFlag(arg string) bool {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	flags, _ := newRunFlags(io.Discard)
+	return flags.Lookup(name) != nil
+}
+
+func runCommand(args []string, s interpreter.Streams) int {
+	flags, f := newRunFlags(s.Err)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	*f.trace = *f.trace || *f.traceFunc != ""
+	//Profiling, coverage and tracing each observe the evaluation on their own
+	observers := 0
+	for _, on := range []bool{*f.profile != "", f.cover.enabled(), *f.trace} {
+		if on {
+			observers++
+		}
+	}
+
+	//Everything after the script is passed on to it
+	var ok bool
+	switch {
+	case isFlagSet(flags, "e") && *f.syntaxTree:
+		fmt.Fprintf(s.Err, "run: -e and -ast cannot be used together\n%s", usage)
+		return exitUsage
+	case *f.profile != "" && (isFlagSet(flags, "e") || *f.syntaxTree):
+		fmt.Fprintf(s.Err, "run: -profile only runs script files\n%s", usage)
+		return exitUsage
+	case isFlagSet(flags, "top") && *f.profile == "":
+		fmt.Fprintf(s.Err, "run: -top needs -profile\n%s", usage)
+		return exitUsage
+	case observers > 1:
+		fmt.Fprintf(s.Err, "run: -profile, -cover and -trace cannot be used together\n%s", usage)
+		return exitUsage
+	case *f.trace && (isFlagSet(flags, "e") || *f.syntaxTree):
+		fmt.Fprintf(s.Err, "run: -trace only runs scripts\n%s", usage)
+		return exitUsage
+	case *f.trace:
+		var functions []string
+		if *f.traceFunc != "" {
+			functions = strings.Split(*f.traceFunc, ",")
+		}
+		path, scriptArgs := "-", []string(nil)
+		if flags.NArg() > 0 {
+			path, scriptArgs = flags.Arg(0), flags.Args()[1:]
+		}
+		ok = interpreter.TraceFile(path, scriptArgs, functions, s)
+	case f.cover.enabled() && (isFlagSet(flags, "e") || *f.syntaxTree || flags.NArg() == 0 || flags.Arg(0) == "-"):
+		fmt.Fprintf(s.Err, "run: -cover needs a script file\n%s", usage)
+		return exitUsage
+	case f.cover.enabled():
+		ok = interpreter.CoverFile(flags.Arg(0), flags.Args()[1:], f.cover.reports(), s)
+	case *f.profile != "" && flags.NArg() == 0:
+		ok = interpreter.ProfileFile("-", nil, *f.profile, *f.top, s)
+	case *f.profile != "":
+		ok = interpreter.ProfileFile(flags.Arg(0), flags.Args()[1:], *f.profile, *f.top, s)
+	case isFlagSet(flags, "e"):
+		ok = interpreter.EvalString(*f.expr, flags.Args(), s)
+	case *f.syntaxTree && flags.NArg() == 0:
+		ok = interpreter.ProcessAST("-", nil, s)
+	case *f.syntaxTree:
+		ok = interpreter.ProcessAST(flags.Arg(0), flags.Args()[1:], s)
+	case flags.NArg() == 0:
+		ok = interpreter.ProcessFile("-", nil, s)
+	default:
+		ok = interpreter.ProcessFile(flags.Arg(0), flags.Args()[1:], s)
+	}
+
+	return exitCode(ok)
+}
+
+// coverFlags are the coverage flags shared by run and test.
+type coverFlags struct {
+	cover   *bool
+	profile *string
+	html    *string
+}
+
+func addCoverFlags(flags *flag.FlagSet) coverFlags {
+	return coverFlags{
+		cover:   flags.Bool("cover", false, "report the statements and branches that ran"),
+		profile: flags.String("coverprofile", "", "write the coverage as LCOV to this file, implies -cover"),
+		html:    flags.String("coverhtml", "", "write the coverage as annotated HTML to this file, implies -cover"),
+	}
+}
+
+func (c coverFlags) enabled() bool {
+	return *c.cover || *c.profile != "" || *c.html != ""
+}
+
+func (c coverFlags) reports() interpreter.CoverageReports {
+	return interpreter.CoverageReports{LCOV: *c.profile, HTML: *c.html}
+}
+
+func replCommand(args []string, s interpreter.Streams) int {
+	if len(args) > 0 {
+		fmt.Fprintf(s.Err, "repl takes no arguments\n%s", usage)
+		return exitUsage
+	}
+
+	fmt.Fprintln(s.Out, "Simply 0.1")
+	interpreter.Start(s.In, s.Out)
+	return exitOK
+}
+
+// fileCommand runs fn on the single file argument.
+func fileCommand(name string, fn func(path string, s interpreter.Streams) bool) command {
+	return func(args []string, s interpreter.Streams) int {
+		flags := newFlagSet(name, s.Err)
+		if err := flags.Parse(args); err != nil {
+			return exitUsage
+		}
+		if flags.NArg() != 1 {
+			fmt.Fprintf(s.Err, "%s takes exactly one file\n%s", name, usage)
+			return exitUsage
+		}
+
+		return exitCode(fn(flags.Arg(0), s))
+	}
+}
+
+// jsonCommand is a fileCommand with a -json flag selecting the output format.
+func jsonCommand(name string, fn func(path string, asJSON bool, s interpreter.Streams) bool) command {
+	return func(args []string, s interpreter.Streams) int {
+		flags := newFlagSet(name, s.Err)
+		asJSON := flags.Bool("json", false, "write JSON instead of text")
+		if err := flags.Parse(args); err != nil {
+			return exitUsage
+		}
+		if flags.NArg() != 1 {
+			fmt.Fprintf(s.Err, "%s takes exactly one file\n%s", name, usage)
+			return exitUsage
+		}
+
+		return exitCode(fn(flags.Arg(0), *asJSON, s))
+	}
+}
+
+func fmtCommand(args []string, s interpreter.Streams) int {
+	flags := newFlagSet("fmt", s.Err)
+	write := flags.Bool("w", false, "write the result to the files instead of stdout")
+	check := flags.Bool("check", false, "list the files that are not formatted and fail if there are any")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	mode := interpreter.FormatPrint
+	switch {
+	case *write && *check:
+		fmt.Fprintf(s.Err, "fmt: -w and -check cannot be used together\n%s", usage)
+		return exitUsage
+	case *write:
+		mode = interpreter.FormatWrite
+	case *check:
+		mode = interpreter.FormatCheck
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	ok := true
+	for _, path := range files {
+		ok = interpreter.FormatFile(path, mode, s) && ok
+	}
+	return exitCode(ok)
+}
+
+func lintCommand(args []string, s interpreter.Streams) int {
+	flags := newFlagSet("lint", s.Err)
+	config := flags.String("config", "", "read the rules from this file instead of the closest "+lint.ConfigFile)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintf(s.Err, "lint takes at least one file\n%s", usage)
+		return exitUsage
+	}
+
+	ok := true
+	for _, path := range flags.Args() {
+		ok = interpreter.LintFile(path, *config, s) && ok
+	}
+	return exitCode(ok)
+}
+
+func lspCommand(args []string, s interpreter.Streams) int {
+	if len(args) > 0 {
+		fmt.Fprintf(s.Err, "lsp takes no arguments\n%s", usage)
+		return exitUsage
+	}
+
+	if err := lsp.Serve(s.In, s.Out); err != nil {
+		fmt.Fprintln(s.Err, "lsp:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func debugCommand(args []string, s interpreter.Streams) int {
+	flags := newFlagSet("debug", s.Err)
+	dap := flags.Bool("dap", false, "speak the debug adapter protocol, the client launches the script")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	switch {
+	case *dap && flags.NArg() > 0:
+		fmt.Fprintf(s.Err, "debug -dap takes no file, the client names it\n%s", usage)
+		return exitUsage
+	case *dap:
+		return exitCode(interpreter.ServeDAP(s))
+	case flags.NArg() == 0:
+		fmt.Fprintf(s.Err, "debug takes a file\n%s", usage)
+		return exitUsage
+	}
+
+	return exitCode(interpreter.DebugFile(flags.Arg(0), flags.Args()[1:], s))
+}
+
+func testCommand(args []string, s interpreter.Streams) int {
+	flags := newFlagSet("test", s.Err)
+	run := flags.String("run", "", "only run the tests whose name matches this regular expression")
+	format := flags.String("format", "text", "write the report as text, tap or junit")
+	verbose := flags.Bool("v", false, "list the passing tests too")
+	cover := addCoverFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	switch *format {
+	case "text", "tap", "junit":
+	default:
+		fmt.Fprintf(s.Err, "test: unknown format %s\n%s", *format, usage)
+		return exitUsage
+	}
+
+	opts := interpreter.TestOptions{Run: *run, Format: *format, Verbose: *verbose, Cover: cover.enabled(), Coverage: cover.reports()}
+	return exitCode(interpreter.RunTests(flags.Args(), opts, s))
+}
+
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() { fmt.Fprint(out, usage) }
+	return flags
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
 
-	if len(os.Args) > 1 {
-		interpreter.ProcessFile(os.Args[1], os.Stdout)
-	} else {
-		fmt.Println("Simply 0.1")
-		interpreter.Start(os.Stdin, os.Stdout)
+func exitCode(ok bool) int {
+	if ok {
+		return exitOK
 	}
+	return exitError
 }
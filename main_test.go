@@ -0,0 +1,77 @@
+package main
+
+import (
+	"Simply/interpreter"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	ok, bad := filepath.Join(dir, "ok.syn"), filepath.Join(dir, "bad.syn")
+	for path, content := range map[string]string{
+		ok:  "func f(n) { n + 1 }\nprintln(f(1));\n",
+		bad: "let x = ;\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args     []string
+		stdin    string
+		code     int
+		expected string //Part of what is written to stdout and stderr
+	}{
+		{nil, "1 + 2\n", exitOK, "3\n"},
+		{[]string{"-h"}, "", exitOK, "Usage:"},
+		{[]string{"help"}, "", exitOK, "Usage:"},
+		{[]string{ok}, "", exitOK, "2\n"},
+		{[]string{bad}, "", exitError, bad + ":1:9: Missing prefix parser for ;"},
+		{[]string{"missing.syn"}, "", exitError, "Error reading file"},
+		{[]string{"-"}, "println(1);", exitOK, "1\n"},
+		{[]string{"-e", "1 + 2"}, "", exitOK, "3\n"},
+		{[]string{"-e", "1 + true"}, "", exitError, "-:1:1: Unknown inflix operation 1 + true"},
+		{[]string{"-bogus"}, "", exitUsage, "unknown flag -bogus"},
+		{[]string{"--trace", ok}, "", exitOK, "call f(1)"},
+		{[]string{"-tracefunc=f", ok}, "", exitOK, "return f = 2"},
+
+		{[]string{"run", ok}, "", exitOK, "2\n"},
+		{[]string{"run", bad}, "", exitError, bad + ":1:9:"},
+		{[]string{"run", "-e", "1", "-ast"}, "", exitUsage, "-e and -ast cannot be used together"},
+		{[]string{"run", "-top", "3", ok}, "", exitUsage, "-top needs -profile"},
+		{[]string{"run", "-cover", "-trace", ok}, "", exitUsage, "cannot be used together"},
+		{[]string{"run", "-cover", "-e", "1"}, "", exitUsage, "-cover needs a script file"},
+		{[]string{"run", "-nope"}, "", exitUsage, "flag provided but not defined"},
+
+		{[]string{"check", ok}, "", exitOK, ""},
+		{[]string{"check", bad}, "", exitError, bad + ":1:9:"},
+		{[]string{"check"}, "", exitUsage, "check takes exactly one file"},
+		{[]string{"lint", bad}, "", exitError, bad + ":1:9:"},
+		{[]string{"lint"}, "", exitUsage, "lint takes at least one file"},
+		{[]string{"ast", ok}, "", exitOK, "Program 1:1"},
+		{[]string{"ast", bad}, "", exitError, bad + ":1:9:"},
+		{[]string{"tokens"}, "", exitUsage, "tokens takes exactly one file"},
+		{[]string{"fmt", "-w", "-check"}, "", exitUsage, "-w and -check cannot be used together"},
+		{[]string{"test", "-format", "xml"}, "", exitUsage, "unknown format xml"},
+		{[]string{"repl", "x"}, "", exitUsage, "repl takes no arguments"},
+		{[]string{"lsp", "x"}, "", exitUsage, "lsp takes no arguments"},
+		{[]string{"debug"}, "", exitUsage, "debug takes a file"},
+		{[]string{"debug", "-dap", ok}, "", exitUsage, "debug -dap takes no file"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		code := run(tt.args, interpreter.Streams{In: strings.NewReader(tt.stdin), Out: &out, Err: &out})
+		if code != tt.code {
+			t.Errorf("%q: expected exit code %d, got %d: %s", tt.args, tt.code, code, out.String())
+		}
+		if !strings.Contains(out.String(), tt.expected) {
+			t.Errorf("%q: expected output to contain %q, got %q", tt.args, tt.expected, out.String())
+		}
+	}
+}
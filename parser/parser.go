@@ -11,6 +11,7 @@ type Parser struct {
 	currentToken   lexer.Token
 	lookAheadToken lexer.Token
 	Errors         []string
+	ErrorPositions []ast.Position //Where each of Errors was found
 
 	prefixParseFuncMap map[lexer.TokenType]prefixParseFunc
 	infixParseFuncMap  map[lexer.TokenType]infixParseFunc
@@ -32,16 +33,26 @@ func NewParser(t *lexer.Tokenizer) *Parser {
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
-	program := &ast.Program{Statements: []ast.Node{}}
+	program := &ast.Program{Position: p.position(), Statements: []ast.Node{}}
 
 	for p.currentToken.Type != lexer.EOF {
+		//Empty statements
+		if p.currentTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
 
-		s := p.parseStatement()
-		program.Statements = append(program.Statements, s)
+		if s := p.parseStatement(); s != nil {
+			program.Statements = append(program.Statements, s)
+		}
 
 		p.nextToken()
 	}
 
+	for _, c := range p.t.Comments {
+		program.Comments = append(program.Comments, &ast.Comment{Position: ast.Position{Row: c.Row, Col: c.Col}, Text: c.Literal})
+	}
+
 	return program
 }
 
@@ -50,6 +61,14 @@ func (p *Parser) nextToken() {
 	p.lookAheadToken = p.t.NextToken()
 }
 
+func (p *Parser) position() ast.Position {
+	return ast.Position{Row: p.currentToken.Row, Col: p.currentToken.Col}
+}
+
+func (p *Parser) newIdentifier() *ast.Identifier {
+	return &ast.Identifier{Position: p.position(), Value: p.currentToken.Literal}
+}
+
 func (p *Parser) currentTokenIs(t lexer.TokenType) bool {
 	return p.currentToken.Type == t
 }
@@ -69,6 +88,7 @@ func (p *Parser) assertToken(t lexer.TokenType) bool {
 }
 
 func (p *Parser) logInvalidToken(t lexer.TokenType) {
+	p.ErrorPositions = append(p.ErrorPositions, ast.Position{Row: p.lookAheadToken.Row, Col: p.lookAheadToken.Col})
 	p.Errors = append(p.Errors,
 		fmt.Sprintf("Invalid token: expected %s, got %s",
 			t,
@@ -80,22 +100,48 @@ func (p *Parser) logInvalidToken(t lexer.TokenType) {
 func (p *Parser) parseStatement() ast.Node {
 	switch p.currentToken.Type {
 	case lexer.LET:
-		return p.parseDeclarativeStatement()
+		return orNil(p.parseDeclarativeStatement())
 	case lexer.RETURN:
-		return p.parseReturnStatement()
+		return orNil(p.parseReturnStatement())
+	case lexer.STRUCT:
+		return orNil(p.parseStructStatement())
+	case lexer.TRAIT:
+		return orNil(p.parseTraitStatement())
+	case lexer.IMPORT:
+		return orNil(p.parseImportStatement())
+	case lexer.EXPORT:
+		return orNil(p.parseExportStatement())
+	case lexer.FUNCTION:
+		if p.nextTokenIs(lexer.IDENTIFIER) {
+			return orNil(p.parseFunctionStatement())
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
+// orNil returns a statement that failed to parse as a nil node rather than a
+// node holding a nil pointer, which would not compare equal to nil.
+func orNil[S any, P interface {
+	*S
+	ast.Node
+}](s P) ast.Node {
+	if s == nil {
+		return nil
+	}
+	return s
+}
+
 func (p *Parser) parseDeclarativeStatement() *ast.DeclarativeStatement {
-	s := &ast.DeclarativeStatement{}
+	s := &ast.DeclarativeStatement{Position: p.position()}
 
 	if !p.assertToken(lexer.IDENTIFIER) {
 		return nil
 	}
 
-	s.Name = ast.Identifier{Value: p.currentToken.Literal}
+	s.Name = *p.newIdentifier()
+	s.Name.Type = p.parseTypeAnnotation()
 
 	if !p.assertToken(lexer.ASSIGN) {
 		return nil
@@ -113,21 +159,24 @@ func (p *Parser) parseDeclarativeStatement() *ast.DeclarativeStatement {
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
-	s := &ast.ReturnStatement{}
+	s := &ast.ReturnStatement{Position: p.position()}
 	p.nextToken()
 
 	s.Value = p.parseExpression((LOWEST))
 
-	for !p.currentTokenIs(lexer.SEMICOLON) {
+	if p.nextTokenIs(lexer.SEMICOLON) {
 		p.nextToken()
 	}
 	return s
 }
 
 func (p *Parser) parseExpressionStatement() ast.Node {
-	e := &ast.ExpressionStatement{}
+	e := &ast.ExpressionStatement{Position: p.position()}
 
 	e.Expression = p.parseExpression(LOWEST)
+	if e.Expression == nil {
+		return nil
+	}
 
 	if p.nextTokenIs(lexer.SEMICOLON) {
 		p.nextToken()
@@ -135,3 +184,198 @@ func (p *Parser) parseExpressionStatement() ast.Node {
 
 	return e
 }
+
+func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
+	s := &ast.FunctionStatement{Position: p.position()}
+
+	if !p.assertToken(lexer.IDENTIFIER) {
+		return nil
+	}
+
+	s.Name = *p.newIdentifier()
+
+	fl, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok || fl == nil {
+		return nil
+	}
+
+	s.Function = fl
+
+	if p.nextTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return s
+}
+
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	s := &ast.StructStatement{Position: p.position()}
+
+	if !p.assertToken(lexer.IDENTIFIER) {
+		return nil
+	}
+
+	s.Name = *p.newIdentifier()
+
+	if p.nextTokenIs(lexer.IMPL) {
+		p.nextToken()
+		if !p.assertToken(lexer.IDENTIFIER) {
+			return nil
+		}
+		s.Traits = append(s.Traits, p.newIdentifier())
+		for p.nextTokenIs(lexer.COMMA) {
+			p.nextToken()
+			if !p.assertToken(lexer.IDENTIFIER) {
+				return nil
+			}
+			s.Traits = append(s.Traits, p.newIdentifier())
+		}
+	}
+
+	if !p.assertToken(lexer.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+
+	for !p.currentTokenIs(lexer.RBRACE) {
+		switch p.currentToken.Type {
+		case lexer.IDENTIFIER:
+			field := p.newIdentifier()
+			field.Type = p.parseTypeAnnotation()
+			s.Fields = append(s.Fields, field)
+		case lexer.FUNCTION:
+			m := p.parseFunctionStatement()
+			if m == nil {
+				return nil
+			}
+			s.Methods = append(s.Methods, m)
+		case lexer.COMMA, lexer.SEMICOLON:
+		default:
+			p.logParseError("Unexpected token in struct %s: %s", s.Name.Value, p.currentToken.Literal)
+			return nil
+		}
+
+		p.nextToken()
+	}
+	s.Rbrace = p.position()
+
+	if p.nextTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return s
+}
+
+func (p *Parser) parseTraitStatement() *ast.TraitStatement {
+	s := &ast.TraitStatement{Position: p.position()}
+
+	if !p.assertToken(lexer.IDENTIFIER) {
+		return nil
+	}
+
+	s.Name = *p.newIdentifier()
+
+	if !p.assertToken(lexer.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+
+	for !p.currentTokenIs(lexer.RBRACE) {
+		switch p.currentToken.Type {
+		case lexer.IDENTIFIER:
+			m := &ast.TraitMethod{Position: p.position(), Name: *p.newIdentifier()}
+			if !p.assertToken(lexer.LPAREN) {
+				return nil
+			}
+			m.Parameters = p.parseFunctionParameters()
+			if m.Parameters == nil {
+				return nil
+			}
+			s.Methods = append(s.Methods, m)
+		case lexer.COMMA, lexer.SEMICOLON:
+		default:
+			p.logParseError("Unexpected token in trait %s: %s", s.Name.Value, p.currentToken.Literal)
+			return nil
+		}
+
+		p.nextToken()
+	}
+	s.Rbrace = p.position()
+
+	if p.nextTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return s
+}
+
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	if !p.nextTokenIs(lexer.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+
+	//func is a keyword but also a valid type name
+	if p.nextTokenIs(lexer.FUNCTION) {
+		p.nextToken()
+		return &ast.TypeAnnotation{Position: p.position(), Name: p.currentToken.Literal}
+	}
+
+	if !p.assertToken(lexer.IDENTIFIER) {
+		return nil
+	}
+
+	return &ast.TypeAnnotation{Position: p.position(), Name: p.currentToken.Literal}
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	s := &ast.ImportStatement{Position: p.position()}
+
+	if !p.assertToken(lexer.STRING) {
+		return nil
+	}
+
+	s.Path = &ast.StringLiteral{Position: p.position(), Value: p.currentToken.Literal}
+
+	if p.nextTokenIs(lexer.AS) {
+		p.nextToken()
+		if !p.assertToken(lexer.IDENTIFIER) {
+			return nil
+		}
+		s.Alias = p.newIdentifier()
+	}
+
+	if p.nextTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return s
+}
+
+func (p *Parser) parseExportStatement() *ast.ExportStatement {
+	s := &ast.ExportStatement{Position: p.position()}
+
+	p.nextToken()
+
+	switch p.currentToken.Type {
+	case lexer.LET:
+		s.Statement = orNil(p.parseDeclarativeStatement())
+	case lexer.FUNCTION:
+		s.Statement = orNil(p.parseFunctionStatement())
+	case lexer.STRUCT:
+		s.Statement = orNil(p.parseStructStatement())
+	case lexer.TRAIT:
+		s.Statement = orNil(p.parseTraitStatement())
+	default:
+		p.logParseError("Unexpected token after export: %s", p.currentToken.Literal)
+		return nil
+	}
+	if s.Statement == nil {
+		return nil
+	}
+
+	return s
+}
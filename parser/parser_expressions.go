@@ -11,12 +11,14 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGNMENT
 	EQUALS
 	LESSGREATER
 	SUM
 	PRODUCT
 	PREFIX
 	CALL
+	INDEX
 )
 
 type (
@@ -25,6 +27,7 @@ type (
 )
 
 var precedences = map[lexer.TokenType]int{
+	lexer.ASSIGN:   ASSIGNMENT,
 	lexer.EQ:       EQUALS,
 	lexer.NOT_EQ:   EQUALS,
 	lexer.LT:       LESSGREATER,
@@ -34,6 +37,8 @@ var precedences = map[lexer.TokenType]int{
 	lexer.SLASH:    PRODUCT,
 	lexer.ASTERISK: PRODUCT,
 	lexer.LPAREN:   CALL,
+	lexer.DOT:      CALL,
+	lexer.LBRACKET: INDEX,
 }
 
 func (p *Parser) nextPrecedence() int {
@@ -57,6 +62,7 @@ func (p *Parser) registerPrefixParsers() {
 	p.prefixParseFuncMap[lexer.IF] = p.parseIfExpression
 
 	p.prefixParseFuncMap[lexer.INT] = p.parseIntegerLiteral
+	p.prefixParseFuncMap[lexer.FLOAT] = p.parseFloatLiteral
 	p.prefixParseFuncMap[lexer.TRUE] = p.parseBooleanLiteral
 	p.prefixParseFuncMap[lexer.FALSE] = p.parseBooleanLiteral
 	p.prefixParseFuncMap[lexer.STRING] = p.parseStringLiteral
@@ -65,6 +71,8 @@ func (p *Parser) registerPrefixParsers() {
 	p.prefixParseFuncMap[lexer.BANG] = p.parsePrefixExpression
 
 	p.prefixParseFuncMap[lexer.LPAREN] = p.parseGroupedExpression
+	p.prefixParseFuncMap[lexer.LBRACKET] = p.parseArrayLiteral
+	p.prefixParseFuncMap[lexer.LBRACE] = p.parseMapLiteral
 }
 
 func (p *Parser) registerInfixParsers() {
@@ -77,10 +85,14 @@ func (p *Parser) registerInfixParsers() {
 	p.infixParseFuncMap[lexer.LT] = p.parseInfixExpression
 	p.infixParseFuncMap[lexer.GT] = p.parseInfixExpression
 	p.infixParseFuncMap[lexer.LPAREN] = p.parseCallExpression
+	p.infixParseFuncMap[lexer.DOT] = p.parseMemberExpression
+	p.infixParseFuncMap[lexer.LBRACKET] = p.parseIndexExpression
+	p.infixParseFuncMap[lexer.ASSIGN] = p.parseAssignmentExpression
 }
 
-func (p *Parser) logParseError(msg string, args ...string) {
-	p.Errors = append(p.Errors, fmt.Sprintf(msg, args))
+func (p *Parser) logParseError(msg string, args ...interface{}) {
+	p.ErrorPositions = append(p.ErrorPositions, p.position())
+	p.Errors = append(p.Errors, fmt.Sprintf(msg, args...))
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Node {
@@ -91,6 +103,9 @@ func (p *Parser) parseExpression(precedence int) ast.Node {
 	}
 
 	leftExpression := prefixFunc()
+	if leftExpression == nil {
+		return nil
+	}
 
 	for !p.nextTokenIs(lexer.SEMICOLON) && precedence < p.nextPrecedence() {
 		infixFunc := p.infixParseFuncMap[p.lookAheadToken.Type]
@@ -102,23 +117,27 @@ func (p *Parser) parseExpression(precedence int) ast.Node {
 		p.nextToken()
 
 		leftExpression = infixFunc(leftExpression)
+		if leftExpression == nil {
+			return nil
+		}
 	}
 
 	return leftExpression
 }
 
 func (p *Parser) parseIdentifier() ast.Node {
-	return &ast.Identifier{Value: p.currentToken.Literal}
+	return p.newIdentifier()
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Node {
-	fl := &ast.FunctionLiteral{}
+	fl := &ast.FunctionLiteral{Position: p.position()}
 
 	if !p.assertToken(lexer.LPAREN) {
 		return nil
 	}
 
 	fl.Parameters = p.parseFunctionParameters()
+	fl.ReturnType = p.parseTypeAnnotation()
 
 	if !p.assertToken(lexer.LBRACE) {
 		return nil
@@ -130,13 +149,13 @@ func (p *Parser) parseFunctionLiteral() ast.Node {
 }
 
 func (p *Parser) parseIfExpression() ast.Node {
+	c := &ast.ConditionalExpression{Position: p.position()}
+
 	if !p.assertToken(lexer.LPAREN) {
 		return nil
 	}
 	p.nextToken()
 
-	c := &ast.ConditionalExpression{}
-
 	c.Condition = p.parseExpression(LOWEST)
 	if !p.assertToken(lexer.RPAREN) {
 		return nil
@@ -164,12 +183,14 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 	p.nextToken()
-	ident := &ast.Identifier{Value: p.currentToken.Literal}
+	ident := p.newIdentifier()
+	ident.Type = p.parseTypeAnnotation()
 	identifiers = append(identifiers, ident)
 	for p.nextTokenIs(lexer.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Value: p.currentToken.Literal}
+		ident := p.newIdentifier()
+		ident.Type = p.parseTypeAnnotation()
 		identifiers = append(identifiers, ident)
 	}
 	if !p.assertToken(lexer.RPAREN) {
@@ -179,19 +200,25 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 }
 
 func (p *Parser) parseCodeBlock() *ast.CodeBlock {
-	block := &ast.CodeBlock{}
+	block := &ast.CodeBlock{Position: p.position()}
 	block.Statements = []ast.Node{}
 	p.nextToken()
 	for !p.currentTokenIs(lexer.RBRACE) && !p.currentTokenIs(lexer.EOF) {
-		s := p.parseStatement()
-		block.Statements = append(block.Statements, s)
+		if p.currentTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		if s := p.parseStatement(); s != nil {
+			block.Statements = append(block.Statements, s)
+		}
 		p.nextToken()
 	}
+	block.Rbrace = p.position()
 	return block
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Node {
-	il := ast.IntLiteral{}
+	il := ast.IntLiteral{Position: p.position()}
 
 	v, e := strconv.ParseInt(p.currentToken.Literal, 0, 64)
 	if e != nil {
@@ -203,16 +230,29 @@ func (p *Parser) parseIntegerLiteral() ast.Node {
 	return &il
 }
 
+func (p *Parser) parseFloatLiteral() ast.Node {
+	fl := &ast.FloatLiteral{Position: p.position()}
+
+	v, e := strconv.ParseFloat(p.currentToken.Literal, 64)
+	if e != nil {
+		p.logParseError("Failed to parse float: %s", p.currentToken.Literal)
+	}
+
+	fl.Value = v
+
+	return fl
+}
+
 func (p *Parser) parseBooleanLiteral() ast.Node {
-	return &ast.BoolLiteral{Value: p.currentTokenIs(lexer.TRUE)}
+	return &ast.BoolLiteral{Position: p.position(), Value: p.currentTokenIs(lexer.TRUE)}
 }
 
 func (p *Parser) parseStringLiteral() ast.Node {
-	return &ast.StringLiteral{Value: p.currentToken.Literal}
+	return &ast.StringLiteral{Position: p.position(), Value: p.currentToken.Literal}
 }
 
 func (p *Parser) parsePrefixExpression() ast.Node {
-	e := &ast.PrefixExpression{Prefix: p.currentToken.Literal}
+	e := &ast.PrefixExpression{Position: p.position(), Prefix: p.currentToken.Literal}
 
 	p.nextToken()
 
@@ -234,7 +274,10 @@ func (p *Parser) parseGroupedExpression() ast.Node {
 }
 
 func (p *Parser) parseInfixExpression(node ast.Node) ast.Node {
-	i := &ast.InfixExpression{Left: node, Operator: p.currentToken.Literal}
+	if node == nil {
+		return nil
+	}
+	i := &ast.InfixExpression{Position: node.Pos(), Left: node, Operator: p.currentToken.Literal}
 	precedence := p.currentPrecedence()
 	p.nextToken()
 	i.Right = p.parseExpression(precedence)
@@ -242,15 +285,50 @@ func (p *Parser) parseInfixExpression(node ast.Node) ast.Node {
 }
 
 func (p *Parser) parseCallExpression(node ast.Node) ast.Node {
-	exp := &ast.CallExpression{Function: node}
+	if node == nil {
+		return nil
+	}
+	exp := &ast.CallExpression{Position: node.Pos(), Function: node}
 	exp.Arguments = p.parseCallArguments()
 
 	return exp
 }
 
+func (p *Parser) parseMemberExpression(node ast.Node) ast.Node {
+	if node == nil {
+		return nil
+	}
+	if !p.assertToken(lexer.IDENTIFIER) {
+		return nil
+	}
+
+	return &ast.MemberExpression{Position: node.Pos(), Object: node, Member: p.newIdentifier()}
+}
+
+func (p *Parser) parseAssignmentExpression(node ast.Node) ast.Node {
+	switch node.(type) {
+	case *ast.Identifier, *ast.MemberExpression, *ast.IndexExpression:
+	case nil:
+		return nil
+	default:
+		p.logParseError("Invalid assignment target")
+		return nil
+	}
+
+	a := &ast.AssignmentExpression{Position: node.Pos(), Target: node}
+	p.nextToken()
+	//Assignment is right associative: a = b = c
+	a.Value = p.parseExpression(LOWEST)
+	return a
+}
+
 func (p *Parser) parseCallArguments() []ast.Node {
+	return p.parseExpressionList(lexer.RPAREN)
+}
+
+func (p *Parser) parseExpressionList(end lexer.TokenType) []ast.Node {
 	args := []ast.Node{}
-	if p.nextTokenIs(lexer.RPAREN) {
+	if p.nextTokenIs(end) {
 		p.nextToken()
 		return args
 	}
@@ -261,8 +339,61 @@ func (p *Parser) parseCallArguments() []ast.Node {
 		p.nextToken()
 		args = append(args, p.parseExpression(LOWEST))
 	}
-	if !p.assertToken(lexer.RPAREN) {
+	if !p.assertToken(end) {
 		return nil
 	}
 	return args
 }
+
+func (p *Parser) parseArrayLiteral() ast.Node {
+	a := &ast.ArrayLiteral{Position: p.position()}
+
+	a.Elements = p.parseExpressionList(lexer.RBRACKET)
+	if a.Elements == nil {
+		return nil
+	}
+
+	return a
+}
+
+func (p *Parser) parseMapLiteral() ast.Node {
+	m := &ast.MapLiteral{Position: p.position(), Pairs: []*ast.MapPair{}}
+
+	for !p.nextTokenIs(lexer.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.assertToken(lexer.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		m.Pairs = append(m.Pairs, &ast.MapPair{Key: key, Value: p.parseExpression(LOWEST)})
+
+		if !p.nextTokenIs(lexer.RBRACE) && !p.assertToken(lexer.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.assertToken(lexer.RBRACE) {
+		return nil
+	}
+
+	return m
+}
+
+func (p *Parser) parseIndexExpression(node ast.Node) ast.Node {
+	if node == nil {
+		return nil
+	}
+	i := &ast.IndexExpression{Position: node.Pos(), Left: node}
+
+	p.nextToken()
+	i.Index = p.parseExpression(LOWEST)
+
+	if !p.assertToken(lexer.RBRACKET) {
+		return nil
+	}
+
+	return i
+}
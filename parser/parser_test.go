@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"Simply/ast"
 	"Simply/lexer"
+	"reflect"
 	"testing"
 )
 
@@ -39,3 +41,155 @@ func checkErrors(t *testing.T, p *Parser) {
 	}
 
 }
+
+func TestStructStatement(t *testing.T) {
+	input := `struct Point {
+	x, y
+	func sum() { return self.x + self.y }
+}`
+	p := NewParser(lexer.NewTokenizer(input))
+	program := p.ParseProgram()
+	checkErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+	}
+
+	s, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("expected *ast.StructStatement, got %T", program.Statements[0])
+	}
+	if s.Name.Value != "Point" || len(s.Fields) != 2 || len(s.Methods) != 1 {
+		t.Fatalf("unexpected struct %s: %d fields, %d methods", s.Name.Value, len(s.Fields), len(s.Methods))
+	}
+	if s.Methods[0].Name.Value != "sum" {
+		t.Fatalf("expected method sum, got %s", s.Methods[0].Name.Value)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []ast.Position
+	}{
+		{"let = 5;", []ast.Position{{Row: 1, Col: 5}, {Row: 1, Col: 5}}},
+		{"let x = 1;\nx + ;", []ast.Position{{Row: 2, Col: 5}}},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewTokenizer(tt.input))
+		p.ParseProgram()
+
+		if len(p.ErrorPositions) != len(p.Errors) {
+			t.Fatalf("%q: %d errors but %d positions", tt.input, len(p.Errors), len(p.ErrorPositions))
+		}
+		if !reflect.DeepEqual(p.ErrorPositions, tt.expected) {
+			t.Errorf("%q: expected %v, got %v (%v)", tt.input, tt.expected, p.ErrorPositions, p.Errors)
+		}
+	}
+}
+
+func TestMalformedExpressions(t *testing.T) {
+	for _, input := range []string{"a.(1)", "a. + 1", "a.()", "a.[0]", "a. = 1", "(a.)(1)", "f(a.)", "a. .b", "(a.).b", "x = ", "a.b = )", "(a.) = 1", "x = a. + 1"} {
+		p := NewParser(lexer.NewTokenizer(input))
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("%q: expected a parse error", input)
+		}
+	}
+}
+
+func TestEmptyStatements(t *testing.T) {
+	tests := []struct {
+		input      string
+		statements int
+	}{
+		{"let a = 1;;", 1},
+		{"println(a);;", 1},
+		{";", 0},
+		{"func f() { 1 };;", 1},
+		{"if (true) { ;a;; };", 1},
+		{"struct P { x };; let b = 2;", 2},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewTokenizer(tt.input))
+		program := p.ParseProgram()
+		checkErrors(t, p)
+		if len(program.Statements) != tt.statements {
+			t.Errorf("%q: expected %d statements, got %d", tt.input, tt.statements, len(program.Statements))
+		}
+	}
+}
+
+func TestFailedStatementsAreLeftOut(t *testing.T) {
+	for _, input := range []string{"let = 5; let x = 1;", "export let = 1;", "if (true) { let = 1; x }", "struct { }", "a.(1); b", "import 1;"} {
+		p := NewParser(lexer.NewTokenizer(input))
+		program := p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("%q: expected a parse error", input)
+		}
+		ast.Inspect(program, func(n ast.Node) bool {
+			if n != nil && reflect.ValueOf(n).IsNil() {
+				t.Errorf("%q: statement %T that failed to parse is in the tree", input, n)
+			}
+			return true
+		})
+	}
+}
+
+func TestAssignmentTargets(t *testing.T) {
+	tests := []struct {
+		input  string
+		target string
+	}{
+		{"self.x = 1", "*ast.MemberExpression"},
+		{"p.x = p.y = 2", "*ast.MemberExpression"},
+		{"x = 1", "*ast.Identifier"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewTokenizer(tt.input))
+		program := p.ParseProgram()
+		checkErrors(t, p)
+
+		s, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("%q: expected *ast.ExpressionStatement, got %T", tt.input, program.Statements[0])
+		}
+		a, ok := s.Expression.(*ast.AssignmentExpression)
+		if !ok {
+			t.Fatalf("%q: expected *ast.AssignmentExpression, got %T", tt.input, s.Expression)
+		}
+		if target := reflect.TypeOf(a.Target).String(); target != tt.target {
+			t.Errorf("%q: expected target %s, got %s", tt.input, tt.target, target)
+		}
+	}
+
+	p := NewParser(lexer.NewTokenizer("1 = 2"))
+	p.ParseProgram()
+	if len(p.Errors) != 1 || p.Errors[0] != "Invalid assignment target" {
+		t.Errorf("expected an invalid assignment target, got %v", p.Errors)
+	}
+}
+
+func TestStructStatementErrors(t *testing.T) {
+	//Methods end with a return and no semicolon, and a wrong token names the struct it is in
+	p := NewParser(lexer.NewTokenizer("struct P { func get() { return self.x } + }"))
+	p.ParseProgram()
+
+	expected := "Unexpected token in struct P: +"
+	if len(p.Errors) == 0 || p.Errors[0] != expected {
+		t.Errorf("expected %q first, got %v", expected, p.Errors)
+	}
+}
+
+func TestMalformedIndexExpressions(t *testing.T) {
+	for _, input := range []string{"a[", "a[0", "a[)]", "(a[)[0]", "a[.][0]", "a[0 = 1", "a[] = 1", "f(a[)", "a[0].", "m[a.] = 1"} {
+		p := NewParser(lexer.NewTokenizer(input))
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("%q: expected a parse error", input)
+		}
+	}
+}
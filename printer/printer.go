@@ -0,0 +1,536 @@
+// Package printer renders syntax trees as canonical source.
+package printer
+
+import (
+	"Simply/ast"
+	"Simply/lexer"
+	"Simply/parser"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const indentation = "    "
+
+// Operator precedences, mirroring the parser.
+const (
+	lowest = iota
+	assignment
+	equals
+	lessGreater
+	sum
+	product
+	prefix
+	postfix
+	primary
+)
+
+var operatorPrecedence = map[string]int{
+	"==": equals,
+	"!=": equals,
+	"<":  lessGreater,
+	">":  lessGreater,
+	"+":  sum,
+	"-":  sum,
+	"*":  product,
+	"/":  product,
+}
+
+type printer struct {
+	buf        bytes.Buffer
+	indent     int
+	blockStart bool           //Nothing printed since the last opening brace
+	comments   []*ast.Comment //Comments not printed yet
+	lastRow    int            //Last source row printed, to place comments
+	lines      []string       //Source lines when known, to keep blank lines
+	err        error
+}
+
+// Fprint writes the node as canonical source. Comments of a program are kept,
+// blocks and literals that span several lines in the source stay multi-line.
+func Fprint(w io.Writer, node ast.Node) error {
+	return fprint(w, node, nil)
+}
+
+func fprint(w io.Writer, node ast.Node, lines []string) error {
+	p := &printer{lines: lines}
+	if program, ok := node.(*ast.Program); ok {
+		p.comments = program.Comments
+	}
+
+	p.node(node)
+	if p.err != nil {
+		return p.err
+	}
+
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// Sprint returns the node as canonical source.
+func Sprint(node ast.Node) string {
+	var sb strings.Builder
+	Fprint(&sb, node)
+	return sb.String()
+}
+
+// Format parses the source and returns it in canonical form, unlike Fprint it
+// also keeps single blank lines between statements.
+func Format(source string) (string, error) {
+	p := parser.NewParser(lexer.NewTokenizer(source))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		return "", errors.New(strings.Join(p.Errors, "\n"))
+	}
+
+	var sb strings.Builder
+	if err := fprint(&sb, program, strings.Split(source, "\n")); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (p *printer) print(args ...string) {
+	for _, a := range args {
+		p.buf.WriteString(a)
+	}
+}
+
+func (p *printer) newline() {
+	p.buf.WriteString("\n")
+	p.buf.WriteString(strings.Repeat(indentation, p.indent))
+}
+
+func (p *printer) visit(pos ast.Position) {
+	p.lastRow = max(p.lastRow, pos.Row)
+}
+
+// flushComments prints the comments that come before pos, each on its own line.
+func (p *printer) flushComments(pos ast.Position) {
+	for len(p.comments) > 0 && before(p.comments[0].Position, pos) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if p.buf.Len() > 0 {
+			p.separate(c.Row)
+		}
+		p.print(c.Text)
+		p.visit(c.Position)
+	}
+}
+
+// trailingComment prints a comment that follows the last printed code on its row.
+func (p *printer) trailingComment() {
+	if len(p.comments) > 0 && p.comments[0].Row == p.lastRow {
+		p.print(" ", p.comments[0].Text)
+		p.comments = p.comments[1:]
+	}
+}
+
+// statementComment prints a comment that trails a statement. Closing brackets are
+// not visited, so a comment after those on a later row still belongs to it.
+func (p *printer) statementComment() {
+	if len(p.comments) == 0 {
+		return
+	}
+
+	c := p.comments[0]
+	if line, ok := p.line(c.Row); ok && c.Row > p.lastRow && c.Col-1 <= len(line) {
+		code := strings.TrimSpace(line[:c.Col-1])
+		if code != "" && strings.Trim(code, "])};, \t") == "" {
+			p.lastRow = c.Row
+		}
+	}
+
+	p.trailingComment()
+}
+
+// separate starts a new line, keeping one blank line where the source had some.
+func (p *printer) separate(row int) {
+	if p.blockStart {
+		p.blockStart = false
+	} else if line, ok := p.line(row - 1); ok && strings.TrimSpace(line) == "" {
+		p.buf.WriteString("\n")
+	}
+	p.newline()
+}
+
+// line returns the source line at row, counting from 1.
+func (p *printer) line(row int) (string, bool) {
+	if row < 1 || row > len(p.lines) {
+		return "", false
+	}
+	return p.lines[row-1], true
+}
+
+func before(a, b ast.Position) bool {
+	return a.Row < b.Row || a.Row == b.Row && a.Col < b.Col
+}
+
+// statements prints a statement list, one statement per line.
+func (p *printer) statements(statements []ast.Node) {
+	for i, s := range statements {
+		p.flushComments(s.Pos())
+		if p.buf.Len() > 0 {
+			p.separate(s.Pos().Row)
+		}
+
+		p.statement(s)
+
+		var next ast.Node
+		if i+1 < len(statements) {
+			next = statements[i+1]
+		}
+		if needsSemicolon(s, next) {
+			p.print(";")
+		}
+		p.statementComment()
+	}
+}
+
+// open prints an opening brace or bracket that starts an indented section.
+func (p *printer) open(brace string) {
+	p.print(brace)
+	p.trailingComment()
+	p.blockStart = true
+	p.indent++
+}
+
+func (p *printer) close(brace string, pos ast.Position) {
+	p.flushComments(pos)
+	p.indent--
+	p.blockStart = false
+	p.newline()
+	p.print(brace)
+	p.visit(pos)
+}
+
+func (p *printer) node(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.Program:
+		p.statements(n.Statements)
+		p.flushComments(ast.Position{Row: int(^uint(0) >> 1)})
+		if p.buf.Len() > 0 {
+			p.print("\n")
+		}
+	case *ast.CodeBlock:
+		p.block(n)
+	case *ast.Comment:
+		p.print(n.Text)
+	default:
+		p.statement(n)
+	}
+}
+
+func (p *printer) statement(n ast.Node) {
+	p.visit(n.Pos())
+
+	switch n := n.(type) {
+	case *ast.DeclarativeStatement:
+		p.print("let ")
+		p.declaration(&n.Name)
+		p.print(" = ")
+		p.expression(n.Value, lowest)
+	case *ast.ReturnStatement:
+		p.print("return ")
+		p.expression(n.Value, lowest)
+	case *ast.ExpressionStatement:
+		p.expression(n.Expression, lowest)
+	case *ast.FunctionStatement:
+		p.print("func ", n.Name.Value)
+		p.function(n.Function)
+	case *ast.StructStatement:
+		p.structStatement(n)
+	case *ast.TraitStatement:
+		p.traitStatement(n)
+	case *ast.ImportStatement:
+		p.print("import ", n.Path.String())
+		if n.Alias != nil {
+			p.print(" as ", n.Alias.Value)
+		}
+	case *ast.ExportStatement:
+		p.print("export ")
+		p.statement(n.Statement)
+	default:
+		p.expression(n, lowest)
+	}
+}
+
+// needsSemicolon reports whether a semicolon must follow the statement. It is
+// left out after declarations ending in a brace unless the next statement would
+// otherwise continue the expression.
+func needsSemicolon(s, next ast.Node) bool {
+	switch s := s.(type) {
+	case *ast.FunctionStatement, *ast.StructStatement, *ast.TraitStatement:
+		return false
+	case *ast.ExportStatement:
+		return needsSemicolon(s.Statement, next)
+	case *ast.ExpressionStatement:
+		if _, ok := s.Expression.(*ast.ConditionalExpression); ok {
+			return continuesExpression(next)
+		}
+	}
+	return true
+}
+
+// continuesExpression reports whether the statement starts with a token that
+// would be parsed as an infix operator of the expression before it.
+func continuesExpression(n ast.Node) bool {
+	if n == nil {
+		return false
+	}
+	source := Sprint(n)
+	return source != "" && strings.ContainsAny(source[:1], "([-+*/<>=.")
+}
+
+func (p *printer) declaration(i *ast.Identifier) {
+	p.visit(i.Pos())
+	p.print(i.Value)
+	if i.Type != nil {
+		p.print(": ", i.Type.Name)
+	}
+}
+
+func (p *printer) parameters(params []*ast.Identifier) {
+	p.print("(")
+	for i, param := range params {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.declaration(param)
+	}
+	p.print(")")
+}
+
+func (p *printer) function(f *ast.FunctionLiteral) {
+	p.parameters(f.Parameters)
+	if f.ReturnType != nil {
+		p.print(": ", f.ReturnType.Name)
+	}
+	p.print(" ")
+	p.block(f.Body)
+}
+
+// block prints the statements indented between braces. A block written on one
+// line in the source with at most one simple statement stays on one line.
+func (p *printer) block(b *ast.CodeBlock) {
+	p.visit(b.Pos())
+
+	if p.isInline(b) {
+		if len(b.Statements) == 0 {
+			p.print("{}")
+		} else {
+			p.print("{ ")
+			p.statement(b.Statements[0])
+			p.print(" }")
+		}
+		p.visit(b.Rbrace)
+		return
+	}
+
+	p.open("{")
+	p.statements(b.Statements)
+	p.close("}", b.Rbrace)
+}
+
+func (p *printer) isInline(b *ast.CodeBlock) bool {
+	if len(b.Statements) == 0 {
+		return len(p.comments) == 0 || !before(p.comments[0].Position, b.Rbrace)
+	}
+	if len(b.Statements) > 1 || b.Pos().Row == 0 || b.Rbrace.Row != b.Pos().Row {
+		return false
+	}
+
+	switch b.Statements[0].(type) {
+	case *ast.FunctionStatement, *ast.StructStatement, *ast.TraitStatement:
+		return false
+	}
+	return true
+}
+
+func (p *printer) structStatement(s *ast.StructStatement) {
+	p.print("struct ", s.Name.Value)
+	for i, t := range s.Traits {
+		if i == 0 {
+			p.print(" impl ")
+		} else {
+			p.print(", ")
+		}
+		p.print(t.Value)
+	}
+
+	if len(s.Fields) == 0 && len(s.Methods) == 0 {
+		p.print(" {}")
+		p.visit(s.Rbrace)
+		return
+	}
+
+	p.print(" ")
+	p.open("{")
+	for _, f := range s.Fields {
+		p.flushComments(f.Pos())
+		p.separate(f.Pos().Row)
+		p.declaration(f)
+		p.trailingComment()
+	}
+	for _, m := range s.Methods {
+		p.flushComments(m.Pos())
+		p.separate(m.Pos().Row)
+		p.statement(m)
+		p.trailingComment()
+	}
+	p.close("}", s.Rbrace)
+}
+
+func (p *printer) traitStatement(t *ast.TraitStatement) {
+	p.print("trait ", t.Name.Value)
+
+	if len(t.Methods) == 0 {
+		p.print(" {}")
+		p.visit(t.Rbrace)
+		return
+	}
+
+	p.print(" ")
+	p.open("{")
+	for _, m := range t.Methods {
+		p.flushComments(m.Pos())
+		p.separate(m.Pos().Row)
+		p.visit(m.Pos())
+		p.print(m.Name.Value)
+		p.parameters(m.Parameters)
+		p.trailingComment()
+	}
+	p.close("}", t.Rbrace)
+}
+
+func precedence(n ast.Node) int {
+	switch n := n.(type) {
+	case *ast.AssignmentExpression:
+		return assignment
+	case *ast.InfixExpression:
+		return operatorPrecedence[n.Operator]
+	case *ast.PrefixExpression:
+		return prefix
+	case *ast.CallExpression, *ast.MemberExpression, *ast.IndexExpression:
+		return postfix
+	}
+	return primary
+}
+
+// expression prints the node, in parentheses when it binds weaker than min.
+func (p *printer) expression(n ast.Node, min int) {
+	if precedence(n) < min {
+		p.print("(")
+		defer p.print(")")
+	}
+
+	p.visit(n.Pos())
+
+	switch n := n.(type) {
+	case *ast.Identifier:
+		p.print(n.Value)
+	case *ast.IntLiteral, *ast.FloatLiteral, *ast.BoolLiteral, *ast.StringLiteral:
+		p.print(n.String())
+	case *ast.PrefixExpression:
+		p.print(n.Prefix)
+		p.expression(n.Expression, prefix)
+	case *ast.InfixExpression:
+		prec := operatorPrecedence[n.Operator]
+		p.expression(n.Left, prec)
+		p.print(" ", n.Operator, " ")
+		p.expression(n.Right, prec+1)
+	case *ast.AssignmentExpression:
+		p.expression(n.Target, postfix)
+		p.print(" = ")
+		p.expression(n.Value, lowest)
+	case *ast.CallExpression:
+		p.expression(n.Function, postfix)
+		p.print("(")
+		p.list(n.Arguments)
+		p.print(")")
+	case *ast.MemberExpression:
+		p.expression(n.Object, postfix)
+		p.print(".", n.Member.Value)
+	case *ast.IndexExpression:
+		p.expression(n.Left, postfix)
+		p.print("[")
+		p.expression(n.Index, lowest)
+		p.print("]")
+	case *ast.ConditionalExpression:
+		p.print("if (")
+		p.expression(n.Condition, lowest)
+		p.print(") ")
+		p.block(n.True)
+		if n.False != nil {
+			p.print(" else ")
+			p.block(n.False)
+		}
+	case *ast.FunctionLiteral:
+		p.print("func")
+		p.function(n)
+	case *ast.ArrayLiteral:
+		p.elements("[", "]", n.Pos(), len(n.Elements), func(i int) ast.Node { return n.Elements[i] }, func(i int) {
+			p.expression(n.Elements[i], lowest)
+		})
+	case *ast.MapLiteral:
+		p.elements("{", "}", n.Pos(), len(n.Pairs), func(i int) ast.Node { return n.Pairs[i].Key }, func(i int) {
+			p.expression(n.Pairs[i].Key, lowest)
+			p.print(": ")
+			p.expression(n.Pairs[i].Value, lowest)
+		})
+	default:
+		if p.err == nil {
+			p.err = fmt.Errorf("printer: unsupported node %T", n)
+		}
+	}
+}
+
+func (p *printer) list(nodes []ast.Node) {
+	for i, n := range nodes {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.expression(n, lowest)
+	}
+}
+
+// elements prints the elements of a literal on one line, or one per line when
+// they do not all start on the row of the opening bracket.
+func (p *printer) elements(open, close string, pos ast.Position, count int, at func(int) ast.Node, print func(int)) {
+	multiline := false
+	for i := 0; i < count; i++ {
+		if at(i).Pos().Row != pos.Row && pos.Row != 0 {
+			multiline = true
+		}
+	}
+
+	if !multiline {
+		p.print(open)
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				p.print(", ")
+			}
+			print(i)
+		}
+		p.print(close)
+		return
+	}
+
+	p.open(open)
+	for i := 0; i < count; i++ {
+		p.flushComments(at(i).Pos())
+		p.separate(at(i).Pos().Row)
+		print(i)
+		if i+1 < count {
+			p.print(",")
+		}
+		p.trailingComment()
+	}
+	p.indent--
+	p.blockStart = false
+	p.newline()
+	p.print(close)
+}
@@ -0,0 +1,109 @@
+package printer
+
+import (
+	"Simply/ast"
+	"Simply/lexer"
+	"Simply/parser"
+	"io"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1", "let x = 1;\n"},
+		{"let x: int = 1;;", "let x: int = 1;\n"},
+		{"return -(a+b)*c", "return -(a + b) * c;\n"},
+		{"a - (b - c) + (d - e) + f * g", "a - (b - c) + (d - e) + f * g;\n"},
+		{"(-f)(x); -f(x); (a = b).c", "(-f)(x);\n-f(x);\n(a = b).c;\n"},
+		{"x = y = 3", "x = y = 3;\n"},
+		{"!(a == b)", "!(a == b);\n"},
+		{"1.0 + 2.5", "1.0 + 2.5;\n"},
+		{`print("a b", [1,2], {"k": true, 1: null})`, "print(\"a b\", [1, 2], {\"k\": true, 1: null});\n"},
+		{"a[i + 1] = b.c", "a[i + 1] = b.c;\n"},
+		{"let f = func(a, b: int): int { a + b }", "let f = func(a, b: int): int { a + b };\n"},
+		{"func f(a) {\nlet b = a\nreturn b\n}", "func f(a) {\n    let b = a;\n    return b;\n}\n"},
+		{"if (a) { 1 } else { 2 }\nlet x = 1", "if (a) { 1 } else { 2 }\nlet x = 1;\n"},
+		{"if (a) { 1 }; -x", "if (a) { 1 };\n-x;\n"},
+		{"if (a) {\n1\n}", "if (a) {\n    1;\n}\n"},
+		{"struct P impl A, B { x: int, y\nfunc len() { self.x }\n}",
+			"struct P impl A, B {\n    x: int\n    y\n    func len() { self.x }\n}\n"},
+		{"struct E { }", "struct E {}\n"},
+		{"trait T { area(); scale(f: float) }", "trait T {\n    area()\n    scale(f: float)\n}\n"},
+		{`import "lib.syn" as lib; export let x = 1; export func f() {}`,
+			"import \"lib.syn\" as lib;\nexport let x = 1;\nexport func f() {}\n"},
+		{"let a = [\n1,\n2]", "let a = [\n    1,\n    2\n];\n"},
+		{"let x = 1\n\n\n\nlet y = 2", "let x = 1;\n\nlet y = 2;\n"},
+		{"// header\nlet x = 1 // one\n\n// two\nlet y = 2\n// end", "// header\nlet x = 1; // one\n\n// two\nlet y = 2;\n// end\n"},
+		{"func f() {\n// only a comment\n}", "func f() {\n    // only a comment\n}\n"},
+		{"let a = [\n1, // one\n2\n] // done\nlet b = 1", "let a = [\n    1, // one\n    2\n]; // done\nlet b = 1;\n"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := Format(tt.input)
+		if err != nil {
+			t.Errorf("%q: %s", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%q: expected\n%s\ngot\n%s", tt.input, tt.expected, got)
+			continue
+		}
+
+		again, err := Format(got)
+		if err != nil || again != got {
+			t.Errorf("%q: formatting is not idempotent, got\n%s", tt.input, again)
+		}
+
+		if original, formatted := parse(t, tt.input), parse(t, got); original.String() != formatted.String() {
+			t.Errorf("%q: formatting changed the program from %s to %s", tt.input, original, formatted)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	if _, err := Format("let = 1"); err == nil {
+		t.Error("expected a parse error")
+	}
+	if err := Fprint(io.Discard, &ast.MapLiteral{Pairs: []*ast.MapPair{{Key: &unknown{}, Value: &ast.Identifier{}}}}); err == nil {
+		t.Error("expected an unsupported node to fail")
+	}
+}
+
+func TestSprintNodes(t *testing.T) {
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{&ast.IntLiteral{Value: 42}, "42"},
+		{&ast.FloatLiteral{Value: 2}, "2.0"},
+		{&ast.InfixExpression{
+			Left:     &ast.InfixExpression{Left: &ast.Identifier{Value: "a"}, Operator: "+", Right: &ast.Identifier{Value: "b"}},
+			Operator: "*",
+			Right:    &ast.Identifier{Value: "c"},
+		}, "(a + b) * c"},
+		{&ast.CodeBlock{Statements: []ast.Node{&ast.ReturnStatement{Value: &ast.BoolLiteral{Value: true}}}}, "{\n    return true;\n}"},
+	}
+
+	for _, tt := range tests {
+		if got := Sprint(tt.node); got != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func parse(t *testing.T, source string) *ast.Program {
+	p := parser.NewParser(lexer.NewTokenizer(source))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		t.Fatalf("%q: parse errors %v", source, p.Errors)
+	}
+	return program
+}
+
+type unknown struct{ ast.Position }
+
+func (u *unknown) String() string { return "?" }
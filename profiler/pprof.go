@@ -0,0 +1,172 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Field numbers of profile.proto, the format of pprof, see
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID       = 1
+	functionName     = 2
+	functionFilename = 4
+)
+
+// WritePprof writes the samples as a gzipped pprof profile of the time spent
+// in nanoseconds, so that go tool pprof can show them.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	strs := &stringTable{index: map[string]int{"": 0}, values: []string{""}}
+	var profile protobuf
+
+	timeType := valueType(strs.id("time"), strs.id("nanoseconds"))
+	profile.message(profileSampleType, timeType)
+
+	for _, s := range p.sortedSamples() {
+		ids := make([]uint64, len(s.stack))
+		for i, id := range s.stack {
+			ids[i] = uint64(id) + 1
+		}
+
+		var sample protobuf
+		sample.packed(sampleLocationID, ids)
+		sample.packed(sampleValue, []uint64{uint64(s.time.Nanoseconds())})
+		profile.message(profileSample, sample)
+	}
+
+	functions := map[function]int{}
+	var functionMessages []protobuf
+	for i, loc := range p.locations {
+		key := function{loc.function, loc.file}
+		id, ok := functions[key]
+		if !ok {
+			id = len(functions) + 1
+			functions[key] = id
+
+			var fn protobuf
+			fn.uint(functionID, uint64(id))
+			fn.uint(functionName, uint64(strs.id(loc.function)))
+			fn.uint(functionFilename, uint64(strs.id(loc.file)))
+			functionMessages = append(functionMessages, fn)
+		}
+
+		var ln protobuf
+		ln.uint(lineFunctionID, uint64(id))
+		ln.uint(lineLine, uint64(loc.line))
+
+		var location protobuf
+		location.uint(locationID, uint64(i+1))
+		location.message(locationLine, ln)
+		profile.message(profileLocation, location)
+	}
+	for _, fn := range functionMessages {
+		profile.message(profileFunction, fn)
+	}
+
+	for _, s := range strs.values {
+		profile.bytes(profileStringTable, []byte(s))
+	}
+	profile.uint(profileTimeNanos, uint64(p.start.UnixNano()))
+	profile.uint(profileDurationNanos, uint64(p.end.Sub(p.start).Nanoseconds()))
+	profile.message(profilePeriodType, timeType)
+	profile.uint(profilePeriod, 1)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(profile); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func valueType(typ, unit int) protobuf {
+	var v protobuf
+	v.uint(valueTypeType, uint64(typ))
+	v.uint(valueTypeUnit, uint64(unit))
+	return v
+}
+
+// stringTable numbers the strings of a profile, the empty string is 0.
+type stringTable struct {
+	index  map[string]int
+	values []string
+}
+
+func (t *stringTable) id(s string) int {
+	if id, ok := t.index[s]; ok {
+		return id
+	}
+	t.index[s] = len(t.values)
+	t.values = append(t.values, s)
+	return len(t.values) - 1
+}
+
+// protobuf is an encoded protocol buffer message.
+type protobuf []byte
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *protobuf) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *protobuf) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// uint writes a varint field, zero values are left out as proto3 does.
+func (b *protobuf) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.key(field, wireVarint)
+	b.varint(v)
+}
+
+// bytes writes a length delimited field, it is always written so that empty
+// strings keep their place in repeated fields.
+func (b *protobuf) bytes(field int, data []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *protobuf) message(field int, m protobuf) {
+	b.bytes(field, m)
+}
+
+func (b *protobuf) packed(field int, values []uint64) {
+	var data protobuf
+	for _, v := range values {
+		data.varint(v)
+	}
+	b.bytes(field, data)
+}
@@ -0,0 +1,281 @@
+// Package profiler measures where scripts spend their time, by function and by
+// line, and writes the measurements as a table or as a pprof profile.
+package profiler
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/types"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// location is a line of a function.
+type location struct {
+	function string
+	file     string
+	line     int
+}
+
+type function struct {
+	name string
+	file string
+}
+
+type line struct {
+	file string
+	line int
+}
+
+// sample is the time spent with a stack of locations.
+type sample struct {
+	stack []int //Indexes of locations, innermost first as in pprof
+	time  time.Duration
+}
+
+// Profiler is an evaluator.CallHook timing the statements and calls of a script.
+// The time between two events is spent on the stack of the first one.
+type Profiler struct {
+	now   func() time.Time
+	start time.Time
+	last  time.Time
+	end   time.Time
+
+	locations []location
+	ids       map[location]int
+	samples   map[string]*sample //By the locations of their stack
+	current   *sample            //Stack being evaluated since last
+	calls     map[function]int
+	counts    map[line]int //Statements evaluated on each line
+}
+
+func New() *Profiler {
+	return newProfiler(time.Now)
+}
+
+func newProfiler(now func() time.Time) *Profiler {
+	start := now()
+	return &Profiler{
+		now:     now,
+		start:   start,
+		last:    start,
+		ids:     map[location]int{},
+		samples: map[string]*sample{},
+		calls:   map[function]int{},
+		counts:  map[line]int{},
+	}
+}
+
+// Statement implements evaluator.Hook.
+func (p *Profiler) Statement(n ast.Node, stack []evaluator.Frame) {
+	p.advance()
+	p.current = p.sampleOf(stack)
+
+	top := stack[len(stack)-1]
+	p.counts[line{top.File, top.Pos.Row}]++
+}
+
+// Call implements evaluator.CallHook.
+func (p *Profiler) Call(stack []evaluator.Frame, args []types.Object) {
+	top := stack[len(stack)-1]
+	p.calls[function{top.Name(), top.File}]++
+}
+
+// Return implements evaluator.CallHook.
+func (p *Profiler) Return(stack []evaluator.Frame, result types.Object) {
+	p.advance()
+	p.current = p.sampleOf(stack[:len(stack)-1])
+}
+
+// Stop ends the measurements, it is called once the script ended.
+func (p *Profiler) Stop() {
+	p.advance()
+	p.current = nil
+	p.end = p.last
+}
+
+func (p *Profiler) advance() {
+	now := p.now()
+	if p.current != nil {
+		p.current.time += now.Sub(p.last)
+	}
+	p.last = now
+}
+
+func (p *Profiler) sampleOf(stack []evaluator.Frame) *sample {
+	if len(stack) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(stack))
+	key := make([]string, len(stack))
+	for i := range stack {
+		f := stack[len(stack)-1-i]
+		loc := location{f.Name(), f.File, f.Pos.Row}
+		id, ok := p.ids[loc]
+		if !ok {
+			id = len(p.locations)
+			p.locations = append(p.locations, loc)
+			p.ids[loc] = id
+		}
+		ids[i], key[i] = id, strconv.Itoa(id)
+	}
+
+	k := strings.Join(key, ",")
+	s, ok := p.samples[k]
+	if !ok {
+		s = &sample{stack: ids}
+		p.samples[k] = s
+	}
+	return s
+}
+
+// Stats are the measurements of a function or a line.
+type Stats struct {
+	Function string
+	File     string
+	Line     int           //Zero for functions
+	Count    int           //Calls of a function, statements evaluated on a line
+	Self     time.Duration //Spent in its own statements
+	Total    time.Duration //Spent in it and in the functions it called
+}
+
+// Total is the time the script ran for.
+func (p *Profiler) Total() time.Duration {
+	var total time.Duration
+	for _, s := range p.samples {
+		total += s.time
+	}
+	return total
+}
+
+// Functions returns the measurements of each function, the slowest first.
+func (p *Profiler) Functions() []Stats {
+	byFunction := map[function]*Stats{}
+	for _, s := range p.sortedSamples() {
+		seen := map[function]bool{}
+		for i, id := range s.stack {
+			loc := p.locations[id]
+			key := function{loc.function, loc.file}
+			stats, ok := byFunction[key]
+			if !ok {
+				stats = &Stats{Function: loc.function, File: loc.file, Count: p.calls[key]}
+				byFunction[key] = stats
+			}
+			if i == 0 {
+				stats.Self += s.time
+			}
+			//Recursive calls are on the stack more than once
+			if !seen[key] {
+				seen[key] = true
+				stats.Total += s.time
+			}
+		}
+	}
+	return sortStats(byFunction)
+}
+
+// Lines returns the measurements of each line, the slowest first.
+func (p *Profiler) Lines() []Stats {
+	byLine := map[line]*Stats{}
+	for _, s := range p.sortedSamples() {
+		seen := map[line]bool{}
+		for i, id := range s.stack {
+			loc := p.locations[id]
+			key := line{loc.file, loc.line}
+			stats, ok := byLine[key]
+			if !ok {
+				stats = &Stats{Function: loc.function, File: loc.file, Line: loc.line, Count: p.counts[key]}
+				byLine[key] = stats
+			}
+			if i == 0 {
+				stats.Self += s.time
+			}
+			if !seen[key] {
+				seen[key] = true
+				stats.Total += s.time
+			}
+		}
+	}
+	return sortStats(byLine)
+}
+
+// sortedSamples returns the samples in a stable order.
+func (p *Profiler) sortedSamples() []*sample {
+	keys := make([]string, 0, len(p.samples))
+	for k := range p.samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]*sample, len(keys))
+	for i, k := range keys {
+		result[i] = p.samples[k]
+	}
+	return result
+}
+
+func sortStats[K comparable](m map[K]*Stats) []Stats {
+	result := make([]Stats, 0, len(m))
+	for _, s := range m {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		switch {
+		case a.Self != b.Self:
+			return a.Self > b.Self
+		case a.Total != b.Total:
+			return a.Total > b.Total
+		case a.File != b.File:
+			return a.File < b.File
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		}
+		return a.Function < b.Function
+	})
+	return result
+}
+
+// WriteTable writes the top functions and lines by the time spent in their own code.
+func (p *Profiler) WriteTable(w io.Writer, top int) error {
+	total := p.Total()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Total: %s\n", duration(total))
+
+	functions := p.Functions()
+	fmt.Fprintf(&b, "\n%10s %7s %10s %7s %8s  %s\n", "self", "self%", "total", "total%", "calls", "function")
+	for _, s := range functions[:min(top, len(functions))] {
+		writeRow(&b, s, total, s.Function)
+	}
+
+	lines := p.Lines()
+	fmt.Fprintf(&b, "\n%10s %7s %10s %7s %8s  %s\n", "self", "self%", "total", "total%", "count", "line")
+	for _, s := range lines[:min(top, len(lines))] {
+		writeRow(&b, s, total, fmt.Sprintf("%s:%d %s", filepath.Base(s.File), s.Line, s.Function))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeRow(b *strings.Builder, s Stats, total time.Duration, name string) {
+	fmt.Fprintf(b, "%10s %7s %10s %7s %8d  %s\n",
+		duration(s.Self), percent(s.Self, total), duration(s.Total), percent(s.Total, total), s.Count, name)
+}
+
+func duration(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d)/float64(time.Millisecond))
+}
+
+func percent(d, total time.Duration) string {
+	if total == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", 100*float64(d)/float64(total))
+}
@@ -0,0 +1,187 @@
+package profiler
+
+import (
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/types"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const script = `func double(x) {
+  x * 2
+}
+let a = double(1);
+let b = double(a);`
+
+// profile runs the script with a clock that advances a millisecond every time it is read.
+func profile(t *testing.T) (*Profiler, string) {
+	t.Helper()
+
+	clock := time.Unix(0, 0)
+	p := newProfiler(func() time.Time {
+		now := clock
+		clock = clock.Add(time.Millisecond)
+		return now
+	})
+
+	path, err := filepath.Abs("prof.syn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := evaluator.New()
+	e.SetHook(p)
+	program := parser.NewParser(lexer.NewTokenizer(script)).ParseProgram()
+	if result := e.EvalModule(program, path, types.NewContext(nil)); result != types.NULL {
+		t.Fatalf("unexpected result %s", result)
+	}
+	p.Stop()
+	return p, path
+}
+
+func TestProfiler(t *testing.T) {
+	p, path := profile(t)
+	ms := time.Millisecond
+
+	if total := p.Total(); total != 7*ms {
+		t.Errorf("expected a total of 7ms, got %s", total)
+	}
+
+	expectedFunctions := []Stats{
+		{Function: "prof.syn", File: path, Self: 5 * ms, Total: 7 * ms},
+		{Function: "double", File: path, Count: 2, Self: 2 * ms, Total: 2 * ms},
+	}
+	if functions := p.Functions(); !reflect.DeepEqual(functions, expectedFunctions) {
+		t.Errorf("expected functions %+v, got %+v", expectedFunctions, functions)
+	}
+
+	expectedLines := []Stats{
+		{Function: "prof.syn", File: path, Line: 4, Count: 1, Self: 2 * ms, Total: 3 * ms},
+		{Function: "prof.syn", File: path, Line: 5, Count: 1, Self: 2 * ms, Total: 3 * ms},
+		{Function: "double", File: path, Line: 2, Count: 2, Self: 2 * ms, Total: 2 * ms},
+		{Function: "prof.syn", File: path, Line: 1, Count: 1, Self: 1 * ms, Total: 1 * ms},
+	}
+	if lines := p.Lines(); !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("expected lines %+v, got %+v", expectedLines, lines)
+	}
+
+	var out strings.Builder
+	if err := p.WriteTable(&out, 3); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Total: 7.000ms
+
+      self   self%      total  total%    calls  function
+   5.000ms  71.43%    7.000ms 100.00%        0  prof.syn
+   2.000ms  28.57%    2.000ms  28.57%        2  double
+
+      self   self%      total  total%    count  line
+   2.000ms  28.57%    3.000ms  42.86%        1  prof.syn:4 prof.syn
+   2.000ms  28.57%    3.000ms  42.86%        1  prof.syn:5 prof.syn
+   2.000ms  28.57%    2.000ms  28.57%        2  prof.syn:2 double
+`
+	if out.String() != expected {
+		t.Errorf("unexpected table:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+// field is a decoded protocol buffer field, value is set for varints and data otherwise.
+type field struct {
+	number int
+	value  uint64
+	data   []byte
+}
+
+func decode(t *testing.T, b []byte) []field {
+	t.Helper()
+
+	varint := func() uint64 {
+		var v uint64
+		for shift := 0; ; shift += 7 {
+			if len(b) == 0 {
+				t.Fatal("truncated varint")
+			}
+			c := b[0]
+			b = b[1:]
+			v |= uint64(c&0x7f) << shift
+			if c < 0x80 {
+				return v
+			}
+		}
+	}
+
+	var fields []field
+	for len(b) > 0 {
+		key := varint()
+		f := field{number: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			f.value = varint()
+		case wireBytes:
+			n := varint()
+			f.data, b = b[:n], b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func TestWritePprof(t *testing.T) {
+	p, path := profile(t)
+
+	var buf bytes.Buffer
+	if err := p.WritePprof(&buf); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var strs []string
+	var samples, locations, functions int
+	var total, duration uint64
+	for _, f := range decode(t, data) {
+		switch f.number {
+		case profileStringTable:
+			strs = append(strs, string(f.data))
+		case profileSample:
+			samples++
+			for _, sf := range decode(t, f.data) {
+				if sf.number == sampleValue {
+					total += decode(t, append([]byte{sampleValue<<3 | wireVarint}, sf.data...))[0].value
+				}
+			}
+		case profileLocation:
+			locations++
+		case profileFunction:
+			functions++
+		case profileDurationNanos:
+			duration = f.value
+		}
+	}
+
+	if expected := []string{"", "time", "nanoseconds", "prof.syn", path, "double"}; !reflect.DeepEqual(strs, expected) {
+		t.Errorf("expected strings %q, got %q", expected, strs)
+	}
+	if samples != 5 || locations != 4 || functions != 2 {
+		t.Errorf("expected 5 samples, 4 locations and 2 functions, got %d, %d and %d", samples, locations, functions)
+	}
+	if total != uint64(7*time.Millisecond) || duration != uint64(8*time.Millisecond) {
+		t.Errorf("expected 7ms of samples in 8ms, got %d in %d", total, duration)
+	}
+}
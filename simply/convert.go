@@ -0,0 +1,514 @@
+package simply
+
+import (
+	"Simply/types"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	valueType       = reflect.TypeOf((*Value)(nil)).Elem()
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+	interpreterType = reflect.TypeOf((*types.Interpreter)(nil)).Elem()
+)
+
+// structTypes caches the script struct type created for each Go struct type.
+var structTypes sync.Map
+
+// visit is a pointer, map or slice being converted, met again it is a cycle.
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// ToValue converts a Go value to a script value. Slices and arrays become arrays,
+// maps become maps, structs become instances with their exported fields
+// (renamed with a `simply:"name"` tag) and functions become builtins, see WrapFunc.
+// Values referring to themselves cannot be converted.
+func ToValue(v any) (Value, error) {
+	if v == nil {
+		return types.NULL, nil
+	}
+	if obj, ok := v.(Value); ok {
+		return obj, nil
+	}
+	return toValue(reflect.ValueOf(v), map[visit]bool{})
+}
+
+func toValue(rv reflect.Value, seen map[visit]bool) (Value, error) {
+	if rv.IsValid() && rv.Type().Implements(valueType) && rv.CanInterface() {
+		if obj, ok := rv.Interface().(Value); ok && obj != nil {
+			return obj, nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return types.NULL, nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return types.TRUE, nil
+		}
+		return types.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &types.Int{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows int", rv.Uint())
+		}
+		return &types.Int{Value: int64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &types.Float{Value: rv.Float()}, nil
+	case reflect.String:
+		return &types.String{Value: rv.String()}, nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return types.NULL, nil
+		}
+		if rv.Kind() == reflect.Pointer {
+			leave, err := enter(rv, seen)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		return toValue(rv.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice {
+			if rv.IsNil() {
+				return types.NULL, nil
+			}
+			leave, err := enter(rv, seen)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		arr := &types.Array{Elements: make([]types.Object, rv.Len())}
+		for i := 0; i < rv.Len(); i++ {
+			el, err := toValue(rv.Index(i), seen)
+			if err != nil {
+				return nil, err
+			}
+			arr.Elements[i] = el
+		}
+		return arr, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return types.NULL, nil
+		}
+		leave, err := enter(rv, seen)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		m := types.NewMap()
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := toValue(iter.Key(), seen)
+			if err != nil {
+				return nil, err
+			}
+			if !types.IsHashable(key) {
+				return nil, fmt.Errorf("unusable map key type %s", iter.Key().Type())
+			}
+			value, err := toValue(iter.Value(), seen)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, value)
+		}
+		return m, nil
+	case reflect.Struct:
+		return structToValue(rv, seen)
+	case reflect.Func:
+		if rv.IsNil() {
+			return types.NULL, nil
+		}
+		return wrapFunc(rv)
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to a script value", rv.Type())
+}
+
+// enter records the pointer, map or slice rv as being converted, leave forgets it.
+func enter(rv reflect.Value, seen map[visit]bool) (leave func(), err error) {
+	v := visit{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		v.len = rv.Len()
+	}
+	if seen[v] {
+		return nil, fmt.Errorf("cannot convert cyclic %s", rv.Type())
+	}
+	seen[v] = true
+	return func() { delete(seen, v) }, nil
+}
+
+func fieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("simply")
+	if tag == "-" {
+		return "", false
+	}
+	if tag != "" {
+		return tag, true
+	}
+	return f.Name, true
+}
+
+func structType(t reflect.Type) *types.StructType {
+	if st, ok := structTypes.Load(t); ok {
+		return st.(*types.StructType)
+	}
+
+	//Anonymous structs are named after their fields, like struct { X int }
+	st := &types.StructType{Name: t.Name(), Methods: map[string]*types.Function{}}
+	if st.Name == "" {
+		st.Name = t.String()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := fieldName(t.Field(i)); ok {
+			st.Fields = append(st.Fields, name)
+		}
+	}
+
+	actual, _ := structTypes.LoadOrStore(t, st)
+	return actual.(*types.StructType)
+}
+
+func structToValue(rv reflect.Value, seen map[visit]bool) (Value, error) {
+	st := structType(rv.Type())
+	instance := &types.Instance{Type: st, Fields: map[string]types.Object{}}
+
+	for i := 0; i < rv.NumField(); i++ {
+		name, ok := fieldName(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		v, err := toValue(rv.Field(i), seen)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		instance.Fields[name] = v
+	}
+
+	return instance, nil
+}
+
+// WrapFunc turns a Go function into a builtin. Arguments are converted with
+// Decode to the parameter types, a first parameter of type types.Interpreter
+// receives the interpreter handle. The function may return nothing, a value,
+// an error, or a value and an error. A panic of the function becomes an error.
+func WrapFunc(fn any) (*types.InternalCall, error) {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%T is not a function", fn)
+	}
+	if rv.IsNil() {
+		return nil, fmt.Errorf("%T is nil", fn)
+	}
+	return wrapFunc(rv)
+}
+
+func wrapFunc(rv reflect.Value) (*types.InternalCall, error) {
+	t := rv.Type()
+
+	switch {
+	case t.NumOut() > 2,
+		t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("unsupported function results %s", t)
+	}
+
+	withInterpreter := t.NumIn() > 0 && t.In(0) == interpreterType
+	firstArg := 0
+	if withInterpreter {
+		firstArg = 1
+	}
+
+	return &types.InternalCall{Fn: func(in types.Interpreter, args ...types.Object) types.Object {
+		want := t.NumIn() - firstArg
+		if len(args) < want-btoi(t.IsVariadic()) || !t.IsVariadic() && len(args) > want {
+			return &types.Error{Value: fmt.Sprintf("wrong number of arguments. got=%d, want=%d", len(args), want)}
+		}
+
+		callArgs := make([]reflect.Value, 0, len(args)+firstArg)
+		if withInterpreter {
+			callArgs = append(callArgs, reflect.ValueOf(&in).Elem())
+		}
+
+		for i, arg := range args {
+			var paramType reflect.Type
+			if t.IsVariadic() && i+firstArg >= t.NumIn()-1 {
+				paramType = t.In(t.NumIn() - 1).Elem()
+			} else {
+				paramType = t.In(i + firstArg)
+			}
+
+			target := reflect.New(paramType)
+			if err := decode(arg, target.Elem()); err != nil {
+				return &types.Error{Value: fmt.Sprintf("argument %d: %s", i+1, err)}
+			}
+			callArgs = append(callArgs, target.Elem())
+		}
+
+		return call(rv, callArgs)
+	}}, nil
+}
+
+func call(rv reflect.Value, args []reflect.Value) (result types.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &types.Error{Value: fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+	return fromResults(rv.Call(args))
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func fromResults(results []reflect.Value) types.Object {
+	if len(results) > 0 {
+		last := results[len(results)-1]
+		if last.Type() == errorType {
+			if !last.IsNil() {
+				return &types.Error{Value: last.Interface().(error).Error()}
+			}
+			results = results[:len(results)-1]
+		}
+	}
+
+	if len(results) == 0 {
+		return types.NULL
+	}
+
+	v, err := toValue(results[0], map[visit]bool{})
+	if err != nil {
+		return &types.Error{Value: err.Error()}
+	}
+	return v
+}
+
+// FromValue converts a script value to its natural Go representation: int64,
+// float64, string, bool, nil, []any and map[string]any for maps with string
+// keys and instances, map[any]any for other maps. Functions are returned as is.
+func FromValue(v Value) any {
+	switch v := v.(type) {
+	case nil, *types.Null:
+		return nil
+	case *types.Int:
+		return v.Value
+	case *types.Float:
+		return v.Value
+	case *types.String:
+		return v.Value
+	case *types.Bool:
+		return v.Value
+	case *types.Array:
+		result := make([]any, len(v.Elements))
+		for i, el := range v.Elements {
+			result[i] = FromValue(el)
+		}
+		return result
+	case *types.Map:
+		stringKeys := map[string]any{}
+		anyKeys := map[any]any{}
+		for _, p := range v.Pairs() {
+			key := FromValue(p.Key)
+			if s, ok := key.(string); ok && stringKeys != nil {
+				stringKeys[s] = FromValue(p.Value)
+			} else {
+				stringKeys = nil
+			}
+			anyKeys[key] = FromValue(p.Value)
+		}
+		if stringKeys != nil {
+			return stringKeys
+		}
+		return anyKeys
+	case *types.Instance:
+		result := map[string]any{}
+		for name, field := range v.Fields {
+			result[name] = FromValue(field)
+		}
+		return result
+	}
+	return v
+}
+
+// Decode stores the script value in the Go value pointed to by target.
+func Decode(v Value, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("decode target must be a non-nil pointer")
+	}
+	return decode(v, rv.Elem())
+}
+
+func decode(v Value, target reflect.Value) error {
+	if target.Type() == valueType {
+		target.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cannot use %s as %s", types.TypeName(v), target.Type())
+	}
+
+	if _, ok := v.(*types.Null); ok {
+		switch target.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		return mismatch()
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		if target.NumMethod() > 0 {
+			return mismatch()
+		}
+		if native := FromValue(v); native != nil {
+			target.Set(reflect.ValueOf(native))
+		}
+		return nil
+	case reflect.Pointer:
+		ptr := reflect.New(target.Type().Elem())
+		if err := decode(v, ptr.Elem()); err != nil {
+			return err
+		}
+		target.Set(ptr)
+		return nil
+	case reflect.Bool:
+		b, ok := v.(*types.Bool)
+		if !ok {
+			return mismatch()
+		}
+		target.SetBool(b.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := v.(*types.Int)
+		if !ok {
+			return mismatch()
+		}
+		if target.OverflowInt(i.Value) {
+			return fmt.Errorf("%d overflows %s", i.Value, target.Type())
+		}
+		target.SetInt(i.Value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := v.(*types.Int)
+		if !ok {
+			return mismatch()
+		}
+		if i.Value < 0 || target.OverflowUint(uint64(i.Value)) {
+			return fmt.Errorf("%d overflows %s", i.Value, target.Type())
+		}
+		target.SetUint(uint64(i.Value))
+	case reflect.Float32, reflect.Float64:
+		switch n := v.(type) {
+		case *types.Float:
+			target.SetFloat(n.Value)
+		case *types.Int:
+			target.SetFloat(float64(n.Value))
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		s, ok := v.(*types.String)
+		if !ok {
+			return mismatch()
+		}
+		target.SetString(s.Value)
+	case reflect.Slice:
+		arr, ok := v.(*types.Array)
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(target.Type(), len(arr.Elements), len(arr.Elements))
+		for i, el := range arr.Elements {
+			if err := decode(el, slice.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		target.Set(slice)
+	case reflect.Map:
+		m, ok := v.(*types.Map)
+		if !ok {
+			return mismatch()
+		}
+		result := reflect.MakeMapWithSize(target.Type(), m.Len())
+		for _, p := range m.Pairs() {
+			key := reflect.New(target.Type().Key()).Elem()
+			if err := decode(p.Key, key); err != nil {
+				return fmt.Errorf("key %s: %w", types.Inspect(p.Key), err)
+			}
+			value := reflect.New(target.Type().Elem()).Elem()
+			if err := decode(p.Value, value); err != nil {
+				return fmt.Errorf("key %s: %w", types.Inspect(p.Key), err)
+			}
+			result.SetMapIndex(key, value)
+		}
+		target.Set(result)
+	case reflect.Struct:
+		return decodeStruct(v, target)
+	default:
+		return mismatch()
+	}
+
+	return nil
+}
+
+// decodeStruct fills the exported fields from an instance or a map with string keys.
+func decodeStruct(v Value, target reflect.Value) error {
+	var lookup func(name string) (types.Object, bool)
+
+	switch v := v.(type) {
+	case *types.Instance:
+		lookup = func(name string) (types.Object, bool) {
+			for field, value := range v.Fields {
+				if strings.EqualFold(field, name) {
+					return value, true
+				}
+			}
+			return nil, false
+		}
+	case *types.Map:
+		lookup = func(name string) (types.Object, bool) {
+			for _, p := range v.Pairs() {
+				if key, ok := p.Key.(*types.String); ok && strings.EqualFold(key.Value, name) {
+					return p.Value, true
+				}
+			}
+			return nil, false
+		}
+	default:
+		return fmt.Errorf("cannot use %s as %s", types.TypeName(v), target.Type())
+	}
+
+	for i := 0; i < target.NumField(); i++ {
+		name, ok := fieldName(target.Type().Field(i))
+		if !ok {
+			continue
+		}
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := decode(value, target.Field(i)); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package simply
+
+import (
+	"Simply/types"
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X     int
+	Y     int
+	Label string `simply:"label"`
+	skip  bool
+}
+
+func TestToValue(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{1.5, "1.5"},
+		{"hi", "hi"},
+		{true, "true"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, `["a", "b"]`},
+		{map[string]int{"a": 1}, `{"a": 1}`},
+		{point{X: 1, Y: 2, Label: "p"}, `point{X: 1, Y: 2, label: "p"}`},
+		{&point{X: 3}, `point{X: 3, Y: 0, label: ""}`},
+		{(*point)(nil), "null"},
+		{&types.Int{Value: 5}, "5"},
+	}
+
+	for _, tt := range tests {
+		v, err := ToValue(tt.input)
+		if err != nil {
+			t.Errorf("ToValue(%#v) failed: %s", tt.input, err)
+			continue
+		}
+		if v.String() != tt.expected {
+			t.Errorf("ToValue(%#v) = %s, want %s", tt.input, v.String(), tt.expected)
+		}
+	}
+
+	if _, err := ToValue(make(chan int)); err == nil {
+		t.Error("expected converting a channel to fail")
+	}
+	if _, err := ToValue(uint64(math.MaxUint64)); err == nil || err.Error() != "18446744073709551615 overflows int" {
+		t.Errorf("expected overflow to fail, got %v", err)
+	}
+
+	type node struct{ Next *node }
+	n := &node{}
+	n.Next = n
+	m := map[string]any{}
+	m["self"] = m
+	for _, cyclic := range []any{n, m} {
+		if _, err := ToValue(cyclic); err == nil || !strings.Contains(err.Error(), "cannot convert cyclic") {
+			t.Errorf("expected converting %T to fail with a cycle, got %v", cyclic, err)
+		}
+	}
+	shared := &point{X: 1}
+	if v, err := ToValue([]*point{shared, shared}); err != nil || v.String() != `[point{X: 1, Y: 0, label: ""}, point{X: 1, Y: 0, label: ""}]` {
+		t.Errorf("expected shared pointers to convert, got %v, %v", v, err)
+	}
+
+	if v, err := ToValue(struct{ X int }{1}); err != nil || v.String() != "struct { X int }{X: 1}" {
+		t.Errorf("expected anonymous structs to be named after their fields, got %v, %v", v, err)
+	}
+}
+
+func TestFromValueAndDecode(t *testing.T) {
+	i := New()
+	v, err := i.Run(context.Background(), `{"X": 1, "Y": 2.5, "label": "p", "tags": ["a", "b"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{"X": int64(1), "Y": 2.5, "label": "p", "tags": []any{"a", "b"}}
+	if got := FromValue(v); !reflect.DeepEqual(got, expected) {
+		t.Errorf("FromValue = %#v, want %#v", got, expected)
+	}
+
+	var target struct {
+		X     int
+		Y     float64
+		Label string `simply:"label"`
+		Tags  []string
+	}
+	if err := Decode(v, &target); err != nil {
+		t.Fatal(err)
+	}
+	if target.X != 1 || target.Y != 2.5 || target.Label != "p" || !reflect.DeepEqual(target.Tags, []string{"a", "b"}) {
+		t.Errorf("unexpected decoded value %+v", target)
+	}
+
+	var n int8
+	if err := Decode(&types.Int{Value: 300}, &n); err == nil {
+		t.Error("expected overflow to fail")
+	}
+	var s string
+	if err := Decode(&types.Int{Value: 1}, &s); err == nil || err.Error() != "cannot use int as string" {
+		t.Errorf("expected type mismatch, got %v", err)
+	}
+}
+
+func TestGoFunctions(t *testing.T) {
+	i := New()
+
+	tests := []struct {
+		name     string
+		fn       any
+		input    string
+		expected string
+	}{
+		{"add", func(a, b int) int { return a + b }, "add(1, 2)", "3"},
+		{"sum", func(nums ...float64) float64 {
+			total := 0.0
+			for _, n := range nums {
+				total += n
+			}
+			return total
+		}, "sum(1, 2.5, 3)", "6.5"},
+		{"names", func(p []point) []string {
+			var names []string
+			for _, pt := range p {
+				names = append(names, pt.Label)
+			}
+			return names
+		}, `names([{"label": "a"}, {"label": "b"}])`, `["a", "b"]`},
+		{"fail", func() error { return errors.New("boom") }, "fail()", "boom"},
+		{"divide", func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		}, "divide(6, 3)", "2"},
+		{"apply", func(in types.Interpreter, fn Value, x int) Value {
+			return in.Call(fn, &types.Int{Value: int64(x)})
+		}, "apply(func(x) { x * 2 }, 21)", "42"},
+		{"noop", func() {}, "noop()", "null"},
+		{"explode", func(a []int) int { return a[1] }, "explode([1])", "panic: runtime error: index out of range [1] with length 1"},
+	}
+
+	for _, tt := range tests {
+		if err := i.Register(tt.name, tt.fn); err != nil {
+			t.Fatal(err)
+		}
+		v, err := i.Run(context.Background(), tt.input)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = v.String()
+		}
+		if !strings.HasSuffix(got, tt.expected) {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+
+	if _, err := i.Run(context.Background(), `add("a", 1)`); err == nil || !strings.Contains(err.Error(), "argument 1: cannot use string as int") {
+		t.Errorf("expected argument conversion error, got %v", err)
+	}
+	if err := i.Register("bad", 5); err == nil {
+		t.Error("expected registering a non-function to fail")
+	}
+	if err := i.Register("none", (func())(nil)); err == nil {
+		t.Error("expected registering a nil function to fail")
+	}
+	if v, err := ToValue(map[string]func(){"f": nil}); err != nil || v.String() != `{"f": null}` {
+		t.Errorf("expected a nil function to convert to null, got %v, %v", v, err)
+	}
+}
+
+func TestGlobalsFromGo(t *testing.T) {
+	i := New()
+	if err := i.SetGlobal("config", map[string]any{"retries": 3, "hosts": []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := i.Run(context.Background(), `let f = func(n) { config["retries"] + n }; config["hosts"][1]`)
+	if err != nil || v.String() != "b" {
+		t.Fatalf("expected b, got %v %v", v, err)
+	}
+
+	v, err = i.Call("f", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := Decode(v, &n); err != nil || n != 5 {
+		t.Errorf("expected 5, got %d %v", n, err)
+	}
+}
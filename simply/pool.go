@@ -0,0 +1,117 @@
+package simply
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPoolClosed is returned by Get once the pool is closed.
+var ErrPoolClosed = errors.New("simply: pool closed")
+
+// Pool keeps a fixed number of prepared interpreters for concurrent callers such
+// as the handlers of a server. Each interpreter is used by one caller at a time.
+type Pool struct {
+	setup     func(*Interpreter) error
+	idle      chan *Interpreter //Prepared interpreters, nil for a slot whose setup failed
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	busy map[*Interpreter]bool //Interpreters handed out by Get and not yet put back
+}
+
+// NewPool creates size interpreters and prepares each with setup, which may be nil.
+// Interpreters put back are replaced by newly prepared ones, so that nothing a
+// caller did, like changing globals or registering builtins, reaches the next caller.
+func NewPool(size int, setup func(*Interpreter) error) (*Pool, error) {
+	if size < 1 {
+		return nil, errors.New("simply: pool size must be positive")
+	}
+
+	p := &Pool{setup: setup, idle: make(chan *Interpreter, size), closed: make(chan struct{}), busy: map[*Interpreter]bool{}}
+	for n := 0; n < size; n++ {
+		i, err := p.prepare()
+		if err != nil {
+			return nil, err
+		}
+		p.idle <- i
+	}
+
+	return p, nil
+}
+
+func (p *Pool) prepare() (*Interpreter, error) {
+	i := New()
+	if p.setup != nil {
+		if err := p.setup(i); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+// Get waits for an idle interpreter, it fails when ctx is done, the pool is
+// closed or preparing the interpreter fails.
+func (p *Pool) Get(ctx context.Context) (*Interpreter, error) {
+	select {
+	case <-p.closed:
+		return nil, ErrPoolClosed
+	default:
+	}
+
+	select {
+	case i := <-p.idle:
+		if i == nil {
+			var err error
+			if i, err = p.prepare(); err != nil {
+				p.idle <- nil
+				return nil, err
+			}
+		}
+		p.mu.Lock()
+		p.busy[i] = true
+		p.mu.Unlock()
+		return i, nil
+	case <-p.closed:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Put gives back an interpreter returned by Get, which must not be used
+// anymore. A newly prepared interpreter takes its place, setup runs in the
+// background so that Put returns right away. Putting back an interpreter twice,
+// or one that is not from the pool, does nothing.
+func (p *Pool) Put(i *Interpreter) {
+	p.mu.Lock()
+	busy := p.busy[i]
+	delete(p.busy, i)
+	p.mu.Unlock()
+	if !busy {
+		return
+	}
+
+	go func() {
+		//When setup fails the slot is left empty, Get prepares it again
+		fresh, _ := p.prepare()
+		p.idle <- fresh
+	}()
+}
+
+// Run evaluates the source on an idle interpreter.
+func (p *Pool) Run(ctx context.Context, source string) (Value, error) {
+	i, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Put(i)
+
+	return i.Run(ctx, source)
+}
+
+// Close makes waiting and future calls to Get fail, closing again does nothing.
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() { close(p.closed) })
+}
@@ -0,0 +1,255 @@
+package simply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// These tests are meant to be run with -race.
+
+func TestInterpretersInParallel(t *testing.T) {
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+
+			i := New()
+			i.SetIO(strings.NewReader(""), io.Discard)
+			i.SetGlobal("n", n)
+			i.Builtins().Remove("print")
+
+			v, err := i.Run(context.Background(), `
+				let fib = func(x) { if (x < 2) { x } else { fib(x - 1) + fib(x - 2) } };
+				let values = map([1, 2, 3], func(x) { x * n });
+				println(math.sqrt(16));
+				fib(10) + len(values) + n;`)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if expected := fmt.Sprint(58 + n); v.String() != expected {
+				t.Errorf("expected %s, got %s", expected, v)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+func TestInterpreterConcurrentCalls(t *testing.T) {
+	i := New()
+	if _, err := i.Run(context.Background(), "let count = 0; let inc = func(n) { count = count + n; count };"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := i.Call("inc", 1); err != nil {
+				t.Error(err)
+			}
+			i.Register("twice", func(x int) int { return x * 2 })
+			i.GetGlobal("count")
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := i.GetGlobal("count"); v.String() != "20" {
+		t.Errorf("expected count 20, got %s", v)
+	}
+}
+
+func TestSharedFunctionBetweenInterpreters(t *testing.T) {
+	source := New()
+	if _, err := source.Run(context.Background(), "let total = 0; let add = func(n) { total = total + n; total };"); err != nil {
+		t.Fatal(err)
+	}
+	add, _ := source.GetGlobal("add")
+
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			i := New()
+			i.SetGlobal("add", add)
+			if _, err := i.Run(context.Background(), "add(1);"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPool(t *testing.T) {
+	p, err := NewPool(3, func(i *Interpreter) error {
+		_, err := i.Run(context.Background(), "let greet = func(name) { \"hello \" + name };")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			v, err := p.Run(context.Background(), fmt.Sprintf(`let name = "%d"; greet(name);`, n))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if expected := fmt.Sprintf("hello %d", n); v.String() != expected {
+				t.Errorf("expected %s, got %s", expected, v)
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	i, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := i.GetGlobal("name"); ok {
+		t.Error("expected globals of a previous run to be discarded")
+	}
+	if _, ok := i.GetGlobal("greet"); !ok {
+		t.Error("expected globals of setup to be kept")
+	}
+	p.Put(i)
+}
+
+func TestPoolIsolation(t *testing.T) {
+	p, err := NewPool(1, func(i *Interpreter) error {
+		_, err := i.Run(context.Background(), "let count = 0; let items = [];")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n := 0; n < 3; n++ {
+		i, err := p.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := i.GetGlobal("twice"); ok {
+			t.Error("expected builtins of a previous caller to be discarded")
+		}
+		i.Register("twice", func(x int) int { return 2 * x })
+		v, err := i.Run(context.Background(), "count = count + 1; items.push(1); [count, len(items)];")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.String() != "[1, 1]" {
+			t.Errorf("expected the globals of setup to be restored, got %s", v)
+		}
+		if _, err := i.Run(context.Background(), "twice(1);"); err != nil {
+			t.Error(err)
+		}
+		p.Put(i)
+		p.Put(i) //Does nothing, and does not block
+	}
+	p.Put(New())
+
+	if v, err := p.Run(context.Background(), "twice(1);"); err == nil {
+		t.Errorf("expected twice to be undefined, got %s", v)
+	}
+}
+
+func TestPoolSetupFailure(t *testing.T) {
+	var fail atomic.Bool
+	p, err := NewPool(1, func(*Interpreter) error {
+		if fail.Load() {
+			return errors.New("setup failed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, _ := p.Get(context.Background())
+	fail.Store(true)
+	p.Put(i)
+	if _, err := p.Get(context.Background()); err == nil || err.Error() != "setup failed" {
+		t.Errorf("expected the setup error, got %v", err)
+	}
+	fail.Store(false)
+	if i, err := p.Get(context.Background()); err != nil {
+		t.Errorf("expected the slot to be prepared again, got %v", err)
+	} else {
+		p.Put(i)
+	}
+}
+
+func TestPoolPutInBackground(t *testing.T) {
+	var setups atomic.Int32
+	release := make(chan struct{})
+	p, err := NewPool(1, func(*Interpreter) error {
+		if setups.Add(1) > 1 {
+			<-release
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Run returns while the replacement is still being prepared
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := p.Run(context.Background(), "1;"); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run not to wait for setup")
+	}
+
+	close(release)
+	if _, err := p.Run(context.Background(), "1;"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPoolGet(t *testing.T) {
+	p, err := NewPool(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, _ := p.Get(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Get to time out, got %v", err)
+	}
+	p.Put(i)
+
+	p.Close()
+	p.Close()
+	if _, err := p.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+
+	if _, err := NewPool(0, nil); err == nil {
+		t.Error("expected an empty pool to be rejected")
+	}
+	if _, err := NewPool(2, func(*Interpreter) error { return errors.New("setup failed") }); err == nil {
+		t.Error("expected setup errors to be returned")
+	}
+}
@@ -0,0 +1,204 @@
+// Package simply embeds the Simply interpreter in Go programs.
+package simply
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/types"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Value is any value a script can hold.
+type Value = types.Object
+
+// ParseError is returned when the source cannot be parsed.
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error: " + strings.Join(e.Errors, "; ")
+}
+
+// RuntimeError is returned when evaluation fails.
+type RuntimeError struct {
+	Message  string
+	Position ast.Position
+}
+
+func (e *RuntimeError) Error() string {
+	if e.Position.Row == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Position.String(), e.Message)
+}
+
+// Interpreter runs scripts that share a set of global bindings.
+//
+// Interpreters are independent of each other and may run in parallel. The methods
+// of a single Interpreter are safe for concurrent use but run one at a time, so a
+// Go function called from a script must not call back into its Interpreter, it
+// should use the types.Interpreter it is passed instead. Arrays, maps and instances
+// passed to several interpreters are not synchronized.
+type Interpreter struct {
+	mu      sync.Mutex
+	eval    *evaluator.Evaluator
+	globals *types.Context
+}
+
+func New() *Interpreter {
+	return &Interpreter{eval: evaluator.New(), globals: types.NewContext(nil)}
+}
+
+// SetIO sets the streams used by the input and output builtins.
+func (i *Interpreter) SetIO(in io.Reader, out io.Writer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.eval.SetIO(in, out)
+}
+
+// Builtins returns the registry of builtins available to the scripts of this interpreter.
+func (i *Interpreter) Builtins() *evaluator.Builtins {
+	return i.eval.Builtins
+}
+
+// SetSearchPath sets the directories searched for imported modules.
+func (i *Interpreter) SetSearchPath(dirs ...string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.eval.SearchPath = dirs
+}
+
+// Run evaluates the source and returns the value of its last statement.
+// Evaluation stops with an error when ctx is done.
+func (i *Interpreter) Run(ctx context.Context, source string) (Value, error) {
+	program, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.eval.SetContext(ctx)
+	defer i.eval.SetContext(nil)
+
+	return evaluate(func() types.Object { return i.eval.Eval(program, i.globals) })
+}
+
+// RunFile evaluates the script at path, resolving its imports relative to it.
+func (i *Interpreter) RunFile(ctx context.Context, path string) (Value, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.eval.SetContext(ctx)
+	defer i.eval.SetContext(nil)
+
+	return evaluate(func() types.Object { return i.eval.EvalModule(program, path, i.globals) })
+}
+
+// SetGlobal binds name to the Go value converted with ToValue.
+func (i *Interpreter) SetGlobal(name string, v any) error {
+	obj, err := ToValue(v)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.globals.Set(name, obj)
+	return nil
+}
+
+// Register makes the Go function available to scripts as the builtin name, see WrapFunc.
+func (i *Interpreter) Register(name string, fn any) error {
+	call, err := WrapFunc(fn)
+	if err != nil {
+		return err
+	}
+
+	i.eval.Builtins.Register(name, call)
+	return nil
+}
+
+func (i *Interpreter) GetGlobal(name string) (Value, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.globals.Get(name)
+}
+
+// Call calls the global function name with the arguments converted with ToValue.
+func (i *Interpreter) Call(name string, args ...any) (Value, error) {
+	values := make([]Value, len(args))
+	for idx, arg := range args {
+		v, err := ToValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", idx+1, err)
+		}
+		values[idx] = v
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	fn, ok := i.globals.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined function %s", name)
+	}
+
+	return evaluate(func() types.Object { return i.eval.Call(fn, values...) })
+}
+
+// Parse parses the source without evaluating it.
+func Parse(source string) (*ast.Program, error) {
+	p := parser.NewParser(lexer.NewTokenizer(source))
+	program := p.ParseProgram()
+
+	if len(p.Errors) > 0 {
+		return nil, &ParseError{Errors: p.Errors}
+	}
+
+	return program, nil
+}
+
+// evaluate runs eval and converts its result, a panic of the evaluator is
+// returned as a RuntimeError instead of crashing the host.
+func evaluate(eval func() types.Object) (v Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			v, err = nil, &RuntimeError{Message: fmt.Sprintf("internal error: %v", r)}
+		}
+	}()
+	return result(eval())
+}
+
+func result(obj types.Object) (Value, error) {
+	if err, ok := obj.(*types.Error); ok {
+		return nil, &RuntimeError{Message: err.Value, Position: err.Position}
+	}
+	if obj == nil {
+		return types.NULL, nil
+	}
+	return obj, nil
+}
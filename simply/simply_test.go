@@ -0,0 +1,91 @@
+package simply
+
+import (
+	"Simply/types"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunAndGlobals(t *testing.T) {
+	var out bytes.Buffer
+	i := New()
+	i.SetIO(strings.NewReader(""), &out)
+	i.SetGlobal("base", &types.Int{Value: 40})
+
+	v, err := i.Run(context.Background(), `let add = func(a, b) { a + b }; println("hi"); add(base, 2);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "42" {
+		t.Errorf("expected 42, got %s", v)
+	}
+	if out.String() != "hi\n" {
+		t.Errorf("expected output to be captured, got %q", out.String())
+	}
+
+	v, err = i.Call("add", &types.Int{Value: 1}, &types.Int{Value: 2})
+	if err != nil || v.String() != "3" {
+		t.Errorf("expected 3, got %v %v", v, err)
+	}
+
+	if _, err := i.Run(context.Background(), "let x = 5;"); err != nil {
+		t.Fatal(err)
+	}
+	if x, ok := i.GetGlobal("x"); !ok || x.String() != "5" {
+		t.Errorf("expected global x = 5, got %v", x)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	i := New()
+
+	_, err := i.Run(context.Background(), "let = 5")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("expected ParseError, got %v", err)
+	}
+
+	_, err = i.Run(context.Background(), "let x = 1;\nlet y = x + \"a\";")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected RuntimeError, got %v", err)
+	}
+	if err.Error() != "2:9: Unknown inflix operation 1 + a" {
+		t.Errorf("unexpected error %q", err)
+	}
+
+	if _, err := i.Call("missing"); err == nil {
+		t.Error("expected calling an undefined function to fail")
+	}
+
+	//Statements have no value, using one is an error rather than a crash
+	if _, err := i.Run(context.Background(), "func f() { let x = 1 }; f() + 1;"); err == nil || err.Error() != "1:25: Unknown inflix operation null + 1" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if v, err := i.Call("f"); err != nil || v != types.NULL {
+		t.Errorf("expected f to return null, got %v, %v", v, err)
+	}
+
+	i.Builtins().RegisterFunc("crash", func(in types.Interpreter, args ...types.Object) types.Object { panic("boom") })
+	if _, err := i.Run(context.Background(), "func g() { crash() }; g();"); err == nil || err.Error() != "internal error: boom" {
+		t.Errorf("expected the panic to be returned, got %v", err)
+	}
+	if v, err := i.Run(context.Background(), "f();"); err != nil || v != types.NULL {
+		t.Errorf("expected the interpreter to be usable after a panic, got %v, %v", v, err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	i := New()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := i.Run(ctx, "let f = func(n) { f(n + 1) }; f(0);")
+	if err == nil || !strings.Contains(err.Error(), "evaluation stopped: context deadline exceeded") {
+		t.Errorf("expected evaluation to stop, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Summary counts the results.
+type Summary struct {
+	Tests    int
+	Failures int
+	Files    int
+	Duration time.Duration
+}
+
+func Summarize(results []Result) Summary {
+	var s Summary
+	files := map[string]bool{}
+	for _, r := range results {
+		s.Tests++
+		if !r.Passed() {
+			s.Failures++
+		}
+		files[r.File] = true
+		s.Duration += r.Duration
+	}
+	s.Files = len(files)
+	return s
+}
+
+// title names the test in reports, or the file when it could not run.
+func (r Result) title() string {
+	if r.Name == "" {
+		return r.File
+	}
+	return r.File + ": " + r.Name
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteText writes the failures with what the failing tests printed and a
+// summary, verbose writes the passing tests too.
+func WriteText(w io.Writer, results []Result, verbose bool) error {
+	var b strings.Builder
+	for _, r := range results {
+		status := "PASS"
+		if !r.Passed() {
+			status = "FAIL"
+		} else if !verbose {
+			continue
+		}
+
+		fmt.Fprintf(&b, "--- %s: %s (%ss)\n", status, r.title(), seconds(r.Duration))
+		if !r.Passed() {
+			b.WriteString(indent(r.Failure, "    "))
+		}
+		if r.Output != "" && (verbose || !r.Passed()) {
+			b.WriteString("    output:\n")
+			b.WriteString(indent(strings.TrimSuffix(r.Output, "\n"), "      "))
+		}
+	}
+
+	s := Summarize(results)
+	switch {
+	case s.Tests == 0:
+		b.WriteString("no tests to run\n")
+	case s.Failures > 0:
+		fmt.Fprintf(&b, "FAIL  %d of %s failed in %s (%ss)\n", s.Failures, count(s.Tests, "test"), count(s.Files, "file"), seconds(s.Duration))
+	default:
+		fmt.Fprintf(&b, "ok    %s passed in %s (%ss)\n", count(s.Tests, "test"), count(s.Files, "file"), seconds(s.Duration))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func count(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func indent(text, prefix string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(prefix + line + "\n")
+	}
+	return b.String()
+}
+
+// WriteTAP writes the results in the Test Anything Protocol version 13, with
+// the failures and output of tests in YAML blocks.
+func WriteTAP(w io.Writer, results []Result) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(results))
+	for i, r := range results {
+		status := "ok"
+		if !r.Passed() {
+			status = "not ok"
+		}
+		fmt.Fprintf(&b, "%s %d - %s\n", status, i+1, r.title())
+
+		if r.Passed() && r.Output == "" {
+			continue
+		}
+		b.WriteString("  ---\n")
+		fmt.Fprintf(&b, "  duration_ms: %.3f\n", float64(r.Duration)/float64(time.Millisecond))
+		if !r.Passed() {
+			b.WriteString("  message: |\n")
+			b.WriteString(indent(r.Failure, "    "))
+		}
+		if r.Output != "" {
+			b.WriteString("  output: |\n")
+			b.WriteString(indent(strings.TrimSuffix(r.Output, "\n"), "    "))
+		}
+		b.WriteString("  ...\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as JUnit XML, with a test suite for each file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	s := Summarize(results)
+	report := junitSuites{Tests: s.Tests, Failures: s.Failures, Time: seconds(s.Duration)}
+
+	suites := map[string]int{} //Index of the suite of each file
+	var durations []time.Duration
+	for _, r := range results {
+		i, ok := suites[r.File]
+		if !ok {
+			i = len(report.Suites)
+			suites[r.File] = i
+			report.Suites = append(report.Suites, junitSuite{Name: r.File})
+			durations = append(durations, 0)
+		}
+		durations[i] += r.Duration
+
+		name := r.Name
+		if name == "" {
+			name = r.File
+		}
+		c := junitCase{Name: name, Classname: r.File, Time: seconds(r.Duration), SystemOut: r.Output}
+		if !r.Passed() {
+			message, _, _ := strings.Cut(r.Failure, "\n")
+			c.Failure = &junitFailure{Message: message, Text: r.Failure}
+		}
+
+		suite := &report.Suites[i]
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+		if !r.Passed() {
+			suite.Failures++
+		}
+	}
+	for i, d := range durations {
+		report.Suites[i].Time = seconds(d)
+	}
+
+	content, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, content)
+	return err
+}
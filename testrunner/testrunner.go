@@ -0,0 +1,162 @@
+// Package testrunner finds the test functions of scripts and runs each of them
+// in its own evaluator.
+package testrunner
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/types"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// FileSuffix ends the names of the files holding tests.
+	FileSuffix = "_test.syn"
+	// Prefix starts the names of test functions.
+	Prefix = "test_"
+)
+
+// Result is the outcome of a test function, or of a file whose tests could not run.
+type Result struct {
+	File     string
+	Name     string //Empty when the file could not be read or parsed
+	Failure  string //Empty when the test passed
+	Output   string //Printed by the test
+	Duration time.Duration
+}
+
+func (r Result) Passed() bool { return r.Failure == "" }
+
+type Options struct {
+	Run          *regexp.Regexp              //Selects the tests by name, nil runs all of them
+	NewEvaluator func() *evaluator.Evaluator //Creates the evaluator of each test, evaluator.New when nil
+}
+
+// Find returns the test files in paths. Directories are searched recursively,
+// skipping hidden ones, and files are taken whatever their name.
+func Find(paths []string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case d.IsDir() && path != root && strings.HasPrefix(d.Name(), "."):
+				return filepath.SkipDir
+			case !d.IsDir() && strings.HasSuffix(d.Name(), FileSuffix):
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Run runs the tests of every file, in order.
+func Run(files []string, opts Options) []Result {
+	var results []Result
+	for _, f := range files {
+		results = append(results, RunFile(f, opts)...)
+	}
+	return results
+}
+
+// RunFile runs the test functions of the file in the order they are declared.
+// Each one gets a new evaluator which runs the whole file before calling it.
+func RunFile(path string, opts Options) []Result {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return []Result{{File: path, Failure: err.Error()}}
+	}
+
+	p := parser.NewParser(lexer.NewTokenizer(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors) > 0 {
+		return []Result{{File: path, Failure: strings.Join(p.Errors, "\n")}}
+	}
+
+	var results []Result
+	for _, name := range Names(program) {
+		if opts.Run == nil || opts.Run.MatchString(name) {
+			results = append(results, runTest(path, program, name, opts))
+		}
+	}
+	return results
+}
+
+// Names returns the test functions declared at the top level of the program.
+func Names(program *ast.Program) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, s := range program.Statements {
+		if export, ok := s.(*ast.ExportStatement); ok {
+			s = export.Statement
+		}
+
+		var name string
+		switch s := s.(type) {
+		case *ast.FunctionStatement:
+			name = s.Name.Value
+		case *ast.DeclarativeStatement:
+			if _, ok := s.Value.(*ast.FunctionLiteral); ok {
+				name = s.Name.Value
+			}
+		}
+
+		if strings.HasPrefix(name, Prefix) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func runTest(path string, program *ast.Program, name string, opts Options) Result {
+	e := evaluator.New()
+	if opts.NewEvaluator != nil {
+		e = opts.NewEvaluator()
+	}
+	var out strings.Builder
+	e.SetIO(strings.NewReader(""), &out)
+
+	start := time.Now()
+	ctx := types.NewContext(nil)
+	result := e.EvalModule(program, path, ctx)
+	if _, failed := result.(*types.Error); !failed {
+		fn, _ := ctx.Get(name)
+		if f, ok := fn.(*types.Function); ok && len(f.Parameters) > 0 {
+			result = &types.Error{Value: fmt.Sprintf("%s takes parameters, test functions take none", name)}
+		} else {
+			result = e.Call(fn)
+		}
+	}
+
+	r := Result{File: path, Name: name, Output: out.String(), Duration: time.Since(start)}
+	if err, ok := result.(*types.Error); ok {
+		r.Failure = err.String()
+		if err.Position.Row != 0 {
+			r.Failure = fmt.Sprintf("%s:%s: %s", path, err.Position.String(), err.String())
+		}
+	}
+	return r
+}
@@ -0,0 +1,213 @@
+package testrunner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const mathTests = `import "math_lib.syn";
+let calls = [];
+func test_add() {
+  assert_eq(math_lib.add(1, 2), 3);
+}
+func test_sub() {
+  println("subtracting");
+  assert_eq(math_lib.sub(3, 1), 1, "sub");
+}
+export let test_isolated = func() {
+  calls.push(1);
+  assert_eq(len(calls), 1);
+};
+func test_params(x) { x }
+func helper() { assert(false) }
+`
+
+// writeTests creates a directory with test files and returns it.
+func writeTests(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"math_lib.syn":          "export func add(a, b) { a + b }\nexport func sub(a, b) { a - b }\n",
+		"math_test.syn":         mathTests,
+		"sub/broken_test.syn":   "let x = ;\n",
+		"sub/notes.syn":         "func test_ignored() { assert(false) }\n",
+		".hidden/skip_test.syn": "func test_skipped() { assert(false) }\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFind(t *testing.T) {
+	dir := writeTests(t)
+
+	files, err := Find([]string{dir, filepath.Join(dir, "sub", "notes.syn")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		filepath.Join(dir, "math_test.syn"),
+		filepath.Join(dir, "sub", "broken_test.syn"),
+		filepath.Join(dir, "sub", "notes.syn"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %q, got %q", expected, files)
+	}
+
+	if _, err := Find([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
+
+// run runs the tests in dir, without durations so that reports can be compared.
+func run(t *testing.T, dir string, opts Options) []Result {
+	t.Helper()
+
+	files, err := Find([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := Run(files, opts)
+	for i := range results {
+		results[i].Duration = 0
+	}
+	return results
+}
+
+func TestRun(t *testing.T) {
+	dir := writeTests(t)
+	mathFile, brokenFile := filepath.Join(dir, "math_test.syn"), filepath.Join(dir, "sub", "broken_test.syn")
+
+	results := run(t, dir, Options{})
+	expected := []Result{
+		{File: mathFile, Name: "test_add"},
+		{File: mathFile, Name: "test_sub", Output: "subtracting\n",
+			Failure: mathFile + ":8:3: assert_eq failed: sub\nexpected: 1\n  actual: 2"},
+		{File: mathFile, Name: "test_isolated"},
+		{File: mathFile, Name: "test_params", Failure: "test_params takes parameters, test functions take none"},
+		{File: brokenFile, Failure: "Missing prefix parser for ;"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+
+	results = run(t, dir, Options{Run: regexp.MustCompile("^test_(add|isolated)$")})
+	if len(results) != 3 || results[0].Name != "test_add" || results[1].Name != "test_isolated" || results[2].File != brokenFile {
+		t.Errorf("expected the matching tests and the broken file, got %+v", results)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	dir := writeTests(t)
+	results := run(t, filepath.Join(dir, "math_test.syn"), Options{})
+	file := filepath.Join(dir, "math_test.syn")
+
+	var out strings.Builder
+	if err := WriteText(&out, results, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := "--- FAIL: " + file + ": test_sub (0.000s)\n" +
+		"    " + file + ":8:3: assert_eq failed: sub\n" +
+		"    expected: 1\n" +
+		"      actual: 2\n" +
+		"    output:\n" +
+		"      subtracting\n" +
+		"--- FAIL: " + file + ": test_params (0.000s)\n" +
+		"    test_params takes parameters, test functions take none\n" +
+		"FAIL  2 of 4 tests failed in 1 file (0.000s)\n"
+	if out.String() != expected {
+		t.Errorf("unexpected report:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+
+	out.Reset()
+	WriteText(&out, results[:1], true)
+	if expected := "--- PASS: " + file + ": test_add (0.000s)\nok    1 test passed in 1 file (0.000s)\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	WriteText(&out, nil, false)
+	if out.String() != "no tests to run\n" {
+		t.Errorf("unexpected report %q", out.String())
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	results := []Result{
+		{File: "a_test.syn", Name: "test_ok"},
+		{File: "a_test.syn", Name: "test_fail", Failure: "a_test.syn:2:3: assert_eq failed\nexpected: 1\n  actual: 2", Output: "hi\n"},
+		{File: "b_test.syn", Failure: "Missing prefix parser for EOF"},
+	}
+
+	var out strings.Builder
+	if err := WriteTAP(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	expected := `TAP version 13
+1..3
+ok 1 - a_test.syn: test_ok
+not ok 2 - a_test.syn: test_fail
+  ---
+  duration_ms: 0.000
+  message: |
+    a_test.syn:2:3: assert_eq failed
+    expected: 1
+      actual: 2
+  output: |
+    hi
+  ...
+not ok 3 - b_test.syn
+  ---
+  duration_ms: 0.000
+  message: |
+    Missing prefix parser for EOF
+  ...
+`
+	if out.String() != expected {
+		t.Errorf("unexpected report:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := []Result{
+		{File: "a_test.syn", Name: "test_ok", Output: "hi\n"},
+		{File: "a_test.syn", Name: "test_fail", Failure: "a_test.syn:2:3: assert_eq failed\nexpected: <1>"},
+		{File: "b_test.syn", Failure: "Missing prefix parser for EOF"},
+	}
+
+	var out strings.Builder
+	if err := WriteJUnit(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="2" time="0.000">
+  <testsuite name="a_test.syn" tests="2" failures="1" time="0.000">
+    <testcase name="test_ok" classname="a_test.syn" time="0.000">
+      <system-out>hi&#xA;</system-out>
+    </testcase>
+    <testcase name="test_fail" classname="a_test.syn" time="0.000">
+      <failure message="a_test.syn:2:3: assert_eq failed">a_test.syn:2:3: assert_eq failed&#xA;expected: &lt;1&gt;</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="b_test.syn" tests="1" failures="1" time="0.000">
+    <testcase name="b_test.syn" classname="b_test.syn" time="0.000">
+      <failure message="Missing prefix parser for EOF">Missing prefix parser for EOF</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if out.String() != expected {
+		t.Errorf("unexpected report:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
@@ -0,0 +1,109 @@
+// Package tracer logs the statements a script evaluates and the calls it makes,
+// indented by call depth, to follow what a script does step by step.
+package tracer
+
+import (
+	"Simply/ast"
+	"Simply/evaluator"
+	"Simply/types"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// maxWidth clips statements and values, which can span whole functions or collections.
+const maxWidth = 60
+
+// Tracer is an evaluator.CallHook writing a line for every statement, call and return.
+type Tracer struct {
+	w         io.Writer
+	functions map[string]bool //Traced functions by name, nil traces everything
+}
+
+// New returns a tracer writing to w. When functions are given, only their calls
+// and returns and the statements of their bodies are traced. Functions are named
+// as in evaluator.Frame.Name, like "fact", "Point.sum" or "func@3".
+func New(w io.Writer, functions ...string) *Tracer {
+	t := &Tracer{w: w}
+	if len(functions) > 0 {
+		t.functions = map[string]bool{}
+		for _, f := range functions {
+			t.functions[f] = true
+		}
+	}
+	return t
+}
+
+// Statement implements evaluator.Hook.
+func (t *Tracer) Statement(n ast.Node, stack []evaluator.Frame) {
+	top := stack[len(stack)-1]
+	if t.traced(top) {
+		t.log(len(stack)-1, top, clip(n.String()))
+	}
+}
+
+// Call implements evaluator.CallHook, the call is logged where the caller is.
+func (t *Tracer) Call(stack []evaluator.Frame, args []types.Object) {
+	callee := stack[len(stack)-1]
+	if !t.traced(callee) {
+		return
+	}
+
+	values := make([]string, len(args))
+	for i, a := range args {
+		values[i] = clip(types.Inspect(a))
+	}
+	t.log(len(stack)-2, caller(stack), fmt.Sprintf("call %s(%s)", callee.Name(), strings.Join(values, ", ")))
+}
+
+// Return implements evaluator.CallHook.
+func (t *Tracer) Return(stack []evaluator.Frame, result types.Object) {
+	callee := stack[len(stack)-1]
+	if !t.traced(callee) {
+		return
+	}
+
+	var value string
+	switch result := result.(type) {
+	case nil:
+		value = "null"
+	case *types.Error:
+		value = "error " + clip(result.Value)
+	default:
+		value = clip(types.Inspect(result))
+	}
+	t.log(len(stack)-2, caller(stack), fmt.Sprintf("return %s = %s", callee.Name(), value))
+}
+
+func (t *Tracer) traced(f evaluator.Frame) bool {
+	return t.functions == nil || t.functions[f.Name()]
+}
+
+// caller is the frame a call was made from, the callee itself when a host called it.
+func caller(stack []evaluator.Frame) evaluator.Frame {
+	if len(stack) < 2 {
+		return stack[len(stack)-1]
+	}
+	return stack[len(stack)-2]
+}
+
+func (t *Tracer) log(depth int, f evaluator.Frame, text string) {
+	pos := f.Pos.String()
+	if f.File != "" {
+		pos = filepath.Base(f.File) + ":" + pos
+	}
+	fmt.Fprintf(t.w, "%s%s: %s\n", strings.Repeat("  ", max(depth, 0)), pos, text)
+}
+
+// clip keeps the first line of s, up to maxWidth characters.
+func clip(s string) string {
+	line, _, multiline := strings.Cut(s, "\n")
+	if runes := []rune(line); len(runes) > maxWidth {
+		return string(runes[:maxWidth-3]) + "..."
+	}
+	if multiline {
+		return line + "..."
+	}
+	return line
+}
@@ -0,0 +1,80 @@
+package tracer
+
+import (
+	"Simply/evaluator"
+	"Simply/lexer"
+	"Simply/parser"
+	"Simply/types"
+	"strings"
+	"testing"
+)
+
+const script = `func fact(n) {
+  if (n < 2) { return 1; }
+  n * fact(n - 1)
+}
+let twice = func(s) { s + s };
+twice("ab" + "` + "0123456789012345678901234567890123456789012345678901234567890123456789" + `");
+fact(2);
+fact(-1 / 0);`
+
+func trace(t *testing.T, functions ...string) string {
+	t.Helper()
+
+	var out strings.Builder
+	e := evaluator.New()
+	e.SetHook(New(&out, functions...))
+	program := parser.NewParser(lexer.NewTokenizer(script)).ParseProgram()
+	e.EvalModule(program, "/scripts/fact.syn", types.NewContext(nil))
+	return out.String()
+}
+
+func TestTracer(t *testing.T) {
+	expected := `fact.syn:1:1: func fact(n) { if (n < 2) { return 1 }; n * fact(n - 1) }
+fact.syn:5:1: let twice = func(s) { s + s }
+fact.syn:6:1: twice("ab" + "0123456789012345678901234567890123456789012...
+fact.syn:6:1: call twice("ab012345678901234567890123456789012345678901234567890123...)
+  fact.syn:5:23: s + s
+fact.syn:6:1: return twice = "ab012345678901234567890123456789012345678901234567890123...
+fact.syn:7:1: fact(2)
+fact.syn:7:1: call fact(2)
+  fact.syn:2:3: if (n < 2) { return 1 }
+  fact.syn:3:3: n * fact(n - 1)
+  fact.syn:3:3: call fact(1)
+    fact.syn:2:3: if (n < 2) { return 1 }
+    fact.syn:2:16: return 1
+  fact.syn:3:3: return fact = 1
+fact.syn:7:1: return fact = 2
+fact.syn:8:1: fact((-1) / 0)
+`
+	if out := trace(t); out != expected {
+		t.Errorf("unexpected trace:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	expected := `fact.syn:6:1: call twice("ab012345678901234567890123456789012345678901234567890123...)
+  fact.syn:5:23: s + s
+fact.syn:6:1: return twice = "ab012345678901234567890123456789012345678901234567890123...
+`
+	if out := trace(t, "twice", "nope"); out != expected {
+		t.Errorf("unexpected trace:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestClip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"short", "short"},
+		{"two\nlines", "two..."},
+		{strings.Repeat("é", 61), strings.Repeat("é", 57) + "..."},
+		{strings.Repeat("a", 60), strings.Repeat("a", 60)},
+	}
+	for _, tt := range tests {
+		if result := clip(tt.input); result != tt.expected {
+			t.Errorf("clip(%q): expected %q, got %q", tt.input, tt.expected, result)
+		}
+	}
+}
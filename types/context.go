@@ -1,6 +1,14 @@
 package types
 
+import (
+	"sort"
+	"sync"
+)
+
+// Context holds the bindings of a scope. It is safe for concurrent use, so
+// functions and modules may be shared between interpreters.
 type Context struct {
+	mu     sync.RWMutex
 	store  map[string]Object
 	parent *Context
 }
@@ -10,15 +18,56 @@ func NewContext(parent *Context) *Context {
 }
 
 func (ctx *Context) Set(k string, v Object) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
 	ctx.store[k] = v
 }
 
 func (ctx *Context) Get(k string) (result Object, ok bool) {
+	ctx.mu.RLock()
 	result, ok = ctx.store[k]
+	ctx.mu.RUnlock()
 
 	if !ok && ctx.parent != nil {
-		result, ok = ctx.parent.store[k]
+		result, ok = ctx.parent.Get(k)
 	}
 
 	return
 }
+
+// Assign rebinds an existing name in the closest context that declares it.
+func (ctx *Context) Assign(k string, v Object) bool {
+	for c := ctx; c != nil; c = c.parent {
+		c.mu.Lock()
+		_, ok := c.store[k]
+		if ok {
+			c.store[k] = v
+		}
+		c.mu.Unlock()
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parent returns the enclosing context, nil for the outermost one.
+func (ctx *Context) Parent() *Context {
+	return ctx.parent
+}
+
+// Names returns the names bound in this context, not its parents, in sorted order.
+func (ctx *Context) Names() []string {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+
+	names := make([]string, 0, len(ctx.store))
+	for name := range ctx.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
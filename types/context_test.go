@@ -0,0 +1,31 @@
+package types
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestContextConcurrentAccess(t *testing.T) {
+	parent := NewContext(nil)
+	parent.Set("shared", &Int{Value: 0})
+
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			ctx := NewContext(parent)
+			ctx.Set(fmt.Sprint("local", n), &Int{Value: int64(n)})
+			parent.Assign("shared", &Int{Value: int64(n)})
+			if _, ok := ctx.Get("shared"); !ok {
+				t.Error("expected shared to be visible from the child context")
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	if ok := parent.Assign("missing", NULL); ok {
+		t.Error("expected assigning an undeclared name to fail")
+	}
+}
@@ -2,8 +2,11 @@ package types
 
 import (
 	"Simply/ast"
+	"bufio"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -17,7 +20,8 @@ type Object interface {
 }
 
 type Error struct {
-	Value string
+	Value    string
+	Position ast.Position //Where the error happened, zero when unknown
 }
 
 func (e *Error) String() string { return e.Value }
@@ -28,6 +32,18 @@ type Int struct {
 
 func (i *Int) String() string { return strconv.FormatInt(i.Value, 10) }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) String() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEnN") {
+		s += ".0"
+	}
+	return s
+}
+
 type String struct {
 	Value string
 }
@@ -45,12 +61,20 @@ type Null struct{}
 func (n *Null) String() string { return "null" }
 
 type Function struct {
+	Name       string //Empty for anonymous functions
+	File       string //Path of the module that defines it, empty when it is not from a file
 	Parameters []*ast.Identifier
 	Body       *ast.CodeBlock
 	Ctx        *Context
 }
 
-func (f *Function) String() string { return "TODO types.Function" }
+func (f *Function) String() string {
+	params := make([]string, len(f.Parameters))
+	for i, p := range f.Parameters {
+		params[i] = p.Value
+	}
+	return "func(" + strings.Join(params, ", ") + ")"
+}
 
 type ReturnValue struct {
 	Value Object
@@ -58,9 +82,232 @@ type ReturnValue struct {
 
 func (r *ReturnValue) String() string { return r.Value.String() }
 
-type InternalCallFunc func(args ...Object) Object
+// Interpreter is the handle builtins receive to interact with the running script.
+type Interpreter interface {
+	// Call invokes a script function, builtin or constructor. Errors are returned as *Error.
+	Call(fn Object, args ...Object) Object
+	// Position is the source position of the call to the builtin.
+	Position() ast.Position
+	In() *bufio.Reader
+	Out() io.Writer
+}
+
+type InternalCallFunc func(in Interpreter, args ...Object) Object
 type InternalCall struct {
-	Fn InternalCallFunc
+	Fn        InternalCallFunc
+	Signature *Signature //Optional, arguments are validated against it before Fn is called
+}
+
+func (i *InternalCall) String() string {
+	if i.Signature != nil {
+		return "builtin " + i.Signature.Name
+	}
+	return "Internal call"
+}
+
+type StructType struct {
+	Name    string
+	Fields  []string
+	Methods map[string]*Function
+	Traits  []*Trait
+}
+
+func (s *StructType) String() string { return "struct " + s.Name }
+
+func (s *StructType) HasField(name string) bool {
+	for _, f := range s.Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *StructType) Implements(t *Trait) bool {
+	for _, v := range s.Traits {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+type Trait struct {
+	Name    string
+	Methods []string
+	Arity   map[string]int
+}
+
+func (t *Trait) String() string { return "trait " + t.Name }
+
+type TraitMethod struct {
+	Trait *Trait
+	Name  string
+}
+
+func (t *TraitMethod) String() string { return t.Trait.Name + "." + t.Name }
+
+type Instance struct {
+	Type   *StructType
+	Fields map[string]Object
+}
+
+func (i *Instance) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(i.Type.Name)
+	sb.WriteString("{")
+	for idx, f := range i.Type.Fields {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(f)
+		sb.WriteString(": ")
+		sb.WriteString(Inspect(i.Fields[f]))
+	}
+	sb.WriteString("}")
+
+	return sb.String()
+}
+
+type Module struct {
+	Name    string
+	Path    string
+	Ctx     *Context
+	Exports map[string]bool
 }
 
-func (i *InternalCall) String() string { return "Internal call" }
+func (m *Module) String() string { return "module " + m.Name }
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) String() string {
+	elements := make([]string, len(a.Elements))
+	for i, e := range a.Elements {
+		elements[i] = Inspect(e)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+// Map keeps its pairs in insertion order so that printing and iteration are stable.
+type Map struct {
+	pairs map[string]*MapPair
+	keys  []string
+}
+
+func NewMap() *Map {
+	return &Map{pairs: map[string]*MapPair{}}
+}
+
+// IsHashable reports whether the object can be used as a map key.
+func IsHashable(o Object) bool {
+	switch o.(type) {
+	case *Int, *Float, *String, *Bool:
+		return true
+	}
+	return false
+}
+
+func hashKey(key Object) string {
+	return TypeName(key) + ":" + key.String()
+}
+
+func (m *Map) Get(key Object) (Object, bool) {
+	if p, ok := m.pairs[hashKey(key)]; ok {
+		return p.Value, true
+	}
+	return nil, false
+}
+
+func (m *Map) Set(key, value Object) {
+	h := hashKey(key)
+	if p, ok := m.pairs[h]; ok {
+		p.Value = value
+		return
+	}
+	m.pairs[h] = &MapPair{Key: key, Value: value}
+	m.keys = append(m.keys, h)
+}
+
+func (m *Map) Delete(key Object) bool {
+	h := hashKey(key)
+	if _, ok := m.pairs[h]; !ok {
+		return false
+	}
+	delete(m.pairs, h)
+	for i, k := range m.keys {
+		if k == h {
+			m.keys = append(m.keys[:i:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (m *Map) Len() int { return len(m.keys) }
+
+// Pairs returns the map entries in insertion order.
+func (m *Map) Pairs() []*MapPair {
+	pairs := make([]*MapPair, len(m.keys))
+	for i, k := range m.keys {
+		pairs[i] = m.pairs[k]
+	}
+	return pairs
+}
+
+func (m *Map) String() string {
+	pairs := make([]string, 0, len(m.keys))
+	for _, p := range m.Pairs() {
+		pairs = append(pairs, Inspect(p.Key)+": "+Inspect(p.Value))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Inspect formats the object like String, but quotes strings so that
+// they can be told apart inside collections.
+func Inspect(o Object) string {
+	if s, ok := o.(*String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return o.String()
+}
+
+// TypeName returns the name of the object's type as seen by scripts.
+func TypeName(o Object) string {
+	switch o := o.(type) {
+	case *Int:
+		return "int"
+	case *Float:
+		return "float"
+	case *String:
+		return "string"
+	case *Bool:
+		return "bool"
+	case *Null:
+		return "null"
+	case *Array:
+		return "array"
+	case *Map:
+		return "map"
+	case *Function, *InternalCall, *TraitMethod:
+		return "func"
+	case *StructType:
+		return "struct"
+	case *Trait:
+		return "trait"
+	case *Instance:
+		return o.Type.Name
+	case *Module:
+		return "module"
+	case *Error:
+		return "error"
+	}
+	return "unknown"
+}
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Param struct {
+	Name     string
+	Types    []string //Accepted TypeName values, any type when empty
+	Optional bool
+	Variadic bool
+}
+
+func (p Param) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(p.Name)
+	if p.Variadic {
+		sb.WriteString("...")
+	}
+	if p.Optional {
+		sb.WriteString("?")
+	}
+	if len(p.Types) > 0 {
+		sb.WriteString(": ")
+		sb.WriteString(strings.Join(p.Types, "|"))
+	}
+
+	return sb.String()
+}
+
+func (p Param) Accepts(o Object) bool {
+	if len(p.Types) == 0 {
+		return true
+	}
+
+	name := TypeName(o)
+	if _, ok := o.(*Instance); ok {
+		name = "struct"
+	}
+	for _, t := range p.Types {
+		if t == name || t == "any" {
+			return true
+		}
+		//Struct types are called like functions, their instances are not
+		if _, ok := o.(*StructType); ok && t == "func" {
+			return true
+		}
+	}
+	return false
+}
+
+// Signature describes the parameters of a builtin so that calls can be
+// validated before the Go function runs.
+type Signature struct {
+	Name    string
+	Params  []Param
+	Returns string
+	Doc     string
+}
+
+func (s *Signature) String() string {
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		params[i] = p.String()
+	}
+
+	result := fmt.Sprintf("%s(%s)", s.Name, strings.Join(params, ", "))
+	if s.Returns != "" {
+		result += ": " + s.Returns
+	}
+	return result
+}
+
+// Arity returns the minimum and maximum number of arguments, max is -1 for variadic signatures.
+func (s *Signature) Arity() (min, max int) {
+	for _, p := range s.Params {
+		if p.Variadic {
+			return min, -1
+		}
+		if !p.Optional {
+			min++
+		}
+		max++
+	}
+	return min, max
+}
+
+// ParseSignature reads a signature written like
+//
+//	sort(arr: array, cmp?: func): array
+//
+// where ? marks optional parameters, ... a variadic parameter
+// and | separates the accepted types.
+func ParseSignature(spec string) (*Signature, error) {
+	lparen := strings.Index(spec, "(")
+	rparen := strings.LastIndex(spec, ")")
+	if lparen <= 0 || rparen < lparen {
+		return nil, fmt.Errorf("invalid signature %q", spec)
+	}
+
+	s := &Signature{Name: strings.TrimSpace(spec[:lparen])}
+
+	rest := strings.TrimSpace(spec[rparen+1:])
+	if rest != "" {
+		if !strings.HasPrefix(rest, ":") {
+			return nil, fmt.Errorf("invalid return type in signature %q", spec)
+		}
+		s.Returns = strings.TrimSpace(rest[1:])
+	}
+
+	params := strings.TrimSpace(spec[lparen+1 : rparen])
+	if params == "" {
+		return s, nil
+	}
+
+	for i, raw := range strings.Split(params, ",") {
+		p := Param{}
+		name, typeList, hasType := strings.Cut(strings.TrimSpace(raw), ":")
+		name = strings.TrimSpace(name)
+
+		if strings.HasSuffix(name, "?") {
+			p.Optional = true
+			name = strings.TrimSuffix(name, "?")
+		}
+		if strings.HasSuffix(name, "...") {
+			p.Variadic = true
+			name = strings.TrimSuffix(name, "...")
+		}
+		if name == "" {
+			return nil, fmt.Errorf("missing name of parameter %d in signature %q", i+1, spec)
+		}
+		p.Name = name
+
+		if hasType {
+			for _, t := range strings.Split(typeList, "|") {
+				p.Types = append(p.Types, strings.TrimSpace(t))
+			}
+		}
+
+		s.Params = append(s.Params, p)
+	}
+
+	for i, p := range s.Params {
+		if p.Variadic && i != len(s.Params)-1 {
+			return nil, fmt.Errorf("variadic parameter %s must be last in signature %q", p.Name, spec)
+		}
+	}
+
+	return s, nil
+}
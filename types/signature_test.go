@@ -0,0 +1,59 @@
+package types
+
+import "testing"
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		spec     string
+		min, max int
+	}{
+		{"len(value: string|array|map): int", 1, 1},
+		{"print(values...)", 0, -1},
+		{"sort(arr: array, cmp?: func): array", 1, 2},
+		{"now(): int", 0, 0},
+	}
+
+	for _, tt := range tests {
+		sig, err := ParseSignature(tt.spec)
+		if err != nil {
+			t.Fatalf("%q: %s", tt.spec, err)
+		}
+		if sig.String() != tt.spec {
+			t.Errorf("expected %q to round trip, got %q", tt.spec, sig.String())
+		}
+		if min, max := sig.Arity(); min != tt.min || max != tt.max {
+			t.Errorf("%q: expected arity %d..%d, got %d..%d", tt.spec, tt.min, tt.max, min, max)
+		}
+	}
+
+	for _, spec := range []string{"nope", "f(a..., b)", "f(: int)", "f() int"} {
+		if _, err := ParseSignature(spec); err == nil {
+			t.Errorf("expected %q to fail", spec)
+		}
+	}
+}
+
+func TestParamAccepts(t *testing.T) {
+	point := &StructType{Name: "Point", Fields: []string{"x"}}
+	fn := Param{Name: "fn", Types: []string{"func"}}
+	value := Param{Name: "value", Types: []string{"struct"}}
+
+	tests := []struct {
+		param    Param
+		arg      Object
+		expected bool
+	}{
+		{fn, &Function{}, true},
+		{fn, point, true},
+		{fn, &Instance{Type: point}, false},
+		{fn, &Int{Value: 1}, false},
+		{value, &Instance{Type: point}, true},
+		{Param{Name: "x"}, &Instance{Type: point}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.param.Accepts(tt.arg); got != tt.expected {
+			t.Errorf("%s accepting %s: expected %t, got %t", tt.param, Inspect(tt.arg), tt.expected, got)
+		}
+	}
+}
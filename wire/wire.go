@@ -0,0 +1,81 @@
+// Package wire reads and writes JSON messages framed by a Content-Length header,
+// the base protocol of the language server and debug adapter protocols.
+package wire
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Conn is a stream of framed messages. Writes are safe for concurrent use.
+type Conn struct {
+	in  *bufio.Reader
+	out io.Writer
+	mu  sync.Mutex //Serializes writes
+}
+
+func NewConn(in io.Reader, out io.Writer) *Conn {
+	return &Conn{in: bufio.NewReader(in), out: out}
+}
+
+// Read returns the content of the next message, io.EOF when the stream ends between messages.
+func (c *Conn) Read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := c.in.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length == -1 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+
+	if length == -1 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.in, content); err != nil {
+		return nil, fmt.Errorf("reading content: %w", err)
+	}
+	return content, nil
+}
+
+// Write encodes msg as JSON and writes it as one message.
+func (c *Conn) Write(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.out, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.out.Write(data)
+	return err
+}
@@ -0,0 +1,57 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewConn(nil, &buf)
+	for _, msg := range []any{map[string]int{"seq": 1}, "héllo"} {
+		if err := w.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := "Content-Length: 9\r\n\r\n{\"seq\":1}Content-Length: 8\r\n\r\n\"héllo\""
+	if buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+
+	r := NewConn(&buf, nil)
+	for _, expected := range []string{`{"seq":1}`, `"héllo"`} {
+		content, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Errorf("expected %s, got %s", expected, content)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last message, got %v", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Content-Type: json\r\n\r\n{}", "missing Content-Length header"},
+		{"Content-Length: x\r\n\r\n{}", `invalid Content-Length " x"`},
+		{"Content-Length 2\r\n\r\n{}", `invalid header "Content-Length 2"`},
+		{"Content-Length: 5\r\n\r\n{}", "reading content: unexpected EOF"},
+		{"Content-Length: 2\r\n", "reading header: EOF"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewConn(strings.NewReader(tt.input), nil).Read()
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}